@@ -0,0 +1,731 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	ansiReverse        = "\x1b[7m"
+	ansiClearScreen    = "\x1b[H\x1b[2J"
+	ansiEnterAltScreen = "\x1b[?1049h\x1b[?25l"
+	ansiLeaveAltScreen = "\x1b[?25h\x1b[?1049l"
+)
+
+// Keys as returned by parseTerminalKeys. Printable characters are returned
+// as themselves.
+const (
+	keyUp        = "up"
+	keyDown      = "down"
+	keyLeft      = "left"
+	keyRight     = "right"
+	keyPageUp    = "pgup"
+	keyPageDown  = "pgdn"
+	keyHome      = "home"
+	keyEnd       = "end"
+	keyEnter     = "enter"
+	keyEscape    = "esc"
+	keyTab       = "tab"
+	keyBacktab   = "backtab"
+	keyBackspace = "backspace"
+	keyInterrupt = "ctrl-c"
+)
+
+var terminalEscapeSequences = map[string]string{
+	"\x1b[A": keyUp, "\x1bOA": keyUp,
+	"\x1b[B": keyDown, "\x1bOB": keyDown,
+	"\x1b[C": keyRight, "\x1bOC": keyRight,
+	"\x1b[D": keyLeft, "\x1bOD": keyLeft,
+	"\x1b[5~": keyPageUp, "\x1b[6~": keyPageDown,
+	"\x1b[H": keyHome, "\x1b[1~": keyHome, "\x1bOH": keyHome,
+	"\x1b[F": keyEnd, "\x1b[4~": keyEnd, "\x1bOF": keyEnd,
+	"\x1b[Z": keyBacktab,
+}
+
+// parseTerminalKeys splits input read from a terminal in raw mode into keys.
+// A lone escape is only recognized when it arrives on its own, as terminals
+// send escape sequences in a single write. A character split across reads is
+// returned as the rest, to be prepended to the next read; invalid bytes are
+// skipped.
+func parseTerminalKeys(input []byte) (keys []string, rest []byte) {
+
+	for len(input) > 0 {
+		if input[0] == 0x1b {
+			if len(input) == 1 {
+				keys = append(keys, keyEscape)
+				break
+			}
+
+			matched := false
+			for sequence, key := range terminalEscapeSequences {
+				if bytes.HasPrefix(input, []byte(sequence)) {
+					keys = append(keys, key)
+					input = input[len(sequence):]
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				// Skip unknown sequences up to their final byte.
+				i := 1
+				for i < len(input) && (input[i] == '[' || input[i] == 'O' || input[i] >= '0' && input[i] <= '9' || input[i] == ';') {
+					i++
+				}
+				input = input[clamp(i+1, 0, len(input)):]
+			}
+			continue
+		}
+
+		r, size := utf8.DecodeRune(input)
+		if r == utf8.RuneError && size <= 1 {
+			if !utf8.FullRune(input) {
+				return keys, input
+			}
+			input = input[1:]
+			continue
+		}
+
+		switch r {
+		case '\r', '\n':
+			keys = append(keys, keyEnter)
+		case '\t':
+			keys = append(keys, keyTab)
+		case 0x7f, 0x08:
+			keys = append(keys, keyBackspace)
+		case 0x03:
+			keys = append(keys, keyInterrupt)
+		case 0x0e:
+			keys = append(keys, keyDown)
+		case 0x10:
+			keys = append(keys, keyUp)
+		default:
+			if r >= ' ' {
+				keys = append(keys, string(r))
+			}
+		}
+		input = input[size:]
+	}
+
+	return keys, nil
+}
+
+type browserLocation struct {
+	number int
+	offset int
+}
+
+type browserDocument struct {
+	doc  *RFCDocument
+	refs []*RFCReference
+}
+
+// Browser is the state of the interactive browser: a filterable list of RFCs
+// and a reader for one of them. It is driven by HandleKey and drawn by
+// Render, so that it does not depend on a terminal.
+type Browser struct {
+	RFCRepository        RFCRepository
+	RFCContentRepository RFCContentRepository
+
+	rfcs     []*RFC
+	haystack []string
+	query    string
+	matches  []*RFC
+	cursor   int
+	top      int
+
+	// The reader shows the RFC number while reading is set.
+	reading   bool
+	number    int
+	offset    int
+	selected  int
+	documents map[int]*browserDocument
+	back      []browserLocation
+	forward   []browserLocation
+
+	toc       bool
+	tocCursor int
+
+	height  int
+	message string
+	quit    bool
+}
+
+func NewBrowser(rfcRepository RFCRepository, contentRepository RFCContentRepository) (*Browser, error) {
+	b := Browser{
+		RFCRepository:        rfcRepository,
+		RFCContentRepository: contentRepository,
+		documents:            make(map[int]*browserDocument),
+		height:               24,
+	}
+
+	rfcs, err := rfcRepository.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	b.rfcs = rfcs
+	for _, rfc := range rfcs {
+		b.haystack = append(b.haystack, strings.ToLower(fmt.Sprintf("%d rfc%d %s %s", rfc.Number, rfc.Number, rfc.Title, strings.Join(rfc.Keywords, " "))))
+	}
+	b.filter()
+
+	return &b, nil
+}
+
+// Run draws the browser on the terminal and handles keys until it is quit.
+func (b *Browser) Run(t *Terminal) error {
+	io.WriteString(t, ansiEnterAltScreen)
+	defer io.WriteString(t, ansiLeaveAltScreen)
+
+	buf := make([]byte, 64)
+	// pending is the start of a character split across reads.
+	var pending []byte
+
+	for !b.quit {
+		width, height, err := t.Size()
+		if err != nil {
+			return err
+		}
+
+		var screen bytes.Buffer
+		b.Render(&screen, width, height)
+		t.Write(screen.Bytes())
+
+		n, err := t.Read(buf)
+		if err != nil {
+			return err
+		}
+
+		var keys []string
+		keys, pending = parseTerminalKeys(append(pending, buf[:n]...))
+		for _, key := range keys {
+			b.HandleKey(key)
+		}
+	}
+
+	return nil
+}
+
+func (b *Browser) filter() {
+	terms := strings.Fields(strings.ToLower(b.query))
+
+	b.matches = nil
+	for i, rfc := range b.rfcs {
+		matched := true
+		for _, term := range terms {
+			if !strings.Contains(b.haystack[i], term) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			b.matches = append(b.matches, rfc)
+		}
+	}
+
+	b.cursor = 0
+	b.top = 0
+}
+
+func (b *Browser) HandleKey(key string) {
+	b.message = ""
+
+	if key == keyInterrupt {
+		b.quit = true
+		return
+	}
+
+	switch {
+	case b.toc:
+		b.handleTOCKey(key)
+	case b.reading:
+		b.handleReaderKey(key)
+	default:
+		b.handleListKey(key)
+	}
+}
+
+func (b *Browser) handleListKey(key string) {
+	page := b.height - 3
+
+	switch key {
+	case keyUp:
+		b.cursor--
+	case keyDown:
+		b.cursor++
+	case keyPageUp:
+		b.cursor -= page
+	case keyPageDown:
+		b.cursor += page
+	case keyHome:
+		b.cursor = 0
+	case keyEnd:
+		b.cursor = len(b.matches) - 1
+	case keyEnter:
+		if b.cursor < len(b.matches) {
+			b.open(b.matches[b.cursor].Number, "")
+		}
+	case keyEscape:
+		if b.query == "" {
+			b.quit = true
+		} else {
+			b.query = ""
+			b.filter()
+		}
+	case keyBackspace:
+		if b.query != "" {
+			runes := []rune(b.query)
+			b.query = string(runes[:len(runes)-1])
+			b.filter()
+		}
+	default:
+		if len([]rune(key)) == 1 {
+			b.query += key
+			b.filter()
+		}
+	}
+
+	b.cursor = clamp(b.cursor, 0, len(b.matches)-1)
+}
+
+func (b *Browser) handleReaderKey(key string) {
+	document := b.documents[b.number]
+	page := b.height - 2
+
+	switch key {
+	case keyUp, "k":
+		b.offset--
+	case keyDown, "j":
+		b.offset++
+	case keyPageUp, "b":
+		b.offset -= page
+	case keyPageDown, " ":
+		b.offset += page
+	case keyHome, "g":
+		b.offset = 0
+	case keyEnd, "G":
+		b.offset = len(document.doc.Lines) - page
+	case keyTab, "n":
+		b.selectReference(1)
+	case keyBacktab, "p":
+		b.selectReference(-1)
+	case keyEnter:
+		if b.selected >= 0 && b.selected < len(document.refs) {
+			b.follow(document.refs[b.selected].Target)
+		}
+	case keyLeft, "h":
+		b.goBack()
+	case keyRight, "l":
+		b.goForward()
+	case "t":
+		if len(document.doc.Sections) == 0 {
+			b.message = "No table of contents"
+			break
+		}
+		b.toc = true
+		b.tocCursor = 0
+		for i, section := range document.doc.Sections {
+			if section.Line <= b.offset {
+				b.tocCursor = i
+			}
+		}
+	case "q", keyEscape:
+		b.reading = false
+		b.back = nil
+		b.forward = nil
+	}
+
+	// Following a reference may have opened another document.
+	b.offset = clamp(b.offset, 0, len(b.documents[b.number].doc.Lines)-1)
+}
+
+func (b *Browser) handleTOCKey(key string) {
+	sections := b.documents[b.number].doc.Sections
+
+	switch key {
+	case keyUp, "k":
+		b.tocCursor--
+	case keyDown, "j":
+		b.tocCursor++
+	case keyPageUp:
+		b.tocCursor -= b.height - 2
+	case keyPageDown:
+		b.tocCursor += b.height - 2
+	case keyHome:
+		b.tocCursor = 0
+	case keyEnd:
+		b.tocCursor = len(sections) - 1
+	case keyEnter:
+		b.toc = false
+		b.jump(browserLocation{number: b.number, offset: sections[b.tocCursor].Line})
+	case "t", "q", keyEscape:
+		b.toc = false
+	}
+
+	b.tocCursor = clamp(b.tocCursor, 0, len(sections)-1)
+}
+
+// selectReference selects the next or previous reference, starting from the
+// top of the screen if none is selected or the selection is off screen.
+func (b *Browser) selectReference(direction int) {
+	refs := b.documents[b.number].refs
+	if len(refs) == 0 {
+		b.message = "No references"
+		return
+	}
+
+	visible := func(ref *RFCReference) bool {
+		return ref.Line >= b.offset && ref.Line < b.offset+b.height-2
+	}
+
+	if b.selected < 0 || b.selected >= len(refs) || !visible(refs[b.selected]) {
+		b.selected = -1
+		for i, ref := range refs {
+			if ref.Line >= b.offset {
+				b.selected = i
+				break
+			}
+		}
+		if b.selected < 0 {
+			b.selected = len(refs) - 1
+		}
+	} else {
+		b.selected = clamp(b.selected+direction, 0, len(refs)-1)
+	}
+
+	if ref := refs[b.selected]; !visible(ref) {
+		b.offset = ref.Line - (b.height-2)/3
+	}
+}
+
+func (b *Browser) follow(target RFCReferenceTarget) {
+	if target.RFCNumber != 0 && target.RFCNumber != b.number {
+		b.open(target.RFCNumber, target.Section)
+		return
+	}
+
+	section := b.documents[b.number].doc.Section(target.Section)
+	if section == nil {
+		b.message = fmt.Sprintf("Section %s not found", target.Section)
+		return
+	}
+
+	b.jump(browserLocation{number: b.number, offset: section.Line})
+}
+
+// open shows the RFC in the reader, scrolled to the given section if any.
+func (b *Browser) open(number int, sectionNumber string) {
+	document, err := b.document(number)
+	if err != nil {
+		b.message = err.Error()
+		return
+	}
+
+	offset := 0
+	if section := document.doc.Section(sectionNumber); section != nil {
+		offset = section.Line
+	}
+
+	b.jump(browserLocation{number: number, offset: offset})
+}
+
+// jump moves to the location, recording the current one in the history.
+func (b *Browser) jump(location browserLocation) {
+	if b.reading {
+		b.back = append(b.back, browserLocation{number: b.number, offset: b.offset})
+		b.forward = nil
+	}
+	b.moveTo(location)
+}
+
+func (b *Browser) moveTo(location browserLocation) {
+	b.reading = true
+	b.number = location.number
+	b.offset = location.offset
+	b.selected = -1
+}
+
+func (b *Browser) goBack() {
+	if len(b.back) == 0 {
+		b.message = "Beginning of history"
+		return
+	}
+
+	b.forward = append(b.forward, browserLocation{number: b.number, offset: b.offset})
+	location := b.back[len(b.back)-1]
+	b.back = b.back[:len(b.back)-1]
+	b.moveTo(location)
+}
+
+func (b *Browser) goForward() {
+	if len(b.forward) == 0 {
+		b.message = "End of history"
+		return
+	}
+
+	b.back = append(b.back, browserLocation{number: b.number, offset: b.offset})
+	location := b.forward[len(b.forward)-1]
+	b.forward = b.forward[:len(b.forward)-1]
+	b.moveTo(location)
+}
+
+func (b *Browser) document(number int) (*browserDocument, error) {
+	if document := b.documents[number]; document != nil {
+		return document, nil
+	}
+
+	content, err := b.RFCContentRepository.FindByNumber(number)
+	if err != nil {
+		return nil, fmt.Errorf("RFC %d: %v", number, err)
+	}
+
+	doc := ParseRFCDocument(content)
+	resolver := RFCReferenceResolver{RFCRepository: b.RFCRepository}
+
+	document := browserDocument{doc: doc, refs: resolver.Resolve(doc)}
+	b.documents[number] = &document
+
+	return &document, nil
+}
+
+// Render draws the current view for a screen of the given size.
+func (b *Browser) Render(w io.Writer, width int, height int) {
+	b.height = height
+
+	var lines []string
+	var status string
+
+	switch {
+	case b.toc:
+		lines, status = b.renderTOC(width, height-1)
+	case b.reading:
+		lines, status = b.renderReader(width, height-1)
+	default:
+		lines, status = b.renderList(width, height-1)
+	}
+
+	if b.message != "" {
+		status = b.message
+	}
+
+	io.WriteString(w, ansiClearScreen)
+	for _, line := range lines {
+		io.WriteString(w, line+"\r\n")
+	}
+	for i := len(lines); i < height-1; i++ {
+		io.WriteString(w, "\r\n")
+	}
+	io.WriteString(w, ansiReverse+padRight(truncateRunes(status, width), width)+ansiReset)
+}
+
+func (b *Browser) renderList(width int, height int) ([]string, string) {
+	listWidth := width
+	if width >= 80 {
+		listWidth = width * 45 / 100
+	}
+
+	rows := height - 1
+	if b.cursor < b.top {
+		b.top = b.cursor
+	} else if b.cursor >= b.top+rows {
+		b.top = b.cursor - rows + 1
+	}
+
+	var preview []string
+	if listWidth < width && b.cursor < len(b.matches) {
+		preview = browserPreview(b.matches[b.cursor], width-listWidth-3)
+	}
+
+	lines := []string{truncateRunes(fmt.Sprintf("Search: %s", b.query), width)}
+
+	for i := 0; i < rows; i++ {
+		line := ""
+		if n := b.top + i; n < len(b.matches) {
+			rfc := b.matches[n]
+			line = padRight(truncateRunes(fmt.Sprintf("%5d  %s", rfc.Number, rfc.Title), listWidth), listWidth)
+			if n == b.cursor {
+				line = ansiReverse + line + ansiReset
+			}
+		} else {
+			line = strings.Repeat(" ", listWidth)
+		}
+
+		if i < len(preview) {
+			line += " │ " + preview[i]
+		} else if listWidth < width {
+			line += " │"
+		}
+
+		lines = append(lines, line)
+	}
+
+	status := fmt.Sprintf(" %d/%d RFCs  type to search, ↑/↓ move, Enter read, Esc clear/quit", len(b.matches), len(b.rfcs))
+
+	return lines, status
+}
+
+// browserPreview returns the metadata of the RFC wrapped to the width.
+func browserPreview(rfc *RFC, width int) []string {
+	var lines []string
+
+	add := func(label string, value string) {
+		if value == "" {
+			return
+		}
+		for i, line := range wrapText(value, width-len(label)-2) {
+			if i == 0 {
+				lines = append(lines, ansiBold+label+":"+ansiReset+" "+line)
+			} else {
+				lines = append(lines, strings.Repeat(" ", len(label)+2)+line)
+			}
+		}
+	}
+
+	numbers := func(numbers []int) string {
+		var names []string
+		for _, number := range numbers {
+			names = append(names, strconv.Itoa(number))
+		}
+		return strings.Join(names, ", ")
+	}
+
+	for _, line := range wrapText(fmt.Sprintf("RFC %d: %s", rfc.Number, rfc.Title), width) {
+		lines = append(lines, ansiBold+line+ansiReset)
+	}
+	lines = append(lines, "")
+
+	add("Published", rfc.PublicationDate.String())
+	add("Status", rfc.Status)
+	add("Stream", rfc.Stream)
+	add("Area", rfc.Area)
+	add("Working group", rfc.WorkingGroup)
+	add("Authors", strings.Join(rfc.Authors, ", "))
+	add("Series", strings.Join(rfc.Series, ", "))
+	add("Obsoletes", numbers(rfc.Obsoletes))
+	add("Obsoleted by", numbers(rfc.ObsoletedBy))
+	add("Updates", numbers(rfc.Updates))
+	add("Updated by", numbers(rfc.UpdatedBy))
+	add("Keywords", strings.Join(rfc.Keywords, ", "))
+
+	if rfc.Abstract != "" {
+		lines = append(lines, "")
+		lines = append(lines, wrapText(rfc.Abstract, width)...)
+	}
+
+	return lines
+}
+
+func (b *Browser) renderReader(width int, height int) ([]string, string) {
+	document := b.documents[b.number]
+
+	headings := make(map[int]bool)
+	for _, section := range document.doc.Sections {
+		headings[section.Line] = true
+	}
+
+	var selected *RFCReference
+	if b.selected >= 0 && b.selected < len(document.refs) {
+		selected = document.refs[b.selected]
+	}
+
+	var lines []string
+	for i := b.offset; i < b.offset+height && i < len(document.doc.Lines); i++ {
+		line := truncateRunes(document.doc.Lines[i], width)
+
+		switch {
+		case selected != nil && selected.Line == i && selected.End <= len(line):
+			line = line[:selected.Start] + ansiReverse + line[selected.Start:selected.End] + ansiReset + line[selected.End:]
+		case headings[i]:
+			line = ansiBold + line + ansiReset
+		}
+
+		lines = append(lines, line)
+	}
+
+	location := fmt.Sprintf("RFC %d", b.number)
+	if section := document.doc.SectionAt(b.offset); section != nil {
+		location += fmt.Sprintf(", Section %s", section.Number)
+	}
+
+	percent := 100
+	if len(document.doc.Lines) > height {
+		percent = clamp((b.offset+height)*100/len(document.doc.Lines), 0, 100)
+	}
+
+	status := fmt.Sprintf(" %s  %d%%  Tab/Enter follow link, ←/→ history, t contents, q list", location, percent)
+	if selected != nil {
+		status = fmt.Sprintf(" %s  → %s", location, describeReferenceTarget(selected.Target))
+	}
+
+	return lines, status
+}
+
+func describeReferenceTarget(target RFCReferenceTarget) string {
+	var s string
+	if target.RFCNumber != 0 {
+		s = fmt.Sprintf("RFC %d", target.RFCNumber)
+		if target.Title != "" {
+			s += " " + target.Title
+		}
+		if target.Section != "" {
+			s += ", "
+		}
+	}
+	if target.Section != "" {
+		if target.Section[0] >= 'A' && target.Section[0] <= 'Z' {
+			s += "Appendix " + target.Section
+		} else {
+			s += "Section " + target.Section
+		}
+	}
+	return s
+}
+
+func (b *Browser) renderTOC(width int, height int) ([]string, string) {
+	sections := b.documents[b.number].doc.Sections
+
+	top := 0
+	if b.tocCursor >= height {
+		top = b.tocCursor - height + 1
+	}
+
+	var lines []string
+	for i := top; i < top+height && i < len(sections); i++ {
+		section := sections[i]
+		line := truncateRunes(strings.Repeat("  ", section.Level-1)+section.Number+"  "+section.Title, width)
+		if i == b.tocCursor {
+			line = ansiReverse + padRight(line, width) + ansiReset
+		}
+		lines = append(lines, line)
+	}
+
+	return lines, fmt.Sprintf(" RFC %d contents  ↑/↓ move, Enter go to section, Esc close", b.number)
+}
+
+func truncateRunes(s string, width int) string {
+	if runes := []rune(s); len(runes) > width {
+		return string(runes[:width])
+	}
+	return s
+}
+
+func padRight(s string, width int) string {
+	if n := len([]rune(s)); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+func clamp(value int, low int, high int) int {
+	if value > high {
+		value = high
+	}
+	if value < low {
+		value = low
+	}
+	return value
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTerminalKeys(t *testing.T) {
+	tests := []struct {
+		input string
+		keys  []string
+		rest  string
+	}{
+		{"ab\r", []string{"a", "b", keyEnter}, ""},
+		{"\x1b[A\x1bOB\x1b[5~\x1b[Z", []string{keyUp, keyDown, keyPageUp, keyBacktab}, ""},
+		{"\x1b", []string{keyEscape}, ""},
+		{"\x1b[1;5Cx", []string{"x"}, ""},
+		{"\t\x7f\x03", []string{keyTab, keyBackspace, keyInterrupt}, ""},
+		{"é\xc3", []string{"é"}, "\xc3"},
+		{"\xffa", []string{"a"}, ""},
+	}
+
+	for _, test := range tests {
+		keys, rest := parseTerminalKeys([]byte(test.input))
+		if !reflect.DeepEqual(keys, test.keys) || string(rest) != test.rest {
+			t.Errorf("%q: got %q and rest %q, want %q and %q", test.input, keys, rest, test.keys, test.rest)
+		}
+	}
+}
+
+func newTestBrowser(t *testing.T) *Browser {
+	rfcIndex, err := ParseRFCIndex([]byte(testRFCIndexAfter))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	browser, err := NewBrowser(&RFCIndexRFCRepository{RFCIndex: rfcIndex}, testRFCContents{
+		9110: "1.  Introduction\n\n   See RFC 3986, Section 3 and Section 2.\n\n2.  Terminology\n\n   Terms.\n",
+		3986: "1.  Introduction\n\n   URIs.\n\n2.  Characters\n\n   Text.\n\n3.  Syntax Components\n\n   Text.\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return browser
+}
+
+func TestBrowserFilter(t *testing.T) {
+	b := newTestBrowser(t)
+
+	for _, key := range []string{"h", "t", "t", "p", " ", "1", "."} {
+		b.HandleKey(key)
+	}
+	if got := rfcNumbers(b.matches); !reflect.DeepEqual(got, []int{2616, 7230}) {
+		t.Errorf("got matches %v for %q", got, b.query)
+	}
+
+	b.HandleKey(keyDown)
+	b.HandleKey(keyDown)
+	if b.cursor != 1 {
+		t.Errorf("got cursor %d past the last match", b.cursor)
+	}
+
+	b.HandleKey(keyBackspace)
+	b.HandleKey("6")
+	if got := rfcNumbers(b.matches); !reflect.DeepEqual(got, []int{2616}) {
+		t.Errorf("got matches %v for %q", got, b.query)
+	}
+
+	b.HandleKey(keyEscape)
+	if b.query != "" || len(b.matches) != 4 || b.quit {
+		t.Errorf("got query %q and %d matches after clearing the search", b.query, len(b.matches))
+	}
+	b.HandleKey(keyEscape)
+	if !b.quit {
+		t.Error("browser did not quit")
+	}
+}
+
+func TestBrowserReader(t *testing.T) {
+	b := newTestBrowser(t)
+
+	for _, key := range []string{"9", "1", "1", "0", keyEnter} {
+		b.HandleKey(key)
+	}
+	if !b.reading || b.number != 9110 {
+		t.Fatalf("got reading %v RFC %d", b.reading, b.number)
+	}
+
+	// The first reference leads to RFC 3986, and back.
+	b.HandleKey(keyTab)
+	b.HandleKey(keyEnter)
+	if b.number != 3986 || b.offset != b.documents[3986].doc.Section("3").Line {
+		t.Errorf("got RFC %d at line %d after following a reference", b.number, b.offset)
+	}
+
+	var screen bytes.Buffer
+	b.Render(&screen, 80, 10)
+	if !strings.Contains(screen.String(), "RFC 3986, Section 3") {
+		t.Errorf("got screen %q", screen.String())
+	}
+
+	b.HandleKey(keyLeft)
+	if b.number != 9110 || b.offset != 0 {
+		t.Errorf("got RFC %d at line %d after going back", b.number, b.offset)
+	}
+	b.HandleKey(keyRight)
+	if b.number != 3986 {
+		t.Errorf("got RFC %d after going forward", b.number)
+	}
+	b.HandleKey(keyLeft)
+
+	// The second reference is to a section of the RFC itself.
+	b.HandleKey(keyTab)
+	b.HandleKey(keyTab)
+	b.HandleKey(keyEnter)
+	if b.number != 9110 || b.offset != b.documents[9110].doc.Section("2").Line {
+		t.Errorf("got RFC %d at line %d after following a section reference", b.number, b.offset)
+	}
+
+	// The table of contents jumps to a section.
+	b.HandleKey("g")
+	b.HandleKey("t")
+	b.HandleKey(keyDown)
+	b.HandleKey(keyEnter)
+	if b.toc || b.offset != b.documents[9110].doc.Section("2").Line {
+		t.Errorf("got line %d after choosing a section", b.offset)
+	}
+
+	b.HandleKey("q")
+	if b.reading || b.back != nil {
+		t.Error("reader was not closed")
+	}
+
+	b.HandleKey(keyEscape)
+	b.HandleKey("7")
+	b.HandleKey(keyEnter)
+	if b.reading || !strings.Contains(b.message, "RFC 7230") {
+		t.Errorf("got reading %v and message %q for an RFC that is not cached", b.reading, b.message)
+	}
+}
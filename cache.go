@@ -0,0 +1,459 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var ErrCorruptCacheEntry = errors.New("corrupt cache entry")
+
+const (
+	cacheLockFileName      = ".lock"
+	cacheChecksumExtension = ".sha256"
+)
+
+func WriteFileAtomic(filename string, content []byte, perm os.FileMode) error {
+	dir, base := filepath.Split(filename)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+base+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpName)
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	if err := os.Chmod(tmpName, perm); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	if err := os.Rename(tmpName, filename); err != nil {
+		os.Remove(tmpName)
+		return err
+	}
+
+	return nil
+}
+
+func checksumOf(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+func checksumFileFor(cacheFile string) string {
+	return cacheFile + cacheChecksumExtension
+}
+
+type CacheCompression int
+
+const (
+	CacheCompressionNone CacheCompression = iota
+	CacheCompressionGzip
+)
+
+func (c CacheCompression) Extension() string {
+	switch c {
+	case CacheCompressionGzip:
+		return ".gz"
+	}
+	return ""
+}
+
+func (c CacheCompression) String() string {
+	switch c {
+	case CacheCompressionNone:
+		return "none"
+	case CacheCompressionGzip:
+		return "gzip"
+	}
+	return fmt.Sprintf("CacheCompression(%d)", int(c))
+}
+
+func (c CacheCompression) decompress(content []byte) ([]byte, error) {
+	switch c {
+	case CacheCompressionNone:
+		return content, nil
+	case CacheCompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, ErrCorruptCacheEntry
+		}
+		defer r.Close()
+
+		content, err := ioutil.ReadAll(r)
+		if err != nil {
+			return nil, ErrCorruptCacheEntry
+		}
+		return content, nil
+	}
+	return nil, fmt.Errorf("unknown cache compression: %v", c)
+}
+
+func toCacheCompression(compression string) (CacheCompression, error) {
+	switch compression {
+	case "", "none":
+		return CacheCompressionNone, nil
+	case "gzip":
+		return CacheCompressionGzip, nil
+	}
+	return CacheCompression(0), fmt.Errorf("unknown cache compression: %s", compression)
+}
+
+// DefaultCacheDirectory returns the directory in RFCS_CACHE_DIR, or the rfcs
+// directory in the user cache directory if it is not set.
+func DefaultCacheDirectory() string {
+	if dir := os.Getenv(envCacheDirectory); dir != "" {
+		return dir
+	}
+	return GetUserCacheDirectory("rfcs")
+}
+
+// CacheCompressionFromEnvironment returns the compression in
+// RFCS_CACHE_COMPRESSION, or none if it is invalid, which
+// ValidateCacheCompressionEnvironment reports before any command runs.
+func CacheCompressionFromEnvironment() CacheCompression {
+	compression, err := toCacheCompression(os.Getenv("RFCS_CACHE_COMPRESSION"))
+	if err != nil {
+		return CacheCompressionNone
+	}
+	return compression
+}
+
+func ValidateCacheCompressionEnvironment() error {
+	if _, err := toCacheCompression(os.Getenv("RFCS_CACHE_COMPRESSION")); err != nil {
+		return fmt.Errorf("RFCS_CACHE_COMPRESSION: %v", err)
+	}
+	return nil
+}
+
+var cacheCompressions = []CacheCompression{CacheCompressionNone, CacheCompressionGzip}
+
+// CacheFileWriter writes a cache entry to a temporary file, compressing it
+// and computing the checksum of the uncompressed content on the way, and
+// moves it into place on Commit.
+type CacheFileWriter struct {
+	cacheFile   string
+	compression CacheCompression
+	lockDir     string
+	tmp         *os.File
+	compressor  io.WriteCloser
+	hash        hash.Hash
+	w           io.Writer
+}
+
+func createCacheFile(cacheFile string, compression CacheCompression) (*CacheFileWriter, error) {
+	dir, base := filepath.Split(cacheFile)
+	if dir == "" {
+		dir = "."
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+base+".tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	w := CacheFileWriter{
+		cacheFile:   cacheFile,
+		compression: compression,
+		tmp:         tmp,
+		hash:        sha256.New(),
+	}
+
+	switch compression {
+	case CacheCompressionNone:
+		w.w = tmp
+	case CacheCompressionGzip:
+		w.compressor = gzip.NewWriter(tmp)
+		w.w = w.compressor
+	default:
+		w.Abort()
+		return nil, fmt.Errorf("unknown cache compression: %v", compression)
+	}
+
+	return &w, nil
+}
+
+func (w *CacheFileWriter) Write(p []byte) (int, error) {
+	w.hash.Write(p)
+	return w.w.Write(p)
+}
+
+func (w *CacheFileWriter) Checksum() string {
+	return hex.EncodeToString(w.hash.Sum(nil))
+}
+
+func (w *CacheFileWriter) Abort() {
+	w.tmp.Close()
+	os.Remove(w.tmp.Name())
+}
+
+// Commit moves the entry into place, removing any copy stored with another
+// compression. If the writer was created by a cache store, the cache
+// directory is locked while doing so.
+func (w *CacheFileWriter) Commit() error {
+	if w.compressor != nil {
+		if err := w.compressor.Close(); err != nil {
+			w.Abort()
+			return err
+		}
+	}
+
+	if err := w.tmp.Sync(); err != nil {
+		w.Abort()
+		return err
+	}
+
+	if err := w.tmp.Close(); err != nil {
+		os.Remove(w.tmp.Name())
+		return err
+	}
+
+	if err := os.Chmod(w.tmp.Name(), 0644); err != nil {
+		os.Remove(w.tmp.Name())
+		return err
+	}
+
+	if w.lockDir != "" {
+		lock, err := lockCacheDirectory(w.lockDir, true)
+		if err != nil {
+			os.Remove(w.tmp.Name())
+			return err
+		}
+		defer lock.Unlock()
+	}
+
+	if err := os.Rename(w.tmp.Name(), w.cacheFile+w.compression.Extension()); err != nil {
+		os.Remove(w.tmp.Name())
+		return err
+	}
+
+	if err := WriteFileAtomic(checksumFileFor(w.cacheFile), []byte(w.Checksum()+"\n"), 0644); err != nil {
+		return err
+	}
+
+	for _, other := range cacheCompressions {
+		if other != w.compression {
+			if err := os.Remove(w.cacheFile + other.Extension()); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// putCacheFile stores content at cacheFile plus the extension of the
+// compression. The checksum is always computed over the uncompressed content.
+func putCacheFile(cacheFile string, content []byte, compression CacheCompression) error {
+	w, err := createCacheFile(cacheFile, compression)
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(content); err != nil {
+		w.Abort()
+		return err
+	}
+
+	return w.Commit()
+}
+
+// getCacheFile returns nil content if the cache file does not exist with any
+// compression. If the cache file has no stored checksum, hasChecksum is false
+// and the caller is responsible for validating the content in some other way.
+func getCacheFile(cacheFile string) (content []byte, hasChecksum bool, compression CacheCompression, err error) {
+	for _, compression = range cacheCompressions {
+		content, err = ioutil.ReadFile(cacheFile + compression.Extension())
+		if !os.IsNotExist(err) {
+			break
+		}
+	}
+	if os.IsNotExist(err) {
+		return nil, false, CacheCompressionNone, nil
+	} else if err != nil {
+		return nil, false, compression, err
+	}
+
+	if content, err = compression.decompress(content); err != nil {
+		return nil, false, compression, err
+	}
+
+	checksum, err := ioutil.ReadFile(checksumFileFor(cacheFile))
+	if os.IsNotExist(err) {
+		return content, false, compression, nil
+	} else if err != nil {
+		return nil, false, compression, err
+	}
+
+	if !bytes.Equal(bytes.TrimSpace(checksum), []byte(checksumOf(content))) {
+		return nil, true, compression, ErrCorruptCacheEntry
+	}
+
+	return content, true, compression, nil
+}
+
+// openCacheFile is like getCacheFile, but returns a reader of the
+// uncompressed content which verifies the checksum when it reaches the end,
+// returning ErrCorruptCacheEntry instead of io.EOF if it does not match.
+func openCacheFile(cacheFile string) (r io.ReadCloser, hasChecksum bool, compression CacheCompression, err error) {
+	var f *os.File
+	for _, compression = range cacheCompressions {
+		f, err = os.Open(cacheFile + compression.Extension())
+		if !os.IsNotExist(err) {
+			break
+		}
+	}
+	if os.IsNotExist(err) {
+		return nil, false, CacheCompressionNone, nil
+	} else if err != nil {
+		return nil, false, compression, err
+	}
+
+	checksum, err := ioutil.ReadFile(checksumFileFor(cacheFile))
+	if err != nil && !os.IsNotExist(err) {
+		f.Close()
+		return nil, false, compression, err
+	}
+
+	reader := verifyingCacheReader{
+		file:     f,
+		r:        f,
+		hash:     sha256.New(),
+		checksum: string(bytes.TrimSpace(checksum)),
+	}
+
+	switch compression {
+	case CacheCompressionNone:
+	case CacheCompressionGzip:
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, false, compression, ErrCorruptCacheEntry
+		}
+		reader.r = gz
+	default:
+		f.Close()
+		return nil, false, compression, fmt.Errorf("unknown cache compression: %v", compression)
+	}
+
+	return &reader, checksum != nil, compression, nil
+}
+
+type verifyingCacheReader struct {
+	file     *os.File
+	r        io.Reader
+	hash     hash.Hash
+	checksum string
+}
+
+func (r *verifyingCacheReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.hash.Write(p[:n])
+
+	if err == io.EOF && r.checksum != "" && hex.EncodeToString(r.hash.Sum(nil)) != r.checksum {
+		return n, ErrCorruptCacheEntry
+	} else if err != nil && err != io.EOF {
+		return n, ErrCorruptCacheEntry
+	}
+
+	return n, err
+}
+
+func (r *verifyingCacheReader) Close() error {
+	return r.file.Close()
+}
+
+func statCacheFile(cacheFile string) (os.FileInfo, error) {
+	var info os.FileInfo
+	var err error
+
+	for _, compression := range cacheCompressions {
+		if info, err = os.Stat(cacheFile + compression.Extension()); !os.IsNotExist(err) {
+			break
+		}
+	}
+
+	return info, err
+}
+
+// The modification time of the checksum file records when the entry was last
+// used, so that the least recently used entries can be pruned first. The
+// cache directory is locked exclusively while doing so, so callers must not
+// hold its lock.
+func touchCacheFile(cacheDir string, cacheFile string) {
+	lock, err := lockCacheDirectory(cacheDir, true)
+	if err != nil {
+		return
+	}
+	defer lock.Unlock()
+
+	now := time.Now()
+	os.Chtimes(checksumFileFor(cacheFile), now, now)
+}
+
+func removeCacheFile(cacheFile string) error {
+	for _, compression := range cacheCompressions {
+		if err := os.Remove(cacheFile + compression.Extension()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Remove(checksumFileFor(cacheFile)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// renameCacheFile moves the entry at from, with its checksum, to to,
+// replacing any entry there.
+func renameCacheFile(from string, to string) error {
+	if err := removeCacheFile(to); err != nil {
+		return err
+	}
+
+	for _, compression := range cacheCompressions {
+		if err := os.Rename(from+compression.Extension(), to+compression.Extension()); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	if err := os.Rename(checksumFileFor(from), checksumFileFor(to)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func lockCacheDirectory(cacheDir string, exclusive bool) (*FileLock, error) {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, err
+	}
+
+	return LockFile(filepath.Join(cacheDir, cacheLockFileName), exclusive)
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestCacheDirectory(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "rfcs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// cacheDirectoryFiles returns the names of the files in a cache directory,
+// without its lock file.
+func cacheDirectoryFiles(t *testing.T, dir string) []string {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, file := range files {
+		if file.Name() != cacheLockFileName {
+			names = append(names, file.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestPutCacheFile(t *testing.T) {
+	dir := newTestCacheDirectory(t)
+	cacheFile := filepath.Join(dir, "rfc9110.txt")
+	content := []byte(strings.Repeat("HTTP Semantics\n", 100))
+
+	for _, compression := range []CacheCompression{CacheCompressionGzip, CacheCompressionNone} {
+		if err := putCacheFile(cacheFile, content, compression); err != nil {
+			t.Fatal(err)
+		}
+
+		// The copy with the other compression is replaced, and no temporary
+		// file is left behind.
+		want := []string{"rfc9110.txt" + compression.Extension(), "rfc9110.txt.sha256"}
+		if got := cacheDirectoryFiles(t, dir); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v: got files %v, want %v", compression, got, want)
+		}
+
+		checksum, err := ioutil.ReadFile(checksumFileFor(cacheFile))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := string(checksum), checksumOf(content)+"\n"; got != want {
+			t.Errorf("%v: got checksum %q, want %q", compression, got, want)
+		}
+
+		got, hasChecksum, gotCompression, err := getCacheFile(cacheFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, content) || !hasChecksum || gotCompression != compression {
+			t.Errorf("%v: got %d bytes, checksum %v, compression %v", compression, len(got), hasChecksum, gotCompression)
+		}
+	}
+
+	// Gzip actually compresses the entry.
+	if err := putCacheFile(cacheFile, content, CacheCompressionGzip); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(cacheFile + ".gz"); err != nil || info.Size() >= int64(len(content)) {
+		t.Errorf("got compressed entry %v, %v", info, err)
+	}
+}
+
+func TestGetCacheFileCorruptEntry(t *testing.T) {
+	content := []byte(strings.Repeat("HTTP Semantics\n", 100))
+
+	tests := []struct {
+		name        string
+		compression CacheCompression
+		corrupt     func(data []byte) []byte
+	}{
+		{"truncated", CacheCompressionNone, func(data []byte) []byte { return data[:len(data)/2] }},
+		{"modified", CacheCompressionNone, func(data []byte) []byte { return bytes.Replace(data, []byte("HTTP"), []byte("HTTX"), 1) }},
+		{"truncated gzip", CacheCompressionGzip, func(data []byte) []byte { return data[:len(data)/2] }},
+		{"not gzip", CacheCompressionGzip, func(data []byte) []byte { return content }},
+	}
+
+	for _, test := range tests {
+		dir := newTestCacheDirectory(t)
+		cacheFile := filepath.Join(dir, "rfc9110.txt")
+		if err := putCacheFile(cacheFile, content, test.compression); err != nil {
+			t.Fatal(err)
+		}
+
+		path := cacheFile + test.compression.Extension()
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, test.corrupt(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, _, _, err := getCacheFile(cacheFile); err != ErrCorruptCacheEntry {
+			t.Errorf("%s: got %d bytes, %v, want ErrCorruptCacheEntry", test.name, len(got), err)
+		}
+
+		r, _, _, err := openCacheFile(cacheFile)
+		if err == nil {
+			_, err = ioutil.ReadAll(r)
+			r.Close()
+		}
+		if err != ErrCorruptCacheEntry {
+			t.Errorf("%s: got %v reading the entry, want ErrCorruptCacheEntry", test.name, err)
+		}
+	}
+}
+
+func TestGetCacheFileWithoutChecksum(t *testing.T) {
+	dir := newTestCacheDirectory(t)
+	cacheFile := filepath.Join(dir, "rfc9110.txt")
+
+	if content, _, _, err := getCacheFile(cacheFile); content != nil || err != nil {
+		t.Errorf("got %q, %v for a missing entry", content, err)
+	}
+
+	if err := ioutil.WriteFile(cacheFile, []byte("HTTP Semantics\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	content, hasChecksum, _, err := getCacheFile(cacheFile)
+	if err != nil || hasChecksum || string(content) != "HTTP Semantics\n" {
+		t.Errorf("got %q, checksum %v, %v", content, hasChecksum, err)
+	}
+}
+
+func TestRFCContentRepositoryEvictsCorruptEntry(t *testing.T) {
+	store := &RFCContentCacheStore{CacheDirectory: newTestCacheDirectory(t), FileFormat: RFCContentFileFormatASCII}
+	if err := store.Put(9110, []byte("HTTP Semantics\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	cacheFile := filepath.Join(store.CacheDirectory, "rfc9110.txt")
+	if err := ioutil.WriteFile(cacheFile, []byte("HTTP Sem"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	repository := DefaultRFCContentRepository{CacheStore: store}
+	if content, err := repository.FindByNumber(9110); err != ErrRFCContentNotCached {
+		t.Errorf("got %q, %v, want ErrRFCContentNotCached", content, err)
+	}
+
+	if got := cacheDirectoryFiles(t, store.CacheDirectory); len(got) != 0 {
+		t.Errorf("got files %v after evicting the entry", got)
+	}
+}
+
+func TestRFCContentCacheStoreConcurrentWriters(t *testing.T) {
+	store := &RFCContentCacheStore{CacheDirectory: newTestCacheDirectory(t), FileFormat: RFCContentFileFormatASCII}
+
+	contents := []string{
+		strings.Repeat("first writer\n", 10000),
+		strings.Repeat("second writer\n", 10000),
+	}
+
+	var wg sync.WaitGroup
+	for i, content := range contents {
+		wg.Add(1)
+		go func(compression CacheCompression, content []byte) {
+			defer wg.Done()
+			for n := 0; n < 20; n++ {
+				if err := (&RFCContentCacheStore{CacheDirectory: store.CacheDirectory, Compression: compression}).Put(9110, content); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(cacheCompressions[i], []byte(content))
+	}
+	wg.Wait()
+
+	content, err := store.Get(9110)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != contents[0] && string(content) != contents[1] {
+		t.Errorf("got mixed content of %d bytes", len(content))
+	}
+
+	if got := cacheDirectoryFiles(t, store.CacheDirectory); len(got) != 2 {
+		t.Errorf("got files %v, want the entry and its checksum", got)
+	}
+}
+
+func TestRFCIndexLoaderWritesFetchedIndex(t *testing.T) {
+	doc := readRFCIndexFixture(t)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/in-notes/rfc-index.xml":
+			w.Write(doc)
+		case "/in-notes/rfc-index.txt":
+			w.Write([]byte("9110 HTTP Semantics. (Format: TXT=512165 bytes)\n"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	defer os.Setenv(envBaseURL, os.Getenv(envBaseURL))
+	os.Setenv(envBaseURL, ts.URL)
+
+	store := &RFCIndexCacheStore{CacheDirectory: newTestCacheDirectory(t), Compression: CacheCompressionGzip}
+	loader := RFCIndexLoader{
+		DataFormat: RFCIndexDataFormatXML,
+		CacheStore: store,
+		Fetcher:    &RFCIndexFetcher{DataFormat: RFCIndexDataFormatXML},
+	}
+
+	rfcIndex, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := len(rfcIndex.RFCEntries), 7; got != want {
+		t.Errorf("got %d RFC entries, want %d", got, want)
+	}
+
+	// The index is written to the cache as it is parsed.
+	if cached, err := store.Get(RFCIndexDataFormatXML); err != nil || !bytes.Equal(cached, doc) {
+		t.Errorf("got cached index of %d bytes, %v, want %d bytes", len(cached), err, len(doc))
+	}
+	if checksum, err := store.Checksum(RFCIndexDataFormatXML); err != nil || checksum != checksumOf(doc) {
+		t.Errorf("got checksum %q, %v", checksum, err)
+	}
+
+	// An index failing to parse, here a text index entry without a date, is
+	// not cached.
+	store = &RFCIndexCacheStore{CacheDirectory: newTestCacheDirectory(t)}
+	loader.CacheStore = store
+	loader.DataFormat = RFCIndexDataFormatASCII
+	loader.Fetcher = &RFCIndexFetcher{DataFormat: RFCIndexDataFormatASCII}
+
+	if _, err := loader.Load(); err == nil {
+		t.Error("got no error for an invalid index")
+	}
+	if got := cacheDirectoryFiles(t, store.CacheDirectory); len(got) != 0 {
+		t.Errorf("got files %v after a failed fetch", got)
+	}
+}
+
+func TestCachePruneCommandRemovesLeastRecentlyUsed(t *testing.T) {
+	contentStore := &RFCContentCacheStore{CacheDirectory: newTestCacheDirectory(t)}
+	draftStore := &DraftCacheStore{CacheDirectory: newTestCacheDirectory(t)}
+
+	now := time.Now()
+	used := func(cacheFile string, age time.Duration) {
+		if err := os.Chtimes(checksumFileFor(cacheFile), now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, number := range []int{2616, 9110, 3986} {
+		if err := contentStore.Put(number, []byte(fmt.Sprintf("RFC %d\n", number))); err != nil {
+			t.Fatal(err)
+		}
+		used(filepath.Join(contentStore.CacheDirectory, fmt.Sprintf("rfc%d.txt", number)), time.Duration(3-i)*time.Hour)
+	}
+	if err := draftStore.Put("draft-ietf-httpbis-semantics", "19", []byte("draft\n")); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := draftStore.Entries()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("got draft entries %v, %v", entries, err)
+	}
+	used(filepath.Join(draftStore.CacheDirectory, entries[0].FileName()), 90*time.Minute)
+
+	// RFC 2616 was used 3 hours ago, RFC 9110 2 hours ago, the draft 90
+	// minutes ago and RFC 3986 an hour ago.
+	command := CachePruneCommand{
+		ContentCacheStore: contentStore,
+		DraftCacheStore:   draftStore,
+		Keep:              2,
+	}
+	if err := command.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := cacheDirectoryFiles(t, contentStore.CacheDirectory), []string{"rfc3986.txt", "rfc3986.txt.sha256"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got RFC files %v, want %v", got, want)
+	}
+	if entries, err := draftStore.Entries(); err != nil || len(entries) != 1 {
+		t.Errorf("got draft entries %v, %v", entries, err)
+	}
+
+	// Reading an entry marks it as used.
+	if _, err := contentStore.Get(3986); err != nil {
+		t.Fatal(err)
+	}
+	command = CachePruneCommand{
+		ContentCacheStore: contentStore,
+		DraftCacheStore:   draftStore,
+		OlderThan:         30 * time.Minute,
+	}
+	if err := command.Execute(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := cacheDirectoryFiles(t, contentStore.CacheDirectory); len(got) != 2 {
+		t.Errorf("got RFC files %v, want RFC 3986 kept", got)
+	}
+	if entries, err := draftStore.Entries(); err != nil || len(entries) != 0 {
+		t.Errorf("got draft entries %v, %v, want none", entries, err)
+	}
+}
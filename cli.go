@@ -4,76 +4,191 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/exec"
 	"strconv"
+	"strings"
 )
 
-func usageListRFCs(f *flag.FlagSet) func() {
-	return func() {
-		fmt.Println("Usage: rfcs list [options]")
-		fmt.Println("")
-		fmt.Println("Options:")
-		f.PrintDefaults()
+// listOptions are the options of rfcs list, which saved queries are made of.
+type listOptions struct {
+	selectOptions  SelectOptions
+	displayOptions DisplayOptions
+	category       string
+	stream         string
+	indexFormat    string
+	fzf            bool
+	preset         string
+}
+
+func (o *listOptions) newFlagSet(name string) *flag.FlagSet {
+	outputTemplate := os.Getenv(envFormat)
+	if outputTemplate == "" {
+		outputTemplate = "{{.DocumentID}} {{.Title}}"
 	}
+
+	f := flag.NewFlagSet(name, flag.ContinueOnError)
+
+	f.BoolVar(&o.selectOptions.ExcludeObsolete, "exclude-obsolete", false, "Exclude obsolete RFCs")
+	f.IntVar(&o.selectOptions.ObsoletedBy, "obsoleted-by", 0, "List RFCs obsoleted by the specified RFC")
+	f.IntVar(&o.selectOptions.Obsolete, "obsolete", 0, "List RFCs obsoleting the specified RFC")
+	f.IntVar(&o.selectOptions.UpdatedBy, "updated-by", 0, "List RFCs updated by the specified RFC")
+	f.IntVar(&o.selectOptions.Update, "update", 0, "List RFCs updating the specified RFC")
+	f.IntVar(&o.selectOptions.STDNumber, "std", 0, "List RFCs labeled with the specified STD")
+	f.IntVar(&o.selectOptions.BCPNumber, "bcp", 0, "List RFCs labeled with the specified BCP")
+	f.IntVar(&o.selectOptions.FYINumber, "fyi", 0, "List RFCs labeled with the specified FYI")
+	f.StringVar(&o.category, "category", "", "List RFCs with the specified category ("+strings.Join(rfcCategoryNames, ", ")+")")
+	f.StringVar(&o.stream, "stream", "", "List RFCs in the specified document stream ("+strings.Join(rfcStreamNames, ", ")+")")
+	f.StringVar(&o.selectOptions.WorkingGroup, "wg", "", "List RFCs of the specified working group, such as httpbis")
+	f.BoolVar(&o.displayOptions.SortByPublicationDate, "sort-by-date", false, "Sort by publication date")
+	f.StringVar(&o.indexFormat, "index-format", "xml", "Read RFCs from the RFC index in the specified format (xml, txt)")
+	f.StringVar(&o.displayOptions.OutputTemplate, "format", outputTemplate, "Format output of each RFC using the given Go template ($"+envFormat+")")
+	f.BoolVar(&o.fzf, "fzf", false, "Format output for fzf, to be used with --delimiter '\\t' --with-nth 2.. --preview 'rfcs preview {}'")
+	f.StringVar(&o.preset, "preset", "", "Apply the options saved under the given name in the presets of the config file")
+
+	return f
 }
 
-func listRFCs(Args []string) error {
-	var selectOptions SelectOptions
-	var displayOptions DisplayOptions
-	var category string
-	var stream string
-
-	f := flag.NewFlagSet("list", flag.ContinueOnError)
-	f.Usage = usageListRFCs(f)
-
-	f.BoolVar(&selectOptions.ExcludeObsolete, "exclude-obsolete", false, "Exclude obsolete RFCs")
-	f.IntVar(&selectOptions.ObsoletedBy, "obsoleted-by", 0, "List RFCs obsoleted by the specified RFC")
-	f.IntVar(&selectOptions.Obsolete, "obsolete", 0, "List RFCs obsoleting the specified RFC")
-	f.IntVar(&selectOptions.UpdatedBy, "updated-by", 0, "List RFCs updated by the specified RFC")
-	f.IntVar(&selectOptions.Update, "update", 0, "List RFCs updating the specified RFC")
-	f.IntVar(&selectOptions.STDNumber, "std", 0, "List RFCs labeled with the specified STD")
-	f.IntVar(&selectOptions.BCPNumber, "bcp", 0, "List RFCs labeled with the specified BCP")
-	f.IntVar(&selectOptions.FYINumber, "fyi", 0, "List RFCs labeled with the specified FYI")
-	f.StringVar(&category, "category", "", "List RFCs with the specified category")
-	f.StringVar(&stream, "stream", "", "List RFCs in the specified document stream")
-	f.BoolVar(&displayOptions.SortByPublicationDate, "sort-by-date", false, "Sort by publication date")
-	f.StringVar(&displayOptions.OutputTemplate, "format", "{{.DocumentID}} {{.Title}}", "Format output of each RFC using the given Go template")
-
-	if err := f.Parse(Args); err != nil {
-		return nil
-	}
-
-	if category != "" {
-		rfcCategory, err := toRFCCategory(category)
+func (o *listOptions) listFlagSet() *flag.FlagSet {
+	f := o.newFlagSet("rfcs list")
+	f.Usage = usageFor(f, "[@<query>]", "A saved query, or a preset, applies the options saved with rfcs query save. Options given with it override them.")
+
+	return f
+}
+
+func (o *listOptions) querySaveFlagSet() *flag.FlagSet {
+	f := o.newFlagSet("rfcs query save")
+	f.Usage = usageFor(f, "<name>", "Saves options of rfcs list under the name, to be run with rfcs list @<name>.")
+
+	return f
+}
+
+// listRFCFlags are the flags of rfcs list taking an RFC number.
+var listRFCFlags = []string{"obsoleted-by", "obsolete", "updated-by", "update"}
+
+// resolve checks the values of the options and converts them for
+// SelectOptions.
+func (o *listOptions) resolve() error {
+	if o.category != "" {
+		rfcCategory, err := toRFCCategory(o.category)
+		if err != nil {
+			return err
+		}
+
+		o.selectOptions.Category = &rfcCategory
+	}
+
+	if o.stream != "" {
+		rfcStream, err := toRFCStream(o.stream)
 		if err != nil {
 			return err
 		}
 
-		selectOptions.Category = &rfcCategory
+		o.selectOptions.Stream = &rfcStream
+	}
+
+	_, err := toRFCIndexDataFormat(o.indexFormat)
+	return err
+}
+
+func listRFCs(Args []string) error {
+	var options listOptions
+	f := options.listFlagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	// A saved query may be given among the options, which stop being parsed
+	// at it.
+	if f.NArg() > 0 {
+		if !strings.HasPrefix(f.Arg(0), "@") {
+			return usageError(f, fmt.Errorf("unexpected argument: %s", f.Arg(0)))
+		}
+		if options.preset != "" {
+			return usageError(f, fmt.Errorf("more than one query given: %s and %s", options.preset, f.Arg(0)))
+		}
+
+		options.preset = strings.TrimPrefix(f.Arg(0), "@")
+		Args = append(Args[:len(Args)-f.NArg():len(Args)-f.NArg()], f.Args()[1:]...)
 	}
 
-	if stream != "" {
-		rfcStream, err := toRFCStream(stream)
+	// The options of the preset come first so that the arguments override
+	// them.
+	if options.preset != "" {
+		config, err := LoadConfig()
+		if err != nil {
+			return err
+		}
+
+		presetArgs, err := config.Preset(options.preset)
 		if err != nil {
+			return usageError(f, err)
+		}
+
+		preset := options.preset
+		if err := parseFlags(f, append(presetArgs, Args...)); err != nil {
 			return err
 		}
+		if options.preset != preset {
+			return usageError(f, fmt.Errorf("more than one query given: %s and %s", preset, options.preset))
+		}
+		if f.NArg() > 0 {
+			return usageError(f, fmt.Errorf("unexpected argument: %s", f.Arg(0)))
+		}
+	}
+
+	if err := options.resolve(); err != nil {
+		return usageError(f, err)
+	}
 
-		selectOptions.Stream = &rfcStream
+	displayOptions := options.displayOptions
+	if options.fzf {
+		displayOptions.OutputTemplate = fzfLineTemplate
 	}
 
-	repository, err := NewRFCIndexRFCRepository()
+	// JSON output is the default if the output is set to json, unless the
+	// format of the lines is given.
+	displayOptions.JSON = os.Getenv(envOutput) == "json"
+	f.Visit(func(flag *flag.Flag) {
+		if flag.Name == "format" || flag.Name == "fzf" {
+			displayOptions.JSON = false
+		}
+	})
+
+	rfcIndexDataFormat, _ := toRFCIndexDataFormat(options.indexFormat)
+	repository, err := NewRFCIndexRFCRepository(rfcIndexDataFormat)
 	if err != nil {
 		return err
 	}
 
 	command := ListCommand{
 		RFCRepository:  repository,
-		SelectOptions:  selectOptions,
+		SelectOptions:  options.selectOptions,
 		DisplayOptions: displayOptions,
 	}
 
 	return command.Execute()
 }
 
+var rfcCategoryNames = []string{
+	"proposed-standard",
+	"draft-standard",
+	"internet-standard",
+	"experimental",
+	"informational",
+	"historic",
+	"bcp",
+	"unknown",
+}
+
+var rfcStreamNames = []string{
+	"ietf",
+	"iab",
+	"irtf",
+	"independent",
+	"legacy",
+}
+
 func toRFCCategory(category string) (RFCCategory, error) {
 	switch category {
 	case "proposed-standard":
@@ -114,30 +229,45 @@ func toRFCStream(stream string) (RFCStream, error) {
 	}
 }
 
-func usageGetRFC(f *flag.FlagSet) func() {
-	return func() {
-		fmt.Println("Usage: rfcs get <RFC number>")
-	}
+type getOptions struct {
+	links      string
+	withErrata bool
+	errataURL  string
+	pager      string
+}
+
+func (o *getOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs get", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<RFC number>")
+
+	f.StringVar(&o.links, "links", "none", "Render references to RFCs and sections as links (none, osc8, footnotes)")
+	f.BoolVar(&o.withErrata, "with-errata", false, "Show errata below the headings of the sections they apply to")
+	f.StringVar(&o.errataURL, "errata-url", "", "Fetch errata from the given URL instead of the RFC Editor")
+	f.StringVar(&o.pager, "pager", os.Getenv(envPager), "Show the RFC with the given pager on a terminal ($"+envPager+")")
+
+	return f
 }
 
 func getRFC(Args []string) error {
-	f := flag.NewFlagSet("get", flag.ContinueOnError)
-	f.Usage = usageGetRFC(f)
+	var options getOptions
+	f := options.flagSet()
 
-	if err := f.Parse(Args); err != nil {
-		return nil
+	if err := parseFlags(f, Args); err != nil {
+		return err
 	}
 
 	if f.NArg() < 1 {
-		f.Usage()
-		return nil
+		return usageError(f, nil)
 	}
 
-	rfcNumber, err := strconv.Atoi(Args[0])
+	rfcNumber, err := parseRFCNumber(f.Arg(0))
 	if err != nil {
-		fmt.Println(err)
-		f.Usage()
-		return nil
+		return usageError(f, err)
+	}
+
+	linkStyle, err := toRFCReferenceStyle(options.links)
+	if err != nil || linkStyle == RFCReferenceStyleHTML {
+		return usageError(f, fmt.Errorf("unknown link style: %s", options.links))
 	}
 
 	repository := NewDefaultRFCContentRepository()
@@ -145,35 +275,1657 @@ func getRFC(Args []string) error {
 	command := GetCommand{
 		RFCContentRepository: repository,
 		RFCNumber:            rfcNumber,
+		LinkStyle:            linkStyle,
+		Pager:                options.pager,
+	}
+
+	if linkStyle != RFCReferenceStyleNone {
+		rfcRepository, err := NewRFCIndexRFCRepository(RFCIndexDataFormatXML)
+		if err != nil {
+			return err
+		}
+		command.RFCRepository = rfcRepository
+	}
+
+	if options.withErrata {
+		command.ErrataRepository = NewRFCErrataRepository()
+		if options.errataURL != "" {
+			command.ErrataRepository.Fetcher.URL = options.errataURL
+		}
+	}
+
+	return command.Execute()
+}
+
+type citeOptions struct {
+	style string
+}
+
+func (o *citeOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs cite", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<ID>...", "IDs are RFC numbers such as 9110 or RFC9110, or series such as BCP14 and STD97.")
+
+	f.StringVar(&o.style, "style", "markdown", "Citation style (bibtex, ris, csl-json, xml2rfc, markdown)")
+
+	return f
+}
+
+func citeRFCs(Args []string) error {
+	var options citeOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	if f.NArg() < 1 {
+		return usageError(f, nil)
+	}
+
+	citationStyle, err := toCitationStyle(options.style)
+	if err != nil {
+		return usageError(f, err)
+	}
+
+	repository, err := NewRFCIndexRFCRepository(RFCIndexDataFormatXML)
+	if err != nil {
+		return err
+	}
+
+	command := CiteCommand{
+		RFCRepository: repository,
+		IDs:           f.Args(),
+		Style:         citationStyle,
+	}
+
+	return command.Execute()
+}
+
+type refsOptions struct {
+	format string
+}
+
+func (o *refsOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs refs", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<RFC number>")
+
+	formatVar(f, &o.format, "text", "Output format (text, json)")
+
+	return f
+}
+
+func listRefs(Args []string) error {
+	var options refsOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	if f.NArg() < 1 {
+		return usageError(f, nil)
+	}
+
+	rfcNumber, err := parseRFCNumber(f.Arg(0))
+	if err != nil {
+		return usageError(f, err)
+	}
+
+	outputFormat, err := toOutputFormat(jsonFormat(f, options.format))
+	if err != nil || outputFormat != OutputFormatText && outputFormat != OutputFormatJSON {
+		return usageError(f, fmt.Errorf("unknown output format: %s", options.format))
+	}
+
+	rfcRepository, err := NewRFCIndexRFCRepository(RFCIndexDataFormatXML)
+	if err != nil {
+		return err
+	}
+
+	command := RefsCommand{
+		RFCContentRepository: NewDefaultRFCContentRepository(),
+		RFCRepository:        rfcRepository,
+		GraphRepository:      NewRFCCitationGraphRepository(),
+		RFCNumber:            rfcNumber,
+		Format:               outputFormat,
 	}
 
 	return command.Execute()
 }
 
-func usage() {
-	fmt.Println("Usage: rfcs command [command options] [argument]")
-	fmt.Println("")
-	fmt.Println("Commands:")
-	fmt.Println("  list    List RFCs")
-	fmt.Println("  get     Fetch RFC")
+type citedByOptions struct {
+	format string
 }
 
-func main() {
-	if len(os.Args) < 2 {
-		usage()
-		return
+func (o *citedByOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs cited-by", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<RFC number>", "Only cached RFCs are searched for citations.")
+
+	formatVar(f, &o.format, "text", "Output format (text, json)")
+
+	return f
+}
+
+func listCitedBy(Args []string) error {
+	var options citedByOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	if f.NArg() < 1 {
+		return usageError(f, nil)
+	}
+
+	rfcNumber, err := parseRFCNumber(f.Arg(0))
+	if err != nil {
+		return usageError(f, err)
+	}
+
+	outputFormat, err := toOutputFormat(jsonFormat(f, options.format))
+	if err != nil || outputFormat != OutputFormatText && outputFormat != OutputFormatJSON {
+		return usageError(f, fmt.Errorf("unknown output format: %s", options.format))
+	}
+
+	rfcRepository, err := NewRFCIndexRFCRepository(RFCIndexDataFormatXML)
+	if err != nil {
+		return err
+	}
+
+	command := CitedByCommand{
+		RFCRepository:   rfcRepository,
+		GraphRepository: NewRFCCitationGraphRepository(),
+		RFCNumber:       rfcNumber,
+		Format:          outputFormat,
+	}
+
+	return command.Execute()
+}
+
+type depsOptions struct {
+	format  string
+	updates bool
+	depth   int
+}
+
+func (o *depsOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs deps", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<RFC number>")
+
+	formatVar(f, &o.format, "tree", "Output format (tree, order, json)")
+	f.BoolVar(&o.updates, "updates", false, "Also follow RFCs updating each dependency")
+	f.IntVar(&o.depth, "depth", 0, "Follow references at most this deep (0 for no limit)")
+
+	return f
+}
+
+func listDeps(Args []string) error {
+	var options depsOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	if f.NArg() < 1 {
+		return usageError(f, nil)
+	}
+
+	rfcNumber, err := parseRFCNumber(f.Arg(0))
+	if err != nil {
+		return usageError(f, err)
+	}
+
+	dependencyFormat, err := toRFCDependencyFormat(jsonFormat(f, options.format))
+	if err != nil {
+		return usageError(f, err)
+	}
+
+	rfcRepository, err := NewRFCIndexRFCRepository(RFCIndexDataFormatXML)
+	if err != nil {
+		return err
+	}
+
+	command := DepsCommand{
+		Resolver: &RFCDependencyResolver{
+			RFCContentRepository: NewDefaultRFCContentRepository(),
+			RFCRepository:        rfcRepository,
+			FollowUpdates:        options.updates,
+			MaxDepth:             options.depth,
+		},
+		RFCNumber: rfcNumber,
+		Format:    dependencyFormat,
+	}
+
+	return command.Execute()
+}
+
+type errataOptions struct {
+	all       bool
+	refresh   bool
+	errataURL string
+	format    string
+}
+
+func (o *errataOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs errata", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<RFC number>")
+
+	f.BoolVar(&o.all, "all", false, "Also list rejected errata")
+	f.BoolVar(&o.refresh, "refresh", false, "Fetch the errata again instead of using the cached copy")
+	f.StringVar(&o.errataURL, "errata-url", "", "Fetch errata from the given URL instead of the RFC Editor")
+	formatVar(f, &o.format, "text", "Output format (text, json)")
+
+	return f
+}
+
+func listErrata(Args []string) error {
+	var options errataOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	if f.NArg() < 1 {
+		return usageError(f, nil)
+	}
+
+	rfcNumber, err := parseRFCNumber(f.Arg(0))
+	if err != nil {
+		return usageError(f, err)
+	}
+
+	outputFormat, err := toOutputFormat(jsonFormat(f, options.format))
+	if err != nil || outputFormat != OutputFormatText && outputFormat != OutputFormatJSON {
+		return usageError(f, fmt.Errorf("unknown output format: %s", options.format))
+	}
+
+	repository := NewRFCErrataRepository()
+	repository.Refresh = options.refresh
+	if options.errataURL != "" {
+		repository.Fetcher.URL = options.errataURL
 	}
 
-	var err error
-	if os.Args[1] == "list" {
-		err = listRFCs(os.Args[2:])
-	} else if os.Args[1] == "get" {
-		err = getRFC(os.Args[2:])
-	} else {
-		usage()
+	command := ErrataCommand{
+		ErrataRepository: repository,
+		RFCNumber:        rfcNumber,
+		IncludeRejected:  options.all,
+		Format:           outputFormat,
 	}
 
+	return command.Execute()
+}
+
+type requirementsOptions struct {
+	format string
+}
+
+func (o *requirementsOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs requirements", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<RFC number>")
+
+	formatVar(f, &o.format, "markdown", "Output format (markdown, csv, json)")
+
+	return f
+}
+
+func listRequirements(Args []string) error {
+	var options requirementsOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	if f.NArg() < 1 {
+		return usageError(f, nil)
+	}
+
+	rfcNumber, err := parseRFCNumber(f.Arg(0))
+	if err != nil {
+		return usageError(f, err)
+	}
+
+	outputFormat, err := toOutputFormat(jsonFormat(f, options.format))
+	if err != nil || outputFormat == OutputFormatText {
+		return usageError(f, fmt.Errorf("unknown output format: %s", options.format))
+	}
+
+	command := RequirementsCommand{
+		RFCContentRepository: NewDefaultRFCContentRepository(),
+		RFCNumber:            rfcNumber,
+		Format:               outputFormat,
+	}
+
+	return command.Execute()
+}
+
+type traceOptions struct {
+	format string
+}
+
+func (o *traceOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs trace", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<RFC number> <path>...")
+
+	formatVar(f, &o.format, "text", "Output format (text, json)")
+
+	return f
+}
+
+func traceRequirements(Args []string) error {
+	var options traceOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	if f.NArg() < 2 {
+		return usageError(f, nil)
+	}
+
+	rfcNumber, err := parseRFCNumber(f.Arg(0))
+	if err != nil {
+		return usageError(f, err)
+	}
+
+	outputFormat, err := toOutputFormat(jsonFormat(f, options.format))
+	if err != nil || outputFormat != OutputFormatText && outputFormat != OutputFormatJSON {
+		return usageError(f, fmt.Errorf("unknown output format: %s", options.format))
+	}
+
+	command := TraceCommand{
+		RFCContentRepository: NewDefaultRFCContentRepository(),
+		RFCNumber:            rfcNumber,
+		Paths:                f.Args()[1:],
+		Format:               outputFormat,
+	}
+
+	return command.Execute()
+}
+
+type diffOptions struct {
+	format  string
+	width   int
+	context int
+}
+
+func (o *diffOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs diff", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<old RFC number, draft or file> <new RFC number, draft or file>")
+
+	f.StringVar(&o.format, "format", "unified", "Output format (unified, side-by-side, html)")
+	f.IntVar(&o.width, "width", 0, "Width of the output in columns (default 80, or 160 side by side)")
+	f.IntVar(&o.context, "context", 1, "Number of unchanged sentences to show around changes")
+
+	return f
+}
+
+func diffRFCs(Args []string) error {
+	var options diffOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	if f.NArg() < 2 {
+		return usageError(f, nil)
+	}
+
+	diffFormat, err := toRFCDiffFormat(options.format)
 	if err != nil {
-		fmt.Println(err)
+		return usageError(f, err)
+	}
+
+	command := DiffCommand{
+		RFCContentRepository: NewDefaultRFCContentRepository(),
+		DraftRepository:      NewDraftContentRepository(),
+		Old:                  f.Arg(0),
+		New:                  f.Arg(1),
+		Renderer: &RFCDiffRenderer{
+			Format:  diffFormat,
+			Color:   diffFormat != RFCDiffFormatHTML && ColorEnabled(os.Stdout),
+			Width:   options.width,
+			Context: options.context,
+		},
 	}
+
+	return command.Execute()
+}
+
+func newDraftContentRepository(draftURL string) *DraftContentRepository {
+	repository := NewDraftContentRepository()
+	if draftURL != "" {
+		repository.Fetcher.BaseURL = draftURL
+	}
+	return repository
+}
+
+type draftGetOptions struct {
+	draftURL string
+}
+
+func (o *draftGetOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs draft get", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<name>[-NN]")
+
+	f.StringVar(&o.draftURL, "draft-url", "", "Fetch drafts from the given base URL instead of the IETF archive")
+
+	return f
+}
+
+func draftGet(Args []string) error {
+	var options draftGetOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	if f.NArg() < 1 {
+		return usageError(f, nil)
+	}
+
+	name, revision, err := ParseDraftName(f.Arg(0))
+	if err != nil {
+		return usageError(f, err)
+	}
+
+	command := DraftGetCommand{
+		DraftRepository: newDraftContentRepository(options.draftURL),
+		Name:            name,
+		Revision:        revision,
+	}
+
+	return command.Execute()
+}
+
+type draftListOptions struct {
+	format string
+}
+
+func (o *draftListOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs draft list", flag.ContinueOnError)
+	f.Usage = usageFor(f, "")
+
+	formatVar(f, &o.format, "text", "Output format (text, json)")
+
+	return f
+}
+
+func draftList(Args []string) error {
+	var options draftListOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	outputFormat, err := toOutputFormat(jsonFormat(f, options.format))
+	if err != nil || outputFormat != OutputFormatText && outputFormat != OutputFormatJSON {
+		return usageError(f, fmt.Errorf("unknown output format: %s", options.format))
+	}
+
+	command := DraftListCommand{
+		CacheStore: NewDraftCacheStore(),
+		Format:     outputFormat,
+	}
+
+	// The index is only used to show which drafts were published as RFCs,
+	// so listing works without it.
+	if repository, err := NewRFCIndexRFCRepository(RFCIndexDataFormatXML); err == nil {
+		command.RFCRepository = repository
+	}
+
+	return command.Execute()
+}
+
+type draftDiffOptions struct {
+	draftURL string
+	format   string
+	width    int
+	context  int
+}
+
+func (o *draftDiffOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs draft diff", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<name>[-NN] [<name>-MM]", "With a single draft, compares it with its previous revision.")
+
+	f.StringVar(&o.draftURL, "draft-url", "", "Fetch drafts from the given base URL instead of the IETF archive")
+	f.StringVar(&o.format, "format", "unified", "Output format (unified, side-by-side, html)")
+	f.IntVar(&o.width, "width", 0, "Width of the output in columns (default 80, or 160 side by side)")
+	f.IntVar(&o.context, "context", 1, "Number of unchanged sentences to show around changes")
+
+	return f
+}
+
+func draftDiff(Args []string) error {
+	var options draftDiffOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	if f.NArg() < 1 {
+		return usageError(f, nil)
+	}
+
+	diffFormat, err := toRFCDiffFormat(options.format)
+	if err != nil {
+		return usageError(f, err)
+	}
+
+	repository := newDraftContentRepository(options.draftURL)
+
+	oldName, newName := f.Arg(0), f.Arg(1)
+
+	// With a single draft, compare it with its previous revision.
+	if newName == "" {
+		name, revision, err := ParseDraftName(oldName)
+		if err != nil {
+			return usageError(f, err)
+		}
+
+		if revision == "" {
+			if revision, err = repository.LatestRevision(name); err != nil {
+				return err
+			}
+		}
+
+		previous := PreviousDraftRevision(revision)
+		if previous == "" {
+			return fmt.Errorf("%s-%s is the first revision", name, revision)
+		}
+
+		oldName, newName = name+"-"+previous, name+"-"+revision
+	}
+
+	command := DiffCommand{
+		RFCContentRepository: NewDefaultRFCContentRepository(),
+		DraftRepository:      repository,
+		Old:                  oldName,
+		New:                  newName,
+		Renderer: &RFCDiffRenderer{
+			Format:  diffFormat,
+			Color:   diffFormat != RFCDiffFormatHTML && ColorEnabled(os.Stdout),
+			Width:   options.width,
+			Context: options.context,
+		},
+	}
+
+	return command.Execute()
+}
+
+type changesOptions struct {
+	refresh     bool
+	watchedOnly bool
+	format      string
+}
+
+func (o *changesOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs changes", flag.ContinueOnError)
+	f.Usage = usageFor(f, "")
+
+	f.BoolVar(&o.refresh, "refresh", false, "Fetch the RFC index first, keeping the cached one as the previous index")
+	f.BoolVar(&o.watchedOnly, "watched", false, "Only show changes to RFCs on the watch list")
+	formatVar(f, &o.format, "text", "Output format (text, json)")
+
+	return f
+}
+
+func listChanges(Args []string) error {
+	var options changesOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	outputFormat, err := toOutputFormat(jsonFormat(f, options.format))
+	if err != nil || outputFormat != OutputFormatText && outputFormat != OutputFormatJSON {
+		return usageError(f, fmt.Errorf("unknown output format: %s", options.format))
+	}
+
+	loader := NewRFCIndexLoader(RFCIndexDataFormatXML)
+	loader.Refresh = options.refresh
+
+	command := ChangesCommand{
+		Loader: loader,
+		Format: outputFormat,
+	}
+
+	if options.watchedOnly {
+		if command.Watched, err = NewWatchList().Load(); err != nil {
+			return err
+		}
+		if command.Watched == nil {
+			command.Watched = []int{}
+		}
+	}
+
+	return command.Execute()
+}
+
+func parseRFCNumber(arg string) (int, error) {
+	number, err := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(arg), "RFC"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid RFC number: %s", arg)
+	}
+	return number, nil
+}
+
+func parseRFCNumbers(args []string) ([]int, error) {
+	var numbers []int
+	for _, arg := range args {
+		number, err := parseRFCNumber(arg)
+		if err != nil {
+			return nil, err
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, nil
+}
+
+func newWatchAddFlagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs watch add", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<RFC number>...")
+
+	return f
+}
+
+func watchAdd(Args []string) error {
+	f := newWatchAddFlagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	if f.NArg() < 1 {
+		return usageError(f, nil)
+	}
+
+	numbers, err := parseRFCNumbers(f.Args())
+	if err != nil {
+		return usageError(f, err)
+	}
+
+	command := WatchAddCommand{
+		WatchList: NewWatchList(),
+		Numbers:   numbers,
+	}
+
+	return command.Execute()
+}
+
+func newWatchRemoveFlagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs watch remove", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<RFC number>...")
+
+	return f
+}
+
+func watchRemove(Args []string) error {
+	f := newWatchRemoveFlagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	if f.NArg() < 1 {
+		return usageError(f, nil)
+	}
+
+	numbers, err := parseRFCNumbers(f.Args())
+	if err != nil {
+		return usageError(f, err)
+	}
+
+	command := WatchRemoveCommand{
+		WatchList: NewWatchList(),
+		Numbers:   numbers,
+	}
+
+	return command.Execute()
+}
+
+type watchListOptions struct {
+	format string
+}
+
+func (o *watchListOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs watch list", flag.ContinueOnError)
+	f.Usage = usageFor(f, "")
+
+	formatVar(f, &o.format, "text", "Output format (text, json)")
+
+	return f
+}
+
+func watchList(Args []string) error {
+	var options watchListOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	outputFormat, err := toOutputFormat(jsonFormat(f, options.format))
+	if err != nil || outputFormat != OutputFormatText && outputFormat != OutputFormatJSON {
+		return usageError(f, fmt.Errorf("unknown output format: %s", options.format))
+	}
+
+	repository, err := NewRFCIndexRFCRepository(RFCIndexDataFormatXML)
+	if err != nil {
+		return err
+	}
+
+	command := WatchListCommand{
+		WatchList:     NewWatchList(),
+		RFCRepository: repository,
+		Format:        outputFormat,
+	}
+
+	return command.Execute()
+}
+
+type watchCheckOptions struct {
+	refresh bool
+	format  string
+}
+
+func (o *watchCheckOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs watch check", flag.ContinueOnError)
+	f.Usage = usageFor(f, "", "Exits with status 1 if watched RFCs changed.")
+
+	f.BoolVar(&o.refresh, "refresh", false, "Fetch the RFC index first, keeping the cached one as the previous index")
+	formatVar(f, &o.format, "text", "Output format (text, json)")
+
+	return f
+}
+
+func watchCheck(Args []string) error {
+	var options watchCheckOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	outputFormat, err := toOutputFormat(jsonFormat(f, options.format))
+	if err != nil || outputFormat != OutputFormatText && outputFormat != OutputFormatJSON {
+		return usageError(f, fmt.Errorf("unknown output format: %s", options.format))
+	}
+
+	watched, err := NewWatchList().Load()
+	if err != nil {
+		return err
+	}
+	if watched == nil {
+		watched = []int{}
+	}
+
+	loader := NewRFCIndexLoader(RFCIndexDataFormatXML)
+	loader.Refresh = options.refresh
+
+	command := ChangesCommand{
+		Loader:        loader,
+		Format:        outputFormat,
+		Watched:       watched,
+		FailOnChanges: true,
+	}
+
+	return command.Execute()
+}
+
+func newCacheInfoFlagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs cache info", flag.ContinueOnError)
+	f.Usage = usageFor(f, "")
+
+	return f
+}
+
+func cacheInfo(Args []string) error {
+	f := newCacheInfoFlagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	command := CacheInfoCommand{
+		ContentCacheStore: NewRFCContentCacheStore(),
+		DraftCacheStore:   NewDraftCacheStore(),
+		IndexCacheStore:   NewRFCIndexCacheStore(),
+	}
+
+	return command.Execute()
+}
+
+func newCacheListFlagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs cache list", flag.ContinueOnError)
+	f.Usage = usageFor(f, "")
+
+	return f
+}
+
+func cacheList(Args []string) error {
+	f := newCacheListFlagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	command := CacheListCommand{
+		ContentCacheStore: NewRFCContentCacheStore(),
+		DraftCacheStore:   NewDraftCacheStore(),
+	}
+
+	return command.Execute()
+}
+
+type cacheVerifyOptions struct {
+	repair bool
+}
+
+func (o *cacheVerifyOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs cache verify", flag.ContinueOnError)
+	f.Usage = usageFor(f, "")
+
+	f.BoolVar(&o.repair, "repair", false, "Remove corrupt entries so that they are fetched again")
+
+	return f
+}
+
+func cacheVerify(Args []string) error {
+	var options cacheVerifyOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	command := CacheVerifyCommand{
+		ContentCacheStore: NewRFCContentCacheStore(),
+		DraftCacheStore:   NewDraftCacheStore(),
+		IndexCacheStore:   NewRFCIndexCacheStore(),
+		Repair:            options.repair,
+	}
+
+	return command.Execute()
+}
+
+type cachePruneOptions struct {
+	olderThan string
+	maxSize   string
+	keep      int
+	dryRun    bool
+}
+
+func (o *cachePruneOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs cache prune", flag.ContinueOnError)
+	f.Usage = usageFor(f, "")
+
+	f.StringVar(&o.olderThan, "older-than", "", "Remove entries not used within the given duration (e.g. 720h, 30d, 4w)")
+	f.StringVar(&o.maxSize, "max-size", "", "Remove least recently used entries until the cache fits in the given size (e.g. 200M)")
+	f.IntVar(&o.keep, "keep", 0, "Keep only the given number of most recently used entries")
+	f.BoolVar(&o.dryRun, "dry-run", false, "Show what would be removed without removing anything")
+
+	return f
+}
+
+func cachePrune(Args []string) error {
+	var options cachePruneOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	command := CachePruneCommand{
+		ContentCacheStore: NewRFCContentCacheStore(),
+		DraftCacheStore:   NewDraftCacheStore(),
+		Keep:              options.keep,
+		DryRun:            options.dryRun,
+	}
+
+	if options.olderThan != "" {
+		duration, err := ParseDuration(options.olderThan)
+		if err != nil {
+			return usageError(f, err)
+		}
+		command.OlderThan = duration
+	}
+
+	if options.maxSize != "" {
+		size, err := ParseSize(options.maxSize)
+		if err != nil {
+			return usageError(f, err)
+		}
+		command.MaxSize = size
+	}
+
+	if command.OlderThan == 0 && command.MaxSize == 0 && command.Keep == 0 {
+		return usageError(f, nil)
+	}
+
+	return command.Execute()
+}
+
+type cacheClearOptions struct {
+	all bool
+}
+
+func (o *cacheClearOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs cache clear", flag.ContinueOnError)
+	f.Usage = usageFor(f, "")
+
+	f.BoolVar(&o.all, "all", false, "Also remove the cached RFC index and errata")
+
+	return f
+}
+
+func cacheClear(Args []string) error {
+	var options cacheClearOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	command := CacheClearCommand{
+		ContentCacheStore:  NewRFCContentCacheStore(),
+		IndexCacheStore:    NewRFCIndexCacheStore(),
+		ErrataCacheStore:   NewRFCErrataCacheStore(),
+		CitationCacheStore: NewRFCCitationGraphCacheStore(),
+		DraftCacheStore:    NewDraftCacheStore(),
+		IncludeIndex:       options.all,
+	}
+
+	return command.Execute()
+}
+
+type cacheMigrateOptions struct {
+	compression string
+}
+
+func (o *cacheMigrateOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs cache migrate", flag.ContinueOnError)
+	f.Usage = usageFor(f, "")
+
+	f.StringVar(&o.compression, "compression", CacheCompressionFromEnvironment().String(), "Compression of cached entries (none, gzip)")
+
+	return f
+}
+
+func cacheMigrate(Args []string) error {
+	var options cacheMigrateOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	cacheCompression, err := toCacheCompression(options.compression)
+	if err != nil {
+		return usageError(f, err)
+	}
+
+	contentCacheStore := NewRFCContentCacheStore()
+	contentCacheStore.Compression = cacheCompression
+
+	indexCacheStore := NewRFCIndexCacheStore()
+	indexCacheStore.Compression = cacheCompression
+
+	command := CacheMigrateCommand{
+		ContentCacheStore: contentCacheStore,
+		IndexCacheStore:   indexCacheStore,
+	}
+
+	return command.Execute()
+}
+
+func newBrowseFlagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs browse", flag.ContinueOnError)
+	f.Usage = usageFor(f, "", "Keys:", "  List:    type to search, Up/Down/PgUp/PgDn to move, Enter to read, Esc to clear or quit", "  Reader:  j/k/Space/b to scroll, Tab/Shift-Tab to select a link, Enter to follow it,", "           Left/Right to go back and forward, t for contents, q to return to the list")
+
+	return f
+}
+
+func browseRFCs(Args []string) error {
+	f := newBrowseFlagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	repository, err := NewRFCIndexRFCRepository(RFCIndexDataFormatXML)
+	if err != nil {
+		return err
+	}
+
+	command := BrowseCommand{
+		RFCRepository:        repository,
+		RFCContentRepository: NewDefaultRFCContentRepository(),
+	}
+
+	return command.Execute()
+}
+
+type pickOptions struct {
+	print bool
+}
+
+func (o *pickOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs pick", flag.ContinueOnError)
+	f.Usage = usageFor(f, "[query]", "Selects an RFC with fzf and prints it. Without fzf, the RFC best matching the query is picked.")
+
+	f.BoolVar(&o.print, "print", false, "Print the number of the selected RFC instead of the RFC")
+
+	return f
+}
+
+func pickRFC(Args []string) error {
+	var options pickOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	repository, err := NewRFCIndexRFCRepository(RFCIndexDataFormatXML)
+	if err != nil {
+		return err
+	}
+
+	command := PickCommand{
+		RFCRepository:        repository,
+		RFCContentRepository: NewDefaultRFCContentRepository(),
+		Query:                strings.Join(f.Args(), " "),
+		Print:                options.print,
+		Pager:                os.Getenv(envPager),
+	}
+
+	if path, err := exec.LookPath("fzf"); err == nil {
+		command.FZFPath = path
+	}
+
+	return command.Execute()
+}
+
+type previewOptions struct {
+	width int
+}
+
+func (o *previewOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs preview", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<line>", "Shows the RFC on a line of rfcs list --fzf, for use as fzf --preview 'rfcs preview {}'.")
+
+	f.IntVar(&o.width, "width", 0, "Width of the preview in columns (default $FZF_PREVIEW_COLUMNS or 80)")
+
+	return f
+}
+
+func previewRFC(Args []string) error {
+	var options previewOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	if f.NArg() < 1 {
+		return usageError(f, nil)
+	}
+
+	if options.width <= 0 {
+		options.width, _ = strconv.Atoi(os.Getenv("FZF_PREVIEW_COLUMNS"))
+	}
+	if options.width <= 0 {
+		options.width = 80
+	}
+
+	repository, err := NewRFCIndexRFCRepository(RFCIndexDataFormatXML)
+	if err != nil {
+		return err
+	}
+
+	command := PreviewCommand{
+		RFCRepository:     repository,
+		ContentCacheStore: NewRFCContentCacheStore(),
+		Line:              strings.Join(f.Args(), " "),
+		Width:             options.width,
+	}
+
+	return command.Execute()
+}
+
+func newPrintCompletionFlagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs completion", flag.ContinueOnError)
+	f.Usage = usageFor(f, "bash|zsh|fish", "Prints a script completing commands, flags and RFC numbers for the given shell, e.g.", "", "  source <(rfcs completion bash)", "  rfcs completion fish > ~/.config/fish/completions/rfcs.fish")
+
+	return f
+}
+
+func printCompletion(Args []string) error {
+	f := newPrintCompletionFlagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	if f.NArg() != 1 {
+		return usageError(f, nil)
+	}
+
+	shell, err := toCompletionShell(f.Arg(0))
+	if err != nil {
+		return usageError(f, err)
+	}
+
+	command := CompletionCommand{
+		Shell: shell,
+	}
+
+	return command.Execute()
+}
+
+// complete is called by the completion scripts with the words of the command
+// line, so its arguments are not parsed as flags.
+func complete(commands []*cliCommand, Args []string) error {
+	// RFC numbers are completed from the cached index only.
+	repository := RFCIndexRFCRepository{
+		Loader: &RFCIndexLoader{DataFormat: RFCIndexDataFormatXML, CacheStore: NewRFCIndexCacheStore()},
+	}
+
+	command := CompleteCommand{
+		Completer: &Completer{Commands: commands, RFCRepository: &repository},
+		Words:     Args,
+	}
+
+	return command.Execute()
+}
+
+type serveOptions struct {
+	addr  string
+	fetch bool
+}
+
+func (o *serveOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs serve", flag.ContinueOnError)
+	f.Usage = usageFor(f, "")
+
+	f.StringVar(&o.addr, "addr", ":8080", "Address to listen on")
+	f.BoolVar(&o.fetch, "fetch", false, "Fetch the index and RFCs missing from the local cache instead of serving only cached ones")
+
+	return f
+}
+
+func serveRFCs(Args []string) error {
+	var options serveOptions
+	f := options.flagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	repository, err := NewRFCIndexRFCRepository(RFCIndexDataFormatXML)
+	if err != nil {
+		return err
+	}
+
+	contentRepository := NewDefaultRFCContentRepository()
+
+	if !options.fetch {
+		repository.Loader.Fetcher = nil
+		contentRepository.Fetcher = nil
+	}
+
+	command := ServeCommand{
+		Addr: options.addr,
+		Server: &Server{
+			RFCRepository:        repository,
+			RFCContentRepository: contentRepository,
+		},
+	}
+
+	return command.Execute()
+}
+
+func parseConfigCommand(f *flag.FlagSet, Args []string, nargs int) (*Config, error) {
+	if err := parseFlags(f, Args); err != nil {
+		return nil, err
+	}
+
+	if f.NArg() != nargs {
+		return nil, usageError(f, nil)
+	}
+
+	return LoadConfig()
+}
+
+func newConfigGetFlagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs config get", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<key>", "Prints the value in effect, from the environment or the config file.")
+
+	return f
+}
+
+func configGet(Args []string) error {
+	f := newConfigGetFlagSet()
+
+	config, err := parseConfigCommand(f, Args, 1)
+	if err != nil {
+		return err
+	}
+
+	command := ConfigGetCommand{
+		Config: config,
+		Key:    f.Arg(0),
+	}
+
+	return command.Execute()
+}
+
+func newConfigSetFlagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs config set", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<key> <value>", configKeysUsage()...)
+
+	return f
+}
+
+func configSet(Args []string) error {
+	f := newConfigSetFlagSet()
+
+	config, err := parseConfigCommand(f, Args, 2)
+	if err != nil {
+		return err
+	}
+
+	command := ConfigSetCommand{
+		Config: config,
+		Key:    f.Arg(0),
+		Value:  f.Arg(1),
+	}
+
+	if err := ValidateConfigSetting(command.Key, command.Value); err != nil {
+		return usageError(f, err)
+	}
+
+	return command.Execute()
+}
+
+func newConfigUnsetFlagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs config unset", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<key>")
+
+	return f
+}
+
+func configUnset(Args []string) error {
+	f := newConfigUnsetFlagSet()
+
+	config, err := parseConfigCommand(f, Args, 1)
+	if err != nil {
+		return err
+	}
+
+	command := ConfigUnsetCommand{
+		Config: config,
+		Key:    f.Arg(0),
+	}
+
+	return command.Execute()
+}
+
+func newConfigListFlagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs config list", flag.ContinueOnError)
+	f.Usage = usageFor(f, "")
+
+	return f
+}
+
+func configList(Args []string) error {
+	f := newConfigListFlagSet()
+
+	config, err := parseConfigCommand(f, Args, 0)
+	if err != nil {
+		return err
+	}
+
+	command := ConfigListCommand{
+		Config: config,
+	}
+
+	return command.Execute()
+}
+
+func querySave(Args []string) error {
+	var options listOptions
+	f := options.querySaveFlagSet()
+
+	// The name comes before the options.
+	if len(Args) == 0 || strings.HasPrefix(Args[0], "-") {
+		if err := parseFlags(f, Args); err != nil {
+			return err
+		}
+		return usageError(f, nil)
+	}
+
+	name, queryArgs := Args[0], Args[1:]
+	if err := parseFlags(f, queryArgs); err != nil {
+		return err
+	}
+
+	if f.NArg() > 0 {
+		return usageError(f, fmt.Errorf("unexpected argument: %s", f.Arg(0)))
+	}
+	if options.preset != "" {
+		return usageError(f, fmt.Errorf("a saved query cannot use --preset"))
+	}
+	if err := options.resolve(); err != nil {
+		return usageError(f, err)
+	}
+	if err := ValidatePresetName(name); err != nil {
+		return usageError(f, err)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	command := QuerySaveCommand{
+		Config: config,
+		Name:   name,
+		Args:   queryArgs,
+	}
+
+	return command.Execute()
+}
+
+func newQueryListFlagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs query list", flag.ContinueOnError)
+	f.Usage = usageFor(f, "")
+
+	return f
+}
+
+func queryList(Args []string) error {
+	f := newQueryListFlagSet()
+
+	config, err := parseConfigCommand(f, Args, 0)
+	if err != nil {
+		return err
+	}
+
+	command := QueryListCommand{
+		Config: config,
+	}
+
+	return command.Execute()
+}
+
+func newQueryRemoveFlagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs query remove", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<name>...")
+
+	return f
+}
+
+func queryRemove(Args []string) error {
+	f := newQueryRemoveFlagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	if f.NArg() < 1 {
+		return usageError(f, nil)
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	command := QueryRemoveCommand{
+		Config: config,
+		Names:  f.Args(),
+	}
+
+	return command.Execute()
+}
+
+func newQueryExportFlagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs query export", flag.ContinueOnError)
+	f.Usage = usageFor(f, "[<name>...]", "Prints the saved queries, or the given ones, as a config file to share with rfcs query import.")
+
+	return f
+}
+
+func queryExport(Args []string) error {
+	f := newQueryExportFlagSet()
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	command := QueryExportCommand{
+		Config: config,
+		Names:  f.Args(),
+	}
+
+	return command.Execute()
+}
+
+type queryImportOptions struct {
+	replace bool
+}
+
+func (o *queryImportOptions) flagSet() *flag.FlagSet {
+	f := flag.NewFlagSet("rfcs query import", flag.ContinueOnError)
+	f.Usage = usageFor(f, "<file>", "Adds the queries of a file written by rfcs query export, or of the presets of a config file. The file - is standard input.")
+
+	f.BoolVar(&o.replace, "replace", false, "Replace saved queries of the same name")
+
+	return f
+}
+
+func queryImport(Args []string) error {
+	var options queryImportOptions
+	f := options.flagSet()
+
+	config, err := parseConfigCommand(f, Args, 1)
+	if err != nil {
+		return err
+	}
+
+	command := QueryImportCommand{
+		Config:  config,
+		Path:    f.Arg(0),
+		Replace: options.replace,
+	}
+
+	return command.Execute()
+}
+
+func configKeysUsage() []string {
+	lines := []string{"Keys:"}
+	for _, key := range configKeys {
+		lines = append(lines, fmt.Sprintf("  %-18s %s ($%s)", key.Name, key.Description, key.Env))
+	}
+	return append(lines, fmt.Sprintf("  %-18s Options of rfcs list --preset <name>", "presets.<name>"))
+}
+
+var cliCommands = []*cliCommand{
+	{Name: "list", Summary: "List RFCs", Run: listRFCs, Flags: new(listOptions).listFlagSet, QueryArgs: true, QueryPrefix: "@", RFCFlags: listRFCFlags, QueryFlags: []string{"preset"}},
+	{Name: "get", Summary: "Fetch RFC", Run: getRFC, Flags: new(getOptions).flagSet, RFCArgs: 1},
+	{Name: "pick", Summary: "Select an RFC with fzf and fetch it", Run: pickRFC, Flags: new(pickOptions).flagSet},
+	{Name: "preview", Summary: "Show the RFC on a line of list --fzf", Run: previewRFC, Flags: new(previewOptions).flagSet},
+	{Name: "requirements", Summary: "List the normative requirements of an RFC", Run: listRequirements, Flags: new(requirementsOptions).flagSet, RFCArgs: 1},
+	{Name: "trace", Summary: "Report which requirements of an RFC are referenced by code", Run: traceRequirements, Flags: new(traceOptions).flagSet, RFCArgs: 1},
+	{Name: "cite", Summary: "Generate citations for RFCs", Run: citeRFCs, Flags: new(citeOptions).flagSet, RFCArgs: -1},
+	{Name: "refs", Summary: "List the references of an RFC", Run: listRefs, Flags: new(refsOptions).flagSet, RFCArgs: 1},
+	{Name: "cited-by", Summary: "List the cached RFCs citing an RFC", Run: listCitedBy, Flags: new(citedByOptions).flagSet, RFCArgs: 1},
+	{Name: "deps", Summary: "List the normative dependencies of an RFC", Run: listDeps, Flags: new(depsOptions).flagSet, RFCArgs: 1},
+	{Name: "errata", Summary: "List errata of an RFC", Run: listErrata, Flags: new(errataOptions).flagSet, RFCArgs: 1},
+	{Name: "diff", Summary: "Compare two RFCs section by section", Run: diffRFCs, Flags: new(diffOptions).flagSet, RFCArgs: 2},
+	{Name: "draft", Summary: "Fetch, list and compare Internet-Drafts", Subcommands: []*cliCommand{
+		{Name: "get", Summary: "Fetch an Internet-Draft", Run: draftGet, Flags: new(draftGetOptions).flagSet},
+		{Name: "list", Summary: "List cached Internet-Drafts", Run: draftList, Flags: new(draftListOptions).flagSet},
+		{Name: "diff", Summary: "Compare two revisions of an Internet-Draft", Run: draftDiff, Flags: new(draftDiffOptions).flagSet},
+	}},
+	{Name: "changes", Summary: "Show what changed in the RFC index since the previous refresh", Run: listChanges, Flags: new(changesOptions).flagSet},
+	{Name: "watch", Summary: "Manage the watch list and check watched RFCs for changes", Subcommands: []*cliCommand{
+		{Name: "add", Summary: "Add RFCs to the watch list", Run: watchAdd, Flags: newWatchAddFlagSet, RFCArgs: -1},
+		{Name: "remove", Summary: "Remove RFCs from the watch list", Run: watchRemove, Flags: newWatchRemoveFlagSet, RFCArgs: -1},
+		{Name: "list", Summary: "List watched RFCs", Run: watchList, Flags: new(watchListOptions).flagSet},
+		{Name: "check", Summary: "Report changes to watched RFCs, exiting with status 1 if there are any", Run: watchCheck, Flags: new(watchCheckOptions).flagSet},
+	}},
+	{Name: "browse", Summary: "Browse RFCs interactively", Run: browseRFCs, Flags: newBrowseFlagSet},
+	{Name: "cache", Summary: "Manage the local cache", Subcommands: []*cliCommand{
+		{Name: "info", Summary: "Show cache location, size and index age", Run: cacheInfo, Flags: newCacheInfoFlagSet},
+		{Name: "list", Summary: "List cached RFCs", Run: cacheList, Flags: newCacheListFlagSet},
+		{Name: "verify", Summary: "Verify the integrity of cached entries", Run: cacheVerify, Flags: new(cacheVerifyOptions).flagSet},
+		{Name: "prune", Summary: "Remove least recently used entries", Run: cachePrune, Flags: new(cachePruneOptions).flagSet},
+		{Name: "clear", Summary: "Remove all cached entries", Run: cacheClear, Flags: new(cacheClearOptions).flagSet},
+		{Name: "migrate", Summary: "Convert cached entries to another compression", Run: cacheMigrate, Flags: new(cacheMigrateOptions).flagSet},
+	}},
+	{Name: "serve", Summary: "Serve RFCs over HTTP", Run: serveRFCs, Flags: new(serveOptions).flagSet},
+	{Name: "query", Summary: "Save, share and import queries of rfcs list", Subcommands: []*cliCommand{
+		{Name: "save", Summary: "Save options of rfcs list under a name", Run: querySave, Flags: new(listOptions).querySaveFlagSet, RFCFlags: listRFCFlags, QueryFlags: []string{"preset"}},
+		{Name: "list", Summary: "List saved queries", Run: queryList, Flags: newQueryListFlagSet},
+		{Name: "remove", Summary: "Remove saved queries", Run: queryRemove, Flags: newQueryRemoveFlagSet, QueryArgs: true},
+		{Name: "export", Summary: "Print saved queries to share", Run: queryExport, Flags: newQueryExportFlagSet, QueryArgs: true},
+		{Name: "import", Summary: "Add queries from a file", Run: queryImport, Flags: new(queryImportOptions).flagSet},
+	}},
+	{Name: "config", Summary: "Show and change the config file", Subcommands: []*cliCommand{
+		{Name: "get", Summary: "Print the value of a setting", Run: configGet, Flags: newConfigGetFlagSet, ConfigKeyArg: true},
+		{Name: "set", Summary: "Change a setting", Run: configSet, Flags: newConfigSetFlagSet, ConfigKeyArg: true},
+		{Name: "unset", Summary: "Remove a setting", Run: configUnset, Flags: newConfigUnsetFlagSet, ConfigKeyArg: true},
+		{Name: "list", Summary: "List the settings of the config file", Run: configList, Flags: newConfigListFlagSet},
+	}},
+	{Name: "completion", Summary: "Generate shell completion scripts", Run: printCompletion, Flags: newPrintCompletionFlagSet, ArgValues: []string{"bash", "zsh", "fish"}},
+}
+
+var helpCommand = &cliCommand{Name: "help", Summary: "Show the usage of a command"}
+
+func usage(f *flag.FlagSet) func() {
+	return func() {
+		fmt.Fprintln(usageOutput, "Usage: rfcs [global options] <command> [options] [arguments]")
+		fmt.Fprintln(usageOutput, "")
+		printCommands(usageOutput, "Commands:", append(cliCommands, helpCommand))
+		fmt.Fprintln(usageOutput, "")
+		fmt.Fprintln(usageOutput, "Global options:")
+		f.SetOutput(usageOutput)
+		f.PrintDefaults()
+		f.SetOutput(nil)
+		fmt.Fprintln(usageOutput, "")
+		fmt.Fprintln(usageOutput, "Run 'rfcs help <command>' for the options of a command.")
+	}
+}
+
+func main() {
+	os.Exit(exitCode(run(os.Args[1:])))
+}
+
+func run(Args []string) error {
+	// The config file sets defaults for the environment, which the global
+	// options default to.
+	config, err := LoadConfig()
+	if err != nil {
+		return err
+	}
+	for _, warning := range config.Warnings {
+		fmt.Fprintf(os.Stderr, "rfcs: %s\n", warning)
+	}
+	config.ApplyToEnvironment()
+
+	options := GlobalOptionsFromEnvironment()
+
+	f := flag.NewFlagSet("rfcs", flag.ContinueOnError)
+	f.Usage = usage(f)
+	options.AddFlags(f)
+
+	if helpRequested(Args) {
+		usageOutput = os.Stdout
+	}
+
+	if err := parseFlags(f, Args); err != nil {
+		return err
+	}
+
+	if err := options.Validate(); err != nil {
+		return usageError(f, err)
+	}
+	options.Apply()
+
+	if f.NArg() < 1 {
+		return usageError(f, nil)
+	}
+
+	name := f.Arg(0)
+
+	// An invalid config file can still be fixed with rfcs config.
+	if err := config.Err(); err != nil {
+		if name != "help" && name != "config" {
+			return err
+		}
+		for _, problem := range config.Errors {
+			fmt.Fprintf(os.Stderr, "rfcs: %s\n", problem)
+		}
+	}
+
+	if name == "help" {
+		return help(f, cliCommands, f.Args()[1:])
+	}
+
+	// __complete is run by the completion scripts, and not listed.
+	if name == "__complete" {
+		return complete(cliCommands, f.Args()[1:])
+	}
+
+	if err := ValidateCacheCompressionEnvironment(); err != nil {
+		return err
+	}
+
+	command := findCommand(cliCommands, name)
+	if command == nil {
+		return usageError(f, fmt.Errorf("unknown command: %s", name))
+	}
+
+	return runCommand(name, command, f.Args()[1:])
+}
+
+// exitCode prints the error returned by a command to standard error and
+// returns the exit status for it.
+func exitCode(err error) int {
+	if err == nil || err == flag.ErrHelp {
+		return 0
+	}
+
+	if exitErr, ok := err.(*ExitError); ok {
+		return exitErr.Code
+	}
+
+	fmt.Fprintf(os.Stderr, "rfcs: %v\n", err)
+	return 1
+}
+
+// ExitError makes the command exit with the given status without printing
+// anything, for commands whose output has already reported the failure.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
 }
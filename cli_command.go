@@ -0,0 +1,215 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// cliCommand is a command of rfcs, or a group of subcommands if it has no
+// Run function. Flags returns the flag set of the command, which Run parses
+// its arguments with and completion lists.
+type cliCommand struct {
+	Name        string
+	Summary     string
+	Run         func(Args []string) error
+	Flags       func() *flag.FlagSet
+	Subcommands []*cliCommand
+
+	// The arguments are completed as RFC numbers, the first RFCArgs of them
+	// or all if it is -1, as a key of the config file, as saved queries
+	// written after QueryPrefix, or as one of ArgValues.
+	RFCArgs      int
+	ConfigKeyArg bool
+	QueryArgs    bool
+	QueryPrefix  string
+	ArgValues    []string
+
+	// The values of RFCFlags are completed as RFC numbers, and those of
+	// QueryFlags as saved queries.
+	RFCFlags   []string
+	QueryFlags []string
+}
+
+// usageOutput is where usage is printed: standard error, unless help was
+// asked for.
+var usageOutput io.Writer = os.Stderr
+
+// usageFor returns the usage function of the flag set of a command, which
+// prints the command line of the command with the given arguments, the
+// description and the options. The flag set is named after the command line
+// of the command, such as "rfcs draft get".
+func usageFor(f *flag.FlagSet, args string, description ...string) func() {
+	return func() {
+		// The global options are listed by rfcs help.
+		options := flag.NewFlagSet(f.Name(), flag.ContinueOnError)
+		f.VisitAll(func(fl *flag.Flag) {
+			if !isGlobalFlag(fl.Name) {
+				options.Var(fl.Value, fl.Name, fl.Usage)
+				options.Lookup(fl.Name).DefValue = fl.DefValue
+			}
+		})
+
+		synopsis := f.Name()
+		hasOptions := false
+		options.VisitAll(func(*flag.Flag) { hasOptions = true })
+		if hasOptions {
+			synopsis += " [options]"
+		}
+		if args != "" {
+			synopsis += " " + args
+		}
+
+		fmt.Fprintf(usageOutput, "Usage: %s\n", synopsis)
+		if len(description) > 0 {
+			fmt.Fprintln(usageOutput, "")
+			for _, line := range description {
+				fmt.Fprintln(usageOutput, line)
+			}
+		}
+		if hasOptions {
+			fmt.Fprintln(usageOutput, "")
+			fmt.Fprintln(usageOutput, "Options:")
+			options.SetOutput(usageOutput)
+			options.PrintDefaults()
+		}
+		fmt.Fprintln(usageOutput, "")
+		fmt.Fprintln(usageOutput, "Run 'rfcs help' for the global options.")
+	}
+}
+
+// parseFlags parses the arguments of a command, which may include the
+// global options. If they are invalid, the flag package has printed the
+// problem and the usage, and the returned error makes rfcs exit with status
+// 2.
+func parseFlags(f *flag.FlagSet, Args []string) error {
+	var options *GlobalOptions
+	if f.Lookup("offline") == nil {
+		options = GlobalOptionsFromEnvironment()
+		options.AddFlags(f)
+	}
+
+	if err := f.Parse(Args); err == flag.ErrHelp {
+		return err
+	} else if err != nil {
+		return &ExitError{Code: 2}
+	}
+
+	if options != nil {
+		if err := options.Validate(); err != nil {
+			return usageError(f, err)
+		}
+		options.Apply()
+	}
+
+	return nil
+}
+
+// usageError prints the error, if any, and the usage of the command, and
+// returns an error making rfcs exit with status 2.
+func usageError(f *flag.FlagSet, err error) error {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", f.Name(), err)
+	}
+	f.Usage()
+	return &ExitError{Code: 2}
+}
+
+// helpRequested reports whether the flags before the first argument ask for
+// help.
+func helpRequested(Args []string) bool {
+	for _, arg := range Args {
+		if arg == "-h" || arg == "-help" || arg == "--help" {
+			return true
+		} else if arg == "--" || !strings.HasPrefix(arg, "-") {
+			return false
+		}
+	}
+	return false
+}
+
+func findCommand(commands []*cliCommand, name string) *cliCommand {
+	for _, command := range commands {
+		if command.Name == name {
+			return command
+		}
+	}
+	return nil
+}
+
+func printCommands(w io.Writer, title string, commands []*cliCommand) {
+	fmt.Fprintln(w, title)
+	for _, command := range commands {
+		fmt.Fprintf(w, "  %-13s %s\n", command.Name, command.Summary)
+	}
+}
+
+func usageGroup(path string, command *cliCommand) {
+	fmt.Fprintf(usageOutput, "Usage: rfcs %s <subcommand> [options]\n", path)
+	fmt.Fprintln(usageOutput, "")
+	printCommands(usageOutput, "Subcommands:", command.Subcommands)
+	fmt.Fprintln(usageOutput, "")
+	fmt.Fprintf(usageOutput, "Run 'rfcs help %s <subcommand>' for the options of a subcommand.\n", path)
+}
+
+// runCommand runs the command, or the subcommand of a group named by the
+// first argument. path is the command line leading to the command, such as
+// "draft".
+func runCommand(path string, command *cliCommand, Args []string) error {
+	if helpRequested(Args) {
+		usageOutput = os.Stdout
+	}
+
+	if command.Run != nil {
+		return command.Run(Args)
+	}
+
+	if len(Args) == 0 || helpRequested(Args) {
+		usageGroup(path, command)
+		if len(Args) == 0 {
+			return &ExitError{Code: 2}
+		}
+		return flag.ErrHelp
+	}
+
+	subcommand := findCommand(command.Subcommands, Args[0])
+	if subcommand == nil {
+		fmt.Fprintf(os.Stderr, "rfcs %s: unknown subcommand: %s\n", path, Args[0])
+		usageGroup(path, command)
+		return &ExitError{Code: 2}
+	}
+
+	return runCommand(path+" "+subcommand.Name, subcommand, Args[1:])
+}
+
+// help prints the usage of the command named by the arguments, or of rfcs
+// if there are none.
+func help(f *flag.FlagSet, commands []*cliCommand, Args []string) error {
+	usageOutput = os.Stdout
+
+	if len(Args) == 0 {
+		f.Usage()
+		return nil
+	}
+
+	path := ""
+	command := &cliCommand{Subcommands: commands}
+	for _, name := range Args {
+		subcommand := findCommand(command.Subcommands, name)
+		if subcommand == nil {
+			fmt.Fprintf(os.Stderr, "rfcs help: unknown command: %s\n", strings.Join(Args, " "))
+			return &ExitError{Code: 2}
+		}
+		path = strings.TrimSpace(path + " " + name)
+		command = subcommand
+	}
+
+	if command.Run == nil {
+		usageGroup(path, command)
+		return nil
+	}
+
+	return command.Run([]string{"-h"})
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestCLICommandsFlags(t *testing.T) {
+	var check func(path string, commands []*cliCommand)
+	check = func(path string, commands []*cliCommand) {
+		for _, command := range commands {
+			name := path + " " + command.Name
+
+			if command.Run == nil {
+				if command.Flags != nil {
+					t.Errorf("%s: group of subcommands has flags", name)
+				}
+				check(name, command.Subcommands)
+				continue
+			}
+
+			if command.Flags == nil {
+				t.Errorf("%s: no flags", name)
+				continue
+			}
+
+			f := command.Flags()
+			if f.Name() != name {
+				t.Errorf("%s: got flag set %q", name, f.Name())
+			}
+			for _, flags := range [][]string{command.RFCFlags, command.QueryFlags} {
+				for _, flag := range flags {
+					if f.Lookup(flag) == nil {
+						t.Errorf("%s: no flag %s to complete", name, flag)
+					}
+				}
+			}
+		}
+	}
+
+	check("rfcs", cliCommands)
+}
@@ -1,10 +1,20 @@
 package main
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"os/exec"
 	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 	"text/template"
+	"time"
 )
 
 type SelectOptions struct {
@@ -18,11 +28,14 @@ type SelectOptions struct {
 	FYINumber       int
 	Category        *RFCCategory
 	Stream          *RFCStream
+	WorkingGroup    string
 }
 
 type DisplayOptions struct {
 	SortByPublicationDate bool
 	OutputTemplate        string
+	// JSON prints the RFCs as a JSON array instead of using the template.
+	JSON bool
 }
 
 type ListCommand struct {
@@ -32,32 +45,7 @@ type ListCommand struct {
 }
 
 func (c *ListCommand) Execute() error {
-	var rfcs []*RFC
-	var err error
-
-	if c.SelectOptions.ExcludeObsolete {
-		rfcs, err = c.RFCRepository.FindNonObsolete()
-	} else if c.SelectOptions.ObsoletedBy != 0 {
-		rfcs, err = c.RFCRepository.FindObsoletedBy(c.SelectOptions.ObsoletedBy)
-	} else if c.SelectOptions.Obsolete != 0 {
-		rfcs, err = c.RFCRepository.FindObsolete(c.SelectOptions.Obsolete)
-	} else if c.SelectOptions.UpdatedBy != 0 {
-		rfcs, err = c.RFCRepository.FindUpdatedBy(c.SelectOptions.UpdatedBy)
-	} else if c.SelectOptions.Update != 0 {
-		rfcs, err = c.RFCRepository.FindUpdate(c.SelectOptions.Update)
-	} else if c.SelectOptions.STDNumber != 0 {
-		rfcs, err = c.RFCRepository.FindBySTDNumber(c.SelectOptions.STDNumber)
-	} else if c.SelectOptions.BCPNumber != 0 {
-		rfcs, err = c.RFCRepository.FindByBCPNumber(c.SelectOptions.BCPNumber)
-	} else if c.SelectOptions.FYINumber != 0 {
-		rfcs, err = c.RFCRepository.FindByFYINumber(c.SelectOptions.FYINumber)
-	} else if c.SelectOptions.Category != nil {
-		rfcs, err = c.RFCRepository.FindByCategory(*c.SelectOptions.Category)
-	} else if c.SelectOptions.Stream != nil {
-		rfcs, err = c.RFCRepository.FindByStream(*c.SelectOptions.Stream)
-	} else {
-		rfcs, err = c.RFCRepository.FindAll()
-	}
+	rfcs, err := SelectRFCs(c.RFCRepository, c.SelectOptions)
 	if err != nil {
 		return err
 	}
@@ -66,6 +54,15 @@ func (c *ListCommand) Execute() error {
 		sort.Stable(ByPublicationDate(rfcs))
 	}
 
+	if c.DisplayOptions.JSON {
+		if rfcs == nil {
+			rfcs = []*RFC{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rfcs)
+	}
+
 	tmpl, err := template.New("").Parse(c.DisplayOptions.OutputTemplate)
 	if err != nil {
 		return err
@@ -79,6 +76,68 @@ func (c *ListCommand) Execute() error {
 	return nil
 }
 
+// SelectRFCs returns the RFCs matching all of the options, in the order of
+// the first one given.
+func SelectRFCs(repository RFCRepository, options SelectOptions) ([]*RFC, error) {
+	var finders []func() ([]*RFC, error)
+	find := func(finder func() ([]*RFC, error)) {
+		finders = append(finders, finder)
+	}
+
+	if options.ExcludeObsolete {
+		find(repository.FindNonObsolete)
+	}
+	if options.ObsoletedBy != 0 {
+		find(func() ([]*RFC, error) { return repository.FindObsoletedBy(options.ObsoletedBy) })
+	}
+	if options.Obsolete != 0 {
+		find(func() ([]*RFC, error) { return repository.FindObsolete(options.Obsolete) })
+	}
+	if options.UpdatedBy != 0 {
+		find(func() ([]*RFC, error) { return repository.FindUpdatedBy(options.UpdatedBy) })
+	}
+	if options.Update != 0 {
+		find(func() ([]*RFC, error) { return repository.FindUpdate(options.Update) })
+	}
+	if options.STDNumber != 0 {
+		find(func() ([]*RFC, error) { return repository.FindBySTDNumber(options.STDNumber) })
+	}
+	if options.BCPNumber != 0 {
+		find(func() ([]*RFC, error) { return repository.FindByBCPNumber(options.BCPNumber) })
+	}
+	if options.FYINumber != 0 {
+		find(func() ([]*RFC, error) { return repository.FindByFYINumber(options.FYINumber) })
+	}
+	if options.Category != nil {
+		find(func() ([]*RFC, error) { return repository.FindByCategory(*options.Category) })
+	}
+	if options.Stream != nil {
+		find(func() ([]*RFC, error) { return repository.FindByStream(*options.Stream) })
+	}
+	if options.WorkingGroup != "" {
+		find(func() ([]*RFC, error) { return repository.FindByWorkingGroup(options.WorkingGroup) })
+	}
+
+	if len(finders) == 0 {
+		return repository.FindAll()
+	}
+
+	rfcs, err := finders[0]()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, finder := range finders[1:] {
+		found, err := finder()
+		if err != nil {
+			return nil, err
+		}
+		rfcs = intersectRFCs(rfcs, found)
+	}
+
+	return rfcs, nil
+}
+
 type ByPublicationDate []*RFC
 
 func (r ByPublicationDate) Len() int {
@@ -95,7 +154,12 @@ func (r ByPublicationDate) Less(i, j int) bool {
 
 type GetCommand struct {
 	RFCContentRepository RFCContentRepository
+	RFCRepository        RFCRepository
+	ErrataRepository     *RFCErrataRepository
 	RFCNumber            int
+	LinkStyle            RFCReferenceStyle
+	// Pager is the command showing the RFC on a terminal, if any.
+	Pager string
 }
 
 func (c *GetCommand) Execute() error {
@@ -104,7 +168,1648 @@ func (c *GetCommand) Execute() error {
 		return err
 	}
 
+	if c.LinkStyle == RFCReferenceStyleNone && c.ErrataRepository == nil {
+		return PageOutput(c.Pager, string(content)+"\n")
+	}
+
+	doc := ParseRFCDocument(content)
+
+	if c.ErrataRepository != nil {
+		errata, err := c.ErrataRepository.FindByNumber(c.RFCNumber)
+		if err != nil {
+			return err
+		}
+
+		doc = AnnotateRFCErrata(doc, filterRFCErrata(errata, false))
+	}
+
+	if c.LinkStyle == RFCReferenceStyleNone {
+		return PageOutput(c.Pager, doc.String())
+	}
+
+	resolver := RFCReferenceResolver{RFCRepository: c.RFCRepository}
+	renderer := RFCReferenceRenderer{Style: c.LinkStyle, Number: c.RFCNumber}
+
+	return PageOutput(c.Pager, renderer.Render(doc, resolver.Resolve(doc)))
+}
+
+type CiteCommand struct {
+	RFCRepository RFCRepository
+	IDs           []string
+	Style         CitationStyle
+}
+
+func (c *CiteCommand) Execute() error {
+	var rfcs []*RFC
+
+	for _, id := range c.IDs {
+		series, number, err := parseCitationID(id)
+		if err != nil {
+			return err
+		}
+
+		var found []*RFC
+		switch series {
+		case "RFC":
+			rfc, err := c.RFCRepository.FindByNumber(number)
+			if err != nil {
+				return err
+			}
+			if rfc != nil {
+				found = []*RFC{rfc}
+			}
+		case "BCP":
+			found, err = c.RFCRepository.FindByBCPNumber(number)
+		case "STD":
+			found, err = c.RFCRepository.FindBySTDNumber(number)
+		case "FYI":
+			found, err = c.RFCRepository.FindByFYINumber(number)
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(found) == 0 {
+			return fmt.Errorf("%s %d not found", series, number)
+		}
+
+		rfcs = append(rfcs, found...)
+	}
+
+	citations, err := FormatCitations(rfcs, c.Style)
+	if err != nil {
+		return err
+	}
+
+	fmt.Print(citations)
+
+	return nil
+}
+
+type RefsCommand struct {
+	RFCContentRepository RFCContentRepository
+	RFCRepository        RFCRepository
+	GraphRepository      *RFCCitationGraphRepository
+	RFCNumber            int
+	Format               OutputFormat
+}
+
+type rfcReferencesReport struct {
+	RFCNumber   int                  `json:"rfc"`
+	Normative   []*RFCReferenceEntry `json:"normative"`
+	Informative []*RFCReferenceEntry `json:"informative"`
+}
+
+func (c *RefsCommand) Execute() error {
+	content, err := c.RFCContentRepository.FindByNumber(c.RFCNumber)
+	if err != nil {
+		return err
+	}
+
+	graph, err := c.GraphRepository.Graph()
+	if err != nil {
+		return err
+	}
+
+	entries := graph.References(c.RFCNumber)
+	if !graph.Contains(c.RFCNumber) {
+		entries = ParseRFCReferenceEntries(ParseRFCDocument(content))
+	}
+
+	report := rfcReferencesReport{
+		RFCNumber:   c.RFCNumber,
+		Normative:   []*RFCReferenceEntry{},
+		Informative: []*RFCReferenceEntry{},
+	}
+	for _, entry := range entries {
+		if entry.Normative {
+			report.Normative = append(report.Normative, entry)
+		} else {
+			report.Informative = append(report.Informative, entry)
+		}
+	}
+
+	if c.Format == OutputFormatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	for i, group := range []struct {
+		name    string
+		entries []*RFCReferenceEntry
+	}{{"Normative references", report.Normative}, {"Informative references", report.Informative}} {
+		if i > 0 {
+			fmt.Println("")
+		}
+		fmt.Printf("%s (%d):\n", group.name, len(group.entries))
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		for _, entry := range group.entries {
+			fmt.Fprintf(w, "  [%s]\t%s\n", entry.Label, c.describe(entry))
+		}
+		w.Flush()
+	}
+
+	return nil
+}
+
+func (c *RefsCommand) describe(entry *RFCReferenceEntry) string {
+	if entry.RFCNumber != 0 {
+		return describeRFC(c.RFCRepository, entry.RFCNumber)
+	} else if entry.Draft != "" {
+		return entry.Draft
+	}
+	return entry.Text
+}
+
+// describeRFC returns the number and title of the RFC, marking obsolete RFCs,
+// or only the number if the RFC is missing from the index.
+func describeRFC(repository RFCRepository, number int) string {
+	rfc, err := repository.FindByNumber(number)
+	if err != nil || rfc == nil {
+		return fmt.Sprintf("RFC %d", number)
+	}
+
+	if rfc.IsObsolete() {
+		return fmt.Sprintf("RFC %d %s (obsolete)", number, rfc.Title)
+	}
+
+	return fmt.Sprintf("RFC %d %s", number, rfc.Title)
+}
+
+type CitedByCommand struct {
+	RFCRepository   RFCRepository
+	GraphRepository *RFCCitationGraphRepository
+	RFCNumber       int
+	Format          OutputFormat
+}
+
+type rfcCitedByReport struct {
+	RFCNumber   int               `json:"rfc"`
+	Searched    int               `json:"searched"`
+	Normative   []*RFCCitingEntry `json:"normative"`
+	Informative []*RFCCitingEntry `json:"informative"`
+}
+
+func (c *CitedByCommand) Execute() error {
+	graph, err := c.GraphRepository.Graph()
+	if err != nil {
+		return err
+	}
+
+	report := rfcCitedByReport{
+		RFCNumber:   c.RFCNumber,
+		Searched:    len(graph.Documents),
+		Normative:   []*RFCCitingEntry{},
+		Informative: []*RFCCitingEntry{},
+	}
+	for _, citing := range graph.CitedBy(c.RFCNumber) {
+		if citing.Normative {
+			report.Normative = append(report.Normative, citing)
+		} else {
+			report.Informative = append(report.Informative, citing)
+		}
+	}
+
+	if c.Format == OutputFormatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	citing := make(map[int]bool)
+	for _, entry := range append(report.Normative, report.Informative...) {
+		citing[entry.From] = true
+	}
+
+	fmt.Printf("RFC %d is cited by %d of %d cached RFCs\n", c.RFCNumber, len(citing), report.Searched)
+
+	for _, group := range []struct {
+		name    string
+		entries []*RFCCitingEntry
+	}{{"Normatively", report.Normative}, {"Informatively", report.Informative}} {
+		if len(group.entries) == 0 {
+			continue
+		}
+		fmt.Printf("\n%s (%d):\n", group.name, len(group.entries))
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		for _, citing := range group.entries {
+			fmt.Fprintf(w, "  %s\t[%s]\n", describeRFC(c.RFCRepository, citing.From), citing.Label)
+		}
+		w.Flush()
+	}
+
+	return nil
+}
+
+type DepsCommand struct {
+	Resolver  *RFCDependencyResolver
+	RFCNumber int
+	Format    RFCDependencyFormat
+}
+
+func (c *DepsCommand) Execute() error {
+	deps, err := c.Resolver.Resolve(c.RFCNumber)
+	if err != nil {
+		return err
+	}
+
+	switch c.Format {
+	case RFCDependencyFormatJSON:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(deps)
+	case RFCDependencyFormatOrder:
+		for i, node := range deps.ReadingOrder() {
+			line := fmt.Sprintf("%3d. RFC %d %s", i+1, node.Number, node.Title)
+			if node.Obsolete {
+				line += " (obsoleted by " + formatRFCNumbers(node.ObsoletedBy) + ")"
+			}
+			fmt.Println(strings.TrimRight(line, " "))
+		}
+	default:
+		deps.WriteTree(os.Stdout)
+	}
+
+	var obsolete, unavailable []int
+	for _, node := range deps.Nodes {
+		if node.Obsolete {
+			obsolete = append(obsolete, node.Number)
+		}
+		if node.Error != "" {
+			unavailable = append(unavailable, node.Number)
+		}
+	}
+
+	if len(obsolete) > 0 {
+		fmt.Fprintf(os.Stderr, "warning: obsolete dependencies: %s\n", formatRFCNumbers(obsolete))
+	}
+	if len(unavailable) > 0 {
+		fmt.Fprintf(os.Stderr, "warning: references not followed, text not available: %s\n", formatRFCNumbers(unavailable))
+	}
+	for _, cycle := range deps.Cycles {
+		fmt.Fprintf(os.Stderr, "warning: dependency cycle: %s\n", strings.Replace(formatRFCNumbers(cycle), ", ", " -> ", -1))
+	}
+
+	return nil
+}
+
+type ErrataCommand struct {
+	ErrataRepository *RFCErrataRepository
+	RFCNumber        int
+	IncludeRejected  bool
+	Format           OutputFormat
+}
+
+func (c *ErrataCommand) Execute() error {
+	errata, err := c.ErrataRepository.FindByNumber(c.RFCNumber)
+	if err != nil {
+		return err
+	}
+
+	errata = filterRFCErrata(errata, c.IncludeRejected)
+
+	if c.Format == OutputFormatJSON {
+		if errata == nil {
+			errata = []*RFCErratum{}
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(errata)
+	}
+
+	if len(errata) == 0 {
+		fmt.Printf("No errata for RFC %d\n", c.RFCNumber)
+		return nil
+	}
+
+	for i, erratum := range errata {
+		if i > 0 {
+			fmt.Println("")
+		}
+
+		section := erratum.Section
+		if number := erratum.SectionNumber(); number != "" {
+			section = "Section " + number
+		}
+
+		fmt.Printf("Erratum %s  %s  %s  %s  %s\n", erratum.ID, erratum.Status, erratum.Type, section, erratum.SubmitDate)
+		for _, line := range formatRFCErratumText(erratum) {
+			fmt.Println(line)
+		}
+	}
+
+	return nil
+}
+
+// filterRFCErrata drops rejected errata unless includeRejected is set.
+func filterRFCErrata(errata []*RFCErratum, includeRejected bool) []*RFCErratum {
+	var filtered []*RFCErratum
+	for _, erratum := range errata {
+		if includeRejected || erratum.Status != RFCErratumStatusRejected {
+			filtered = append(filtered, erratum)
+		}
+	}
+	return filtered
+}
+
+type RequirementsCommand struct {
+	RFCContentRepository RFCContentRepository
+	RFCNumber            int
+	Format               OutputFormat
+}
+
+func (c *RequirementsCommand) Execute() error {
+	content, err := c.RFCContentRepository.FindByNumber(c.RFCNumber)
+	if err != nil {
+		return err
+	}
+
+	requirements := ExtractRFCRequirements(c.RFCNumber, ParseRFCDocument(content))
+
+	switch c.Format {
+	case OutputFormatJSON:
+		if requirements == nil {
+			requirements = []*RFCRequirement{}
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(requirements)
+	case OutputFormatCSV:
+		w := csv.NewWriter(os.Stdout)
+		w.Write([]string{"id", "section", "section_title", "level", "keywords", "text"})
+		for _, r := range requirements {
+			w.Write([]string{r.ID, r.Section, r.SectionTitle, r.Level.String(), strings.Join(r.Keywords, " "), r.Text})
+		}
+		w.Flush()
+		return w.Error()
+	}
+
+	fmt.Printf("# RFC %d requirements\n", c.RFCNumber)
+
+	section := ""
+	for _, r := range requirements {
+		if r.Section != section {
+			fmt.Printf("\n## %s %s\n\n", r.Section, r.SectionTitle)
+			section = r.Section
+		}
+		fmt.Printf("- [ ] **%s** `%s` %s\n", r.Level, r.ID, r.Text)
+	}
+
+	return nil
+}
+
+type TraceCommand struct {
+	RFCContentRepository RFCContentRepository
+	RFCNumber            int
+	Paths                []string
+	Format               OutputFormat
+}
+
+func (c *TraceCommand) Execute() error {
+	content, err := c.RFCContentRepository.FindByNumber(c.RFCNumber)
+	if err != nil {
+		return err
+	}
+
+	refs, err := ScanRFCTraceReferences(c.RFCNumber, c.Paths)
+	if err != nil {
+		return err
+	}
+
+	report := BuildRFCTraceReport(c.RFCNumber, ParseRFCDocument(content), refs)
+
+	if c.Format == OutputFormatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+
+	referenced := 0
+	for _, s := range report.Sections {
+		if len(s.Locations) > 0 {
+			referenced++
+		}
+	}
+
+	fmt.Printf("RFC %d: %d/%d requirements covered (%d by ID, %d by section), %d/%d sections referenced\n",
+		c.RFCNumber, report.CoveredCount+report.SectionCoveredCount, len(report.Requirements),
+		report.CoveredCount, report.SectionCoveredCount, referenced, len(report.Sections))
+
+	if len(report.Requirements) > 0 {
+		fmt.Println("")
+		fmt.Println("Requirements:")
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, r := range report.Requirements {
+		mark := " "
+		if r.Coverage == RFCTraceCovered {
+			mark = "x"
+		} else if r.Coverage == RFCTraceSectionCovered {
+			mark = "~"
+		}
+		fmt.Fprintf(w, "  [%s] %s\t%s\t%s\n", mark, r.ID, r.Level, formatTraceLocations(r.Locations))
+	}
+	w.Flush()
+
+	fmt.Println("")
+	fmt.Println("Sections:")
+
+	w = tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, s := range report.Sections {
+		fmt.Fprintf(w, "  %s\t%s\t%d/%d\t%s\n", s.Number, s.Title, s.Covered, s.Requirements, formatTraceLocations(s.Locations))
+	}
+	w.Flush()
+
+	if len(report.Unresolved) > 0 {
+		fmt.Println("")
+		fmt.Println("Unresolved references:")
+		for _, ref := range report.Unresolved {
+			name := ref.RequirementID
+			if name == "" {
+				name = "Section " + ref.Section
+			}
+			fmt.Printf("  %s:%d: %s\n", ref.Location.Path, ref.Location.Line, name)
+		}
+	}
+
+	return nil
+}
+
+func formatTraceLocations(locations []RFCTraceLocation) string {
+	var s []string
+	for _, location := range locations {
+		s = append(s, fmt.Sprintf("%s:%d", location.Path, location.Line))
+	}
+	return strings.Join(s, " ")
+}
+
+type DiffCommand struct {
+	RFCContentRepository RFCContentRepository
+	DraftRepository      *DraftContentRepository
+	Old                  string
+	New                  string
+	Renderer             *RFCDiffRenderer
+}
+
+func (c *DiffCommand) Execute() error {
+	oldName, oldDoc, err := c.load(c.Old)
+	if err != nil {
+		return err
+	}
+
+	newName, newDoc, err := c.load(c.New)
+	if err != nil {
+		return err
+	}
+
+	c.Renderer.OldName = oldName
+	c.Renderer.NewName = newName
+
+	return c.Renderer.Render(os.Stdout, DiffRFCDocuments(oldDoc, newDoc))
+}
+
+// load reads the RFC with the given number, the file at the given path, or
+// the Internet-Draft with the given name if there is no such file.
+func (c *DiffCommand) load(name string) (string, *RFCDocument, error) {
+	var content []byte
+	var err error
+
+	if number, convErr := strconv.Atoi(name); convErr == nil {
+		content, err = c.RFCContentRepository.FindByNumber(number)
+		name = fmt.Sprintf("RFC %d", number)
+	} else if _, statErr := os.Stat(name); statErr == nil || c.DraftRepository == nil {
+		content, err = ioutil.ReadFile(name)
+	} else if draftName, revision, parseErr := ParseDraftName(name); parseErr == nil {
+		content, revision, err = c.DraftRepository.FindByName(draftName, revision)
+		name = draftName + "-" + revision
+	} else {
+		content, err = ioutil.ReadFile(name)
+	}
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	return name, ParseRFCDocument(content), nil
+}
+
+type DraftGetCommand struct {
+	DraftRepository *DraftContentRepository
+	Name            string
+	Revision        string
+}
+
+func (c *DraftGetCommand) Execute() error {
+	content, _, err := c.DraftRepository.FindByName(c.Name, c.Revision)
+	if err != nil {
+		return err
+	}
+
 	fmt.Println(string(content))
 
 	return nil
 }
+
+type DraftListCommand struct {
+	CacheStore    *DraftCacheStore
+	RFCRepository RFCRepository
+	Format        OutputFormat
+}
+
+type draftListEntry struct {
+	Name      string   `json:"name"`
+	Revisions []string `json:"revisions"`
+	Size      int64    `json:"size"`
+	RFCNumber int      `json:"rfc,omitempty"`
+}
+
+func (c *DraftListCommand) Execute() error {
+	entries, err := c.CacheStore.Entries()
+	if err != nil {
+		return err
+	}
+
+	drafts := []*draftListEntry{}
+	for _, entry := range entries {
+		if len(drafts) == 0 || drafts[len(drafts)-1].Name != entry.Name {
+			draft := draftListEntry{Name: entry.Name}
+			if c.RFCRepository != nil {
+				if rfc, err := c.RFCRepository.FindByDraft(entry.Name); err == nil && rfc != nil {
+					draft.RFCNumber = rfc.Number
+				}
+			}
+			drafts = append(drafts, &draft)
+		}
+
+		draft := drafts[len(drafts)-1]
+		draft.Revisions = append(draft.Revisions, entry.Revision)
+		draft.Size += entry.Size
+	}
+
+	if c.Format == OutputFormatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(drafts)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, draft := range drafts {
+		published := ""
+		if draft.RFCNumber != 0 {
+			published = fmt.Sprintf("published as RFC %d", draft.RFCNumber)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", draft.Name, strings.Join(draft.Revisions, ", "), FormatSize(draft.Size), published)
+	}
+	w.Flush()
+
+	return nil
+}
+
+type ChangesCommand struct {
+	Loader *RFCIndexLoader
+	Format OutputFormat
+
+	// Watched limits the changes to the given RFCs if it is not nil.
+	Watched []int
+
+	// FailOnChanges makes Execute return an ExitError if there are changes.
+	FailOnChanges bool
+}
+
+type rfcIndexChangesReport struct {
+	From    time.Time         `json:"from"`
+	To      time.Time         `json:"to"`
+	Changes []*RFCIndexChange `json:"changes"`
+}
+
+func (c *ChangesCommand) Execute() error {
+	current, err := c.Loader.Load()
+	if err != nil {
+		return err
+	}
+
+	previousDoc, err := c.Loader.CacheStore.GetPrevious(c.Loader.DataFormat)
+	if err != nil {
+		return err
+	} else if previousDoc == nil {
+		fmt.Println("No previous index to compare with; run with -refresh once the RFC index has been updated")
+		return nil
+	}
+
+	previous, err := ParseRFCIndexData(previousDoc, c.Loader.DataFormat)
+	if err != nil {
+		return err
+	}
+
+	report := rfcIndexChangesReport{}
+	for _, change := range DiffRFCIndexes(previous, current) {
+		if c.Watched == nil || containsInt(c.Watched, change.Number) {
+			report.Changes = append(report.Changes, change)
+		}
+	}
+
+	if info, err := c.Loader.CacheStore.StatPrevious(c.Loader.DataFormat); err == nil {
+		report.From = info.ModTime()
+	}
+	if info, err := c.Loader.CacheStore.Stat(c.Loader.DataFormat); err == nil {
+		report.To = info.ModTime()
+	}
+
+	if c.Format == OutputFormatJSON {
+		if report.Changes == nil {
+			report.Changes = []*RFCIndexChange{}
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		c.printChanges(report)
+	}
+
+	if c.FailOnChanges && len(report.Changes) > 0 {
+		return &ExitError{Code: 1}
+	}
+
+	return nil
+}
+
+func (c *ChangesCommand) printChanges(report rfcIndexChangesReport) {
+	fmt.Printf("RFC index changes from %s to %s\n", report.From.Format("2006-01-02"), report.To.Format("2006-01-02"))
+
+	if len(report.Changes) == 0 {
+		fmt.Println("")
+		fmt.Println("No changes")
+		return
+	}
+
+	headings := map[string]string{
+		RFCIndexChangeNew:         "New RFCs",
+		RFCIndexChangeStatus:      "Status changes",
+		RFCIndexChangeObsoletedBy: "Obsoleted",
+		RFCIndexChangeUpdatedBy:   "Updated",
+		RFCIndexChangeErrata:      "New errata",
+	}
+
+	kind := ""
+	for _, change := range report.Changes {
+		if change.Kind != kind {
+			fmt.Printf("\n%s:\n", headings[change.Kind])
+			kind = change.Kind
+		}
+
+		var detail string
+		switch change.Kind {
+		case RFCIndexChangeNew:
+			detail = change.New
+		case RFCIndexChangeStatus:
+			detail = change.Old + " -> " + change.New
+		case RFCIndexChangeObsoletedBy:
+			detail = "obsoleted by " + change.New
+		case RFCIndexChangeUpdatedBy:
+			detail = "updated by " + change.New
+		case RFCIndexChangeErrata:
+			detail = change.New
+		}
+
+		fmt.Printf("  RFC%04d %s (%s)\n", change.Number, change.Title, detail)
+	}
+}
+
+type WatchAddCommand struct {
+	WatchList *WatchList
+	Numbers   []int
+}
+
+func (c *WatchAddCommand) Execute() error {
+	added, err := c.WatchList.Add(c.Numbers...)
+	if err != nil {
+		return err
+	}
+
+	for _, number := range added {
+		fmt.Printf("Watching RFC %d\n", number)
+	}
+
+	return nil
+}
+
+type WatchRemoveCommand struct {
+	WatchList *WatchList
+	Numbers   []int
+}
+
+func (c *WatchRemoveCommand) Execute() error {
+	removed, err := c.WatchList.Remove(c.Numbers...)
+	if err != nil {
+		return err
+	}
+
+	for _, number := range removed {
+		fmt.Printf("No longer watching RFC %d\n", number)
+	}
+
+	return nil
+}
+
+type WatchListCommand struct {
+	WatchList     *WatchList
+	RFCRepository RFCRepository
+	Format        OutputFormat
+}
+
+func (c *WatchListCommand) Execute() error {
+	numbers, err := c.WatchList.Load()
+	if err != nil {
+		return err
+	}
+
+	rfcs := []*RFC{}
+	for _, number := range numbers {
+		rfc, err := c.RFCRepository.FindByNumber(number)
+		if err != nil {
+			return err
+		} else if rfc == nil {
+			rfc = &RFC{Number: number, DocumentID: fmt.Sprintf("RFC%04d", number)}
+		}
+		rfcs = append(rfcs, rfc)
+	}
+
+	if c.Format == OutputFormatJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(rfcs)
+	}
+
+	for _, rfc := range rfcs {
+		status := rfc.Status
+		if rfc.IsObsolete() {
+			status += ", obsolete"
+		}
+		fmt.Printf("%s %s (%s)\n", rfc.DocumentID, rfc.Title, status)
+	}
+
+	return nil
+}
+
+type BrowseCommand struct {
+	RFCRepository        RFCRepository
+	RFCContentRepository RFCContentRepository
+}
+
+func (c *BrowseCommand) Execute() error {
+	browser, err := NewBrowser(c.RFCRepository, c.RFCContentRepository)
+	if err != nil {
+		return err
+	}
+
+	terminal, err := OpenTerminal()
+	if err != nil {
+		return err
+	}
+	defer terminal.Close()
+
+	return browser.Run(terminal)
+}
+
+type PreviewCommand struct {
+	RFCRepository     RFCRepository
+	ContentCacheStore *RFCContentCacheStore
+	Line              string
+	Width             int
+}
+
+func (c *PreviewCommand) Execute() error {
+	number, err := parseFZFLine(c.Line)
+	if err != nil {
+		return err
+	}
+
+	rfc, err := c.RFCRepository.FindByNumber(number)
+	if err != nil {
+		return err
+	} else if rfc == nil {
+		return fmt.Errorf("RFC %d not found", number)
+	}
+
+	for _, line := range browserPreview(rfc, c.Width) {
+		fmt.Println(line)
+	}
+
+	// Only cached RFCs are shown, since previews have to be fast.
+	content, err := c.ContentCacheStore.Get(number)
+	if err != nil || content == nil {
+		return nil
+	}
+
+	doc := ParseRFCDocument(content)
+	if len(doc.Sections) > 0 {
+		fmt.Println("")
+		fmt.Println(ansiBold + "Contents:" + ansiReset)
+		for _, section := range doc.Sections {
+			fmt.Println(truncateRunes(strings.Repeat("  ", section.Level)+section.Number+"  "+section.Title, c.Width))
+		}
+	}
+
+	return nil
+}
+
+type PickCommand struct {
+	RFCRepository        RFCRepository
+	RFCContentRepository RFCContentRepository
+	Query                string
+	Print                bool
+	// FZFPath is the path of fzf. Without it, the best match for the query
+	// is picked.
+	FZFPath string
+	Pager   string
+}
+
+func (c *PickCommand) Execute() error {
+	rfcs, err := c.RFCRepository.FindAll()
+	if err != nil {
+		return err
+	}
+
+	var number int
+	if c.FZFPath != "" {
+		number, err = c.pickWithFZF(rfcs)
+	} else {
+		number, err = c.pickBestMatch(rfcs)
+	}
+	if err != nil || number == 0 {
+		return err
+	}
+
+	if c.Print {
+		fmt.Println(number)
+		return nil
+	}
+
+	command := GetCommand{
+		RFCContentRepository: c.RFCContentRepository,
+		RFCRepository:        c.RFCRepository,
+		RFCNumber:            number,
+		Pager:                c.Pager,
+	}
+
+	return command.Execute()
+}
+
+// pickWithFZF returns the RFC selected in fzf, or zero if the selection was
+// cancelled.
+func (c *PickCommand) pickWithFZF(rfcs []*RFC) (int, error) {
+	tmpl, err := template.New("").Parse(fzfLineTemplate)
+	if err != nil {
+		return 0, err
+	}
+
+	var input bytes.Buffer
+	for _, rfc := range rfcs {
+		tmpl.Execute(&input, rfc)
+		input.WriteString("\n")
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return 0, err
+	}
+
+	cmd := exec.Command(c.FZFPath,
+		"--delimiter", "\t", "--with-nth", "2..", "--ansi",
+		"--query", c.Query,
+		"--preview", fmt.Sprintf("%s preview {}", shellQuote(self)))
+	cmd.Stdin = &input
+	cmd.Stderr = os.Stderr
+
+	output, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && (exitErr.ExitCode() == 1 || exitErr.ExitCode() == 130) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	return parseFZFLine(string(output))
+}
+
+func (c *PickCommand) pickBestMatch(rfcs []*RFC) (int, error) {
+	if c.Query == "" {
+		return 0, fmt.Errorf("fzf not found; give a query to pick the best matching RFC")
+	}
+
+	matches := RankRFCs(rfcs, c.Query)
+	if len(matches) == 0 {
+		return 0, fmt.Errorf("no RFC matches %q", c.Query)
+	}
+
+	tied := 1
+	for tied < len(matches) && matches[tied].Score == matches[0].Score {
+		tied++
+	}
+
+	if tied > 1 {
+		fmt.Fprintf(os.Stderr, "%d RFCs match %q equally well:\n", tied, c.Query)
+		for _, match := range matches[:clamp(tied, 0, 10)] {
+			fmt.Fprintf(os.Stderr, "  %s %s\n", match.RFC.DocumentID, match.RFC.Title)
+		}
+		return 0, &ExitError{Code: 1}
+	}
+
+	return matches[0].RFC.Number, nil
+}
+
+// shellQuote quotes s for use as a single word in a POSIX shell command.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+type ServeCommand struct {
+	Addr   string
+	Server *Server
+}
+
+func (c *ServeCommand) Execute() error {
+	fmt.Printf("Serving RFCs on %s\n", c.Addr)
+
+	server := http.Server{
+		Addr:              c.Addr,
+		Handler:           c.Server.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+	}
+
+	return server.ListenAndServe()
+}
+
+type CacheInfoCommand struct {
+	ContentCacheStore *RFCContentCacheStore
+	DraftCacheStore   *DraftCacheStore
+	IndexCacheStore   *RFCIndexCacheStore
+}
+
+func (c *CacheInfoCommand) Execute() error {
+	cacheDir, err := c.ContentCacheStore.Directory()
+	if err != nil {
+		return err
+	}
+
+	entries, err := c.ContentCacheStore.Entries()
+	if err != nil {
+		return err
+	}
+
+	var totalSize int64
+	counts := make(map[RFCContentFileFormat]int)
+	sizes := make(map[RFCContentFileFormat]int64)
+	compressed := 0
+	for _, entry := range entries {
+		totalSize += entry.Size
+		counts[entry.FileFormat]++
+		sizes[entry.FileFormat] += entry.Size
+		if entry.Compression != CacheCompressionNone {
+			compressed++
+		}
+	}
+
+	drafts, err := c.DraftCacheStore.Entries()
+	if err != nil {
+		return err
+	}
+
+	var draftSize int64
+	for _, draft := range drafts {
+		totalSize += draft.Size
+		draftSize += draft.Size
+		if draft.Compression != CacheCompressionNone {
+			compressed++
+		}
+	}
+
+	fmt.Printf("Location:    %s\n", cacheDir)
+	fmt.Printf("Size:        %s (%d entries, %d compressed)\n", FormatSize(totalSize), len(entries)+len(drafts), compressed)
+	fmt.Printf("Compression: %s\n", c.ContentCacheStore.Compression)
+	fmt.Println("")
+
+	fmt.Println("Entries by format:")
+	for _, format := range []RFCContentFileFormat{RFCContentFileFormatASCII, RFCContentFileFormatPs, RFCContentFileFormatPdf} {
+		fmt.Printf("  %-6s %6d  %s\n", format, counts[format], FormatSize(sizes[format]))
+	}
+	fmt.Printf("  %-6s %6d  %s\n", "draft", len(drafts), FormatSize(draftSize))
+	fmt.Println("")
+
+	fmt.Println("Index:")
+	for _, format := range []RFCIndexDataFormat{RFCIndexDataFormatXML, RFCIndexDataFormatASCII} {
+		fileName, err := format.FileName()
+		if err != nil {
+			return err
+		}
+
+		info, err := c.IndexCacheStore.Stat(format)
+		if os.IsNotExist(err) {
+			fmt.Printf("  %-14s not cached\n", fileName)
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		fmt.Printf("  %-14s %s, updated %s (%s)\n", fileName, FormatSize(info.Size()), FormatAge(info.ModTime()), info.ModTime().Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+type CacheListCommand struct {
+	ContentCacheStore *RFCContentCacheStore
+	DraftCacheStore   *DraftCacheStore
+}
+
+func (c *CacheListCommand) Execute() error {
+	entries, err := c.ContentCacheStore.Entries()
+	if err != nil {
+		return err
+	}
+
+	sort.Sort(ByCacheEntryNumber(entries))
+
+	drafts, err := c.DraftCacheStore.Entries()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "RFC\tFORMAT\tSIZE\tSTORED\tLAST USED")
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", entry.Number, entry.FileFormat, FormatSize(entry.Size), entry.StoredAt.Format("2006-01-02 15:04"), FormatAge(entry.LastUsedAt))
+	}
+	for _, draft := range drafts {
+		fmt.Fprintf(w, "%s-%s\t%s\t%s\t%s\t%s\n", draft.Name, draft.Revision, RFCContentFileFormatASCII, FormatSize(draft.Size), draft.StoredAt.Format("2006-01-02 15:04"), FormatAge(draft.LastUsedAt))
+	}
+
+	return w.Flush()
+}
+
+type CacheVerifyCommand struct {
+	ContentCacheStore *RFCContentCacheStore
+	DraftCacheStore   *DraftCacheStore
+	IndexCacheStore   *RFCIndexCacheStore
+	Repair            bool
+}
+
+func (c *CacheVerifyCommand) Execute() error {
+	entries, err := c.ContentCacheStore.Entries()
+	if err != nil {
+		return err
+	}
+
+	sort.Sort(ByCacheEntryNumber(entries))
+
+	corrupt := 0
+	for _, entry := range entries {
+		err := c.ContentCacheStore.Verify(entry)
+		if err == nil {
+			continue
+		}
+
+		corrupt++
+		fmt.Printf("%s: %v\n", entry.Name(), err)
+
+		if c.Repair && err == ErrCorruptCacheEntry {
+			if err := c.ContentCacheStore.RemoveEntry(entry); err != nil {
+				return err
+			}
+			fmt.Printf("%s: removed\n", entry.Name())
+		}
+	}
+
+	drafts, err := c.DraftCacheStore.Entries()
+	if err != nil {
+		return err
+	}
+
+	for _, draft := range drafts {
+		err := c.DraftCacheStore.Verify(draft)
+		if err == nil {
+			continue
+		}
+
+		corrupt++
+		fmt.Printf("%s: %v\n", draft.FileName(), err)
+
+		if c.Repair && err == ErrCorruptCacheEntry {
+			if err := c.DraftCacheStore.RemoveEntry(draft); err != nil {
+				return err
+			}
+			fmt.Printf("%s: removed\n", draft.FileName())
+		}
+	}
+
+	for _, format := range []RFCIndexDataFormat{RFCIndexDataFormatXML, RFCIndexDataFormatASCII} {
+		if _, err := c.IndexCacheStore.Get(format); err == nil {
+			continue
+		} else if err != ErrCorruptCacheEntry {
+			return err
+		}
+
+		fileName, _ := format.FileName()
+		corrupt++
+		fmt.Printf("%s: %v\n", fileName, err)
+
+		if c.Repair {
+			if err := c.IndexCacheStore.Remove(format); err != nil {
+				return err
+			}
+			if err := c.IndexCacheStore.RemovePrevious(format); err != nil {
+				return err
+			}
+			fmt.Printf("%s: removed\n", fileName)
+		}
+	}
+
+	if corrupt > 0 && !c.Repair {
+		return fmt.Errorf("%d corrupt cache entries found", corrupt)
+	}
+
+	fmt.Printf("%d entries verified, %d corrupt\n", len(entries)+len(drafts), corrupt)
+
+	return nil
+}
+
+type CachePruneCommand struct {
+	ContentCacheStore *RFCContentCacheStore
+	DraftCacheStore   *DraftCacheStore
+	OlderThan         time.Duration
+	MaxSize           int64
+	Keep              int
+	DryRun            bool
+}
+
+// cachePruneEntry is a cached RFC or draft. Both are pruned together, least
+// recently used first.
+type cachePruneEntry struct {
+	name       string
+	size       int64
+	lastUsedAt time.Time
+	remove     func() error
+}
+
+func (c *CachePruneCommand) Execute() error {
+	entries, err := c.ContentCacheStore.Entries()
+	if err != nil {
+		return err
+	}
+
+	drafts, err := c.DraftCacheStore.Entries()
+	if err != nil {
+		return err
+	}
+
+	var candidates []*cachePruneEntry
+	for _, entry := range entries {
+		entry := entry
+		candidates = append(candidates, &cachePruneEntry{
+			name:       entry.Name(),
+			size:       entry.Size,
+			lastUsedAt: entry.LastUsedAt,
+			remove:     func() error { return c.ContentCacheStore.RemoveEntry(entry) },
+		})
+	}
+	for _, draft := range drafts {
+		draft := draft
+		candidates = append(candidates, &cachePruneEntry{
+			name:       draft.FileName(),
+			size:       draft.Size,
+			lastUsedAt: draft.LastUsedAt,
+			remove:     func() error { return c.DraftCacheStore.RemoveEntry(draft) },
+		})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].lastUsedAt.After(candidates[j].lastUsedAt)
+	})
+
+	now := time.Now()
+	var keptSize, prunedSize int64
+	pruned := 0
+
+	for i, entry := range candidates {
+		expired := c.OlderThan > 0 && now.Sub(entry.lastUsedAt) > c.OlderThan
+		overCount := c.Keep > 0 && i >= c.Keep
+		overSize := c.MaxSize > 0 && keptSize+entry.size > c.MaxSize
+
+		if !expired && !overCount && !overSize {
+			keptSize += entry.size
+			continue
+		}
+
+		if !c.DryRun {
+			if err := entry.remove(); err != nil {
+				return err
+			}
+		}
+
+		action := "removed"
+		if c.DryRun {
+			action = "would remove"
+		}
+
+		pruned++
+		prunedSize += entry.size
+		fmt.Printf("%s %s (%s, last used %s)\n", action, entry.name, FormatSize(entry.size), FormatAge(entry.lastUsedAt))
+	}
+
+	if c.DryRun {
+		fmt.Printf("%d entries would be pruned, %s would be freed\n", pruned, FormatSize(prunedSize))
+	} else {
+		fmt.Printf("%d entries pruned, %s freed\n", pruned, FormatSize(prunedSize))
+	}
+
+	return nil
+}
+
+type CacheClearCommand struct {
+	ContentCacheStore  *RFCContentCacheStore
+	IndexCacheStore    *RFCIndexCacheStore
+	ErrataCacheStore   *RFCErrataCacheStore
+	CitationCacheStore *RFCCitationGraphCacheStore
+	DraftCacheStore    *DraftCacheStore
+	IncludeIndex       bool
+}
+
+func (c *CacheClearCommand) Execute() error {
+	entries, err := c.ContentCacheStore.Entries()
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if err := c.ContentCacheStore.RemoveEntry(entry); err != nil {
+			return err
+		}
+	}
+
+	drafts, err := c.DraftCacheStore.Entries()
+	if err != nil {
+		return err
+	}
+
+	for _, draft := range drafts {
+		if err := c.DraftCacheStore.RemoveEntry(draft); err != nil {
+			return err
+		}
+	}
+
+	if err := c.CitationCacheStore.Remove(); err != nil {
+		return err
+	}
+
+	if c.IncludeIndex {
+		for _, format := range []RFCIndexDataFormat{RFCIndexDataFormatXML, RFCIndexDataFormatASCII} {
+			if err := c.IndexCacheStore.Remove(format); err != nil {
+				return err
+			}
+			if err := c.IndexCacheStore.RemovePrevious(format); err != nil {
+				return err
+			}
+		}
+
+		if err := c.IndexCacheStore.RemoveSnapshot(); err != nil {
+			return err
+		}
+
+		if err := c.ErrataCacheStore.Remove(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("%d entries removed\n", len(entries)+len(drafts))
+
+	return nil
+}
+
+type CacheMigrateCommand struct {
+	ContentCacheStore *RFCContentCacheStore
+	IndexCacheStore   *RFCIndexCacheStore
+}
+
+func (c *CacheMigrateCommand) Execute() error {
+	entries, err := c.ContentCacheStore.Entries()
+	if err != nil {
+		return err
+	}
+
+	migrated := 0
+	for _, entry := range entries {
+		if entry.Compression == c.ContentCacheStore.Compression {
+			continue
+		}
+
+		if err := c.ContentCacheStore.Recompress(entry); err != nil {
+			fmt.Printf("%s: %v\n", entry.Name(), err)
+			continue
+		}
+		migrated++
+	}
+
+	for _, format := range []RFCIndexDataFormat{RFCIndexDataFormatXML, RFCIndexDataFormatASCII} {
+		if _, err := c.IndexCacheStore.Get(format); err != nil && err != ErrCorruptCacheEntry {
+			return err
+		}
+	}
+
+	fmt.Printf("%d entries converted to %s\n", migrated, c.ContentCacheStore.Compression)
+
+	return nil
+}
+
+type ByCacheEntryNumber []*RFCContentCacheEntry
+
+func (r ByCacheEntryNumber) Len() int {
+	return len(r)
+}
+
+func (r ByCacheEntryNumber) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
+
+func (r ByCacheEntryNumber) Less(i, j int) bool {
+	if r[i].Number != r[j].Number {
+		return r[i].Number < r[j].Number
+	}
+	return r[i].FileFormat < r[j].FileFormat
+}
+
+type CompletionCommand struct {
+	Shell CompletionShell
+}
+
+func (c *CompletionCommand) Execute() error {
+	fmt.Print(CompletionScript(c.Shell))
+	return nil
+}
+
+type CompleteCommand struct {
+	Completer *Completer
+	Words     []string
+}
+
+func (c *CompleteCommand) Execute() error {
+	completions, err := c.Completer.Complete(c.Words)
+	if err != nil {
+		// The output is read by the shell, so errors must not be printed.
+		return &ExitError{Code: 1}
+	}
+
+	for _, completion := range completions {
+		if completion.Description != "" {
+			fmt.Printf("%s\t%s\n", completion.Value, completion.Description)
+		} else {
+			fmt.Println(completion.Value)
+		}
+	}
+
+	return nil
+}
+
+type ConfigGetCommand struct {
+	Config *Config
+	Key    string
+}
+
+// Execute prints the value in effect, which is the value in the environment
+// for settings, falling back to the config file.
+func (c *ConfigGetCommand) Execute() error {
+	value, ok := c.Config.Get(c.Key)
+	if key := findConfigKey(c.Key); key != nil {
+		if env, set := os.LookupEnv(key.Env); set {
+			value, ok = env, true
+		}
+	}
+
+	if !ok {
+		return &ExitError{Code: 1}
+	}
+
+	fmt.Println(value)
+	return nil
+}
+
+type ConfigSetCommand struct {
+	Config *Config
+	Key    string
+	Value  string
+}
+
+func (c *ConfigSetCommand) Execute() error {
+	if err := ValidateConfigSetting(c.Key, c.Value); err != nil {
+		return err
+	}
+
+	c.Config.Set(c.Key, c.Value)
+
+	return c.Config.Save()
+}
+
+type ConfigUnsetCommand struct {
+	Config *Config
+	Key    string
+}
+
+func (c *ConfigUnsetCommand) Execute() error {
+	if !c.Config.Unset(c.Key) {
+		return fmt.Errorf("%s is not set in %s", c.Key, c.Config.Path)
+	}
+
+	return c.Config.Save()
+}
+
+type ConfigListCommand struct {
+	Config *Config
+}
+
+// Execute prints the settings of the config file, noting those overridden
+// by the environment or global options.
+func (c *ConfigListCommand) Execute() error {
+	fmt.Printf("# %s\n", c.Config.Path)
+
+	table := ""
+	for _, name := range c.Config.Keys() {
+		value, _ := c.Config.Get(name)
+
+		keyTable, key := splitConfigKey(name)
+		if keyTable != table {
+			fmt.Printf("\n[%s]\n", keyTable)
+			table = keyTable
+		}
+
+		line := key + " = " + formatConfigValue(name, value)
+		if setting := findConfigKey(name); setting != nil {
+			if env, set := os.LookupEnv(setting.Env); set && env != setting.environmentValue(value) {
+				line += fmt.Sprintf("  # overridden by %s=%s", setting.Env, env)
+			}
+		}
+
+		fmt.Println(line)
+	}
+
+	return nil
+}
+
+type QuerySaveCommand struct {
+	Config *Config
+	Name   string
+	Args   []string
+}
+
+func (c *QuerySaveCommand) Execute() error {
+	if err := ValidatePresetName(c.Name); err != nil {
+		return err
+	}
+
+	c.Config.Set(joinConfigKey(configPreset, c.Name), JoinArguments(c.Args))
+	if err := c.Config.Save(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Saved query %s, run it with rfcs list @%s\n", c.Name, c.Name)
+	return nil
+}
+
+type QueryListCommand struct {
+	Config *Config
+}
+
+func (c *QueryListCommand) Execute() error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	for _, name := range c.Config.Presets() {
+		value, _ := c.Config.Get(joinConfigKey(configPreset, name))
+		fmt.Fprintf(w, "@%s\t%s\n", name, value)
+	}
+	return w.Flush()
+}
+
+type QueryRemoveCommand struct {
+	Config *Config
+	Names  []string
+}
+
+func (c *QueryRemoveCommand) Execute() error {
+	for _, name := range c.Names {
+		if _, ok := c.Config.Get(joinConfigKey(configPreset, name)); !ok {
+			return fmt.Errorf("unknown query: %s", name)
+		}
+	}
+
+	for _, name := range c.Names {
+		c.Config.Unset(joinConfigKey(configPreset, name))
+	}
+	if err := c.Config.Save(); err != nil {
+		return err
+	}
+
+	for _, name := range c.Names {
+		fmt.Printf("Removed query %s\n", name)
+	}
+
+	return nil
+}
+
+// QueryExportCommand prints saved queries as the presets table of a config
+// file, which QueryImportCommand reads.
+type QueryExportCommand struct {
+	Config *Config
+	Names  []string
+}
+
+func (c *QueryExportCommand) Execute() error {
+	names := c.Names
+	if len(names) == 0 {
+		names = c.Config.Presets()
+	}
+
+	export := &Config{values: make(map[string]string)}
+	for _, name := range names {
+		key := joinConfigKey(configPreset, name)
+		value, ok := c.Config.Get(key)
+		if !ok {
+			return fmt.Errorf("unknown query: %s", name)
+		}
+		export.Set(key, value)
+	}
+
+	_, err := os.Stdout.Write(export.Bytes())
+	return err
+}
+
+// QueryImportCommand adds the presets of a config file, such as one written
+// by QueryExportCommand, to the saved queries. Other settings of the file
+// are ignored.
+type QueryImportCommand struct {
+	Config *Config
+	// Path is the file to import, or - for standard input.
+	Path string
+	// Replace replaces saved queries of the same name with different
+	// options instead of failing.
+	Replace bool
+}
+
+func (c *QueryImportCommand) Execute() error {
+	var content []byte
+	var err error
+	if c.Path == "-" {
+		content, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		content, err = ioutil.ReadFile(c.Path)
+	}
+	if err != nil {
+		return err
+	}
+
+	imported := ParseConfig(c.Path, content)
+	if err := imported.Err(); err != nil {
+		return err
+	}
+
+	var names []string
+	for _, name := range imported.Presets() {
+		key := joinConfigKey(configPreset, name)
+		value, _ := imported.Get(key)
+
+		if existing, ok := c.Config.Get(key); ok {
+			if existing == value {
+				continue
+			}
+			if !c.Replace {
+				return fmt.Errorf("query %s exists with different options, use --replace to replace it", name)
+			}
+		}
+
+		names = append(names, name)
+	}
+
+	for _, name := range names {
+		key := joinConfigKey(configPreset, name)
+		value, _ := imported.Get(key)
+		c.Config.Set(key, value)
+	}
+
+	if len(names) > 0 {
+		if err := c.Config.Save(); err != nil {
+			return err
+		}
+	}
+
+	for _, name := range names {
+		fmt.Printf("Imported query %s\n", name)
+	}
+
+	return nil
+}
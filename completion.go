@@ -0,0 +1,423 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type CompletionShell int
+
+const (
+	CompletionShellBash CompletionShell = iota
+	CompletionShellZsh
+	CompletionShellFish
+)
+
+func toCompletionShell(shell string) (CompletionShell, error) {
+	switch shell {
+	case "bash":
+		return CompletionShellBash, nil
+	case "zsh":
+		return CompletionShellZsh, nil
+	case "fish":
+		return CompletionShellFish, nil
+	}
+	return CompletionShell(0), fmt.Errorf("unknown shell: %s", shell)
+}
+
+// The completion scripts pass the words of the command line after "rfcs" to
+// "rfcs __complete", which prints one candidate per line, optionally followed
+// by a tab and a description. When there is no candidate the shell falls back
+// to completing file names.
+
+const bashCompletionScript = `# bash completion for rfcs
+_rfcs() {
+	local cur=${COMP_WORDS[COMP_CWORD]}
+	local IFS=$'\n'
+	local candidates
+	candidates=($(rfcs __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null | cut -f1))
+	COMPREPLY=($(compgen -W "${candidates[*]}" -- "$cur"))
+}
+complete -o default -F _rfcs rfcs
+`
+
+const zshCompletionScript = `#compdef rfcs
+_rfcs() {
+	local -a completions
+	local line value description
+	for line in "${(@f)$(rfcs __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}"; do
+		[[ -n $line ]] || continue
+		value=${line%%$'\t'*}
+		description=${line#*$'\t'}
+		value=${value//:/\\:}
+		if [[ $description != $line && -n $description ]]; then
+			completions+=("$value:$description")
+		else
+			completions+=("$value")
+		fi
+	done
+	if (( ${#completions} )); then
+		_describe -V rfcs completions
+	else
+		_files
+	fi
+}
+if [[ $funcstack[1] == _rfcs ]]; then
+	_rfcs "$@"
+else
+	compdef _rfcs rfcs
+fi
+`
+
+const fishCompletionScript = `# fish completion for rfcs
+function __rfcs_complete
+	set -l words (commandline -opc) (commandline -ct)
+	set -e words[1]
+	set -l completions (rfcs __complete $words 2>/dev/null)
+	if test (count $completions) -eq 0
+		__fish_complete_path (commandline -ct)
+		return
+	end
+	printf '%s\n' $completions
+end
+complete -c rfcs -f -a '(__rfcs_complete)'
+`
+
+func CompletionScript(shell CompletionShell) string {
+	switch shell {
+	case CompletionShellZsh:
+		return zshCompletionScript
+	case CompletionShellFish:
+		return fishCompletionScript
+	}
+	return bashCompletionScript
+}
+
+type completionFlag struct {
+	Name        string
+	Description string
+	// Bool flags take no value.
+	Bool   bool
+	Values []string
+	// RFC flags take an RFC number.
+	RFC bool
+	// Preset flags take the name of a preset of the config file.
+	Preset bool
+}
+
+type completionCommand struct {
+	Name        string
+	Description string
+	Flags       []*completionFlag
+	Subcommands []*completionCommand
+	// RFCArgs is the number of leading arguments that are RFC numbers, or -1
+	// if all of them are.
+	RFCArgs int
+	// ConfigKeyArg is set if the first argument is a key of the config file.
+	ConfigKeyArg bool
+	// QueryArgs is set if the arguments are saved queries, written with
+	// QueryPrefix.
+	QueryArgs   bool
+	QueryPrefix string
+	// ArgValues are the values of the first argument.
+	ArgValues []string
+}
+
+var (
+	flagValuesPattern = regexp.MustCompile(`\(([a-z0-9-]+(?:, [a-z0-9-]+)+)\)`)
+	flagUsageNotes    = regexp.MustCompile(`(?: \([^()]*\))+$`)
+)
+
+// newCompletionFlags describes the flags of a flag set for completion. Flags
+// taking one of a few values list them in their usage, such as "Output format
+// (text, json)".
+func newCompletionFlags(f *flag.FlagSet, rfcFlags []string, queryFlags []string) []*completionFlag {
+	var flags []*completionFlag
+	f.VisitAll(func(fl *flag.Flag) {
+		completion := completionFlag{
+			Name:        fl.Name,
+			Description: flagUsageNotes.ReplaceAllString(fl.Usage, ""),
+		}
+
+		if value, ok := fl.Value.(interface{ IsBoolFlag() bool }); ok {
+			completion.Bool = value.IsBoolFlag()
+		}
+
+		if m := flagValuesPattern.FindStringSubmatch(fl.Usage); m != nil {
+			completion.Values = strings.Split(m[1], ", ")
+		}
+
+		completion.RFC = containsString(rfcFlags, fl.Name)
+		completion.Preset = containsString(queryFlags, fl.Name)
+
+		flags = append(flags, &completion)
+	})
+	return flags
+}
+
+// newCompletionCommand describes a command for completion, with the flags
+// of its flag set.
+func newCompletionCommand(command *cliCommand) *completionCommand {
+	completion := completionCommand{
+		Name:         command.Name,
+		Description:  command.Summary,
+		RFCArgs:      command.RFCArgs,
+		ConfigKeyArg: command.ConfigKeyArg,
+		QueryArgs:    command.QueryArgs,
+		QueryPrefix:  command.QueryPrefix,
+		ArgValues:    command.ArgValues,
+	}
+
+	if command.Flags != nil {
+		f := command.Flags()
+		if f.Lookup("offline") == nil {
+			(&GlobalOptions{}).AddFlags(f)
+		}
+		completion.Flags = newCompletionFlags(f, command.RFCFlags, command.QueryFlags)
+	}
+
+	for _, subcommand := range command.Subcommands {
+		completion.Subcommands = append(completion.Subcommands, newCompletionCommand(subcommand))
+	}
+
+	return &completion
+}
+
+type Completion struct {
+	Value       string
+	Description string
+}
+
+// Completer completes command lines of rfcs. RFC numbers are completed from
+// the RFC index, which should be read from the cache only so that completing
+// never waits for the network.
+type Completer struct {
+	Commands      []*cliCommand
+	RFCRepository RFCRepository
+}
+
+// Complete returns the candidates for the last of the given words, which are
+// the words after the program name. Flags may be followed by their value
+// either as the next word or after "=", which bash passes as a word of its
+// own, as it does with the @ of saved queries.
+func (c *Completer) Complete(words []string) ([]*Completion, error) {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+
+	current := words[len(words)-1]
+	if current == "=" {
+		current = ""
+	}
+
+	globalFlags := flag.NewFlagSet("rfcs", flag.ContinueOnError)
+	(&GlobalOptions{}).AddFlags(globalFlags)
+
+	root := newCompletionCommand(&cliCommand{Subcommands: append(c.Commands, helpCommand)})
+	root.Flags = newCompletionFlags(globalFlags, nil, nil)
+
+	command := root
+	var pendingFlag *completionFlag
+	args := 0
+	// After help, the words name the command to show the usage of.
+	help := false
+
+	// bash also passes the @ of a saved query as a word of its own.
+	query := len(words) > 1 && words[len(words)-2] == "@"
+
+	for _, word := range words[:len(words)-1] {
+		if word == "=" || word == "@" {
+			continue
+		}
+
+		if pendingFlag != nil {
+			pendingFlag = nil
+			continue
+		}
+
+		if strings.HasPrefix(word, "-") && len(word) > 1 {
+			flag := command.flag(word)
+			if flag != nil && !flag.Bool && !strings.Contains(word, "=") {
+				pendingFlag = flag
+			}
+			continue
+		}
+
+		if args == 0 && len(command.Subcommands) > 0 {
+			if subcommand := command.subcommand(word); subcommand != nil {
+				command = subcommand
+				if command.Name == "help" && !help {
+					command = &completionCommand{Subcommands: root.Subcommands}
+					help = true
+				}
+				continue
+			}
+		}
+
+		args++
+	}
+
+	if pendingFlag != nil {
+		return c.completeFlagValue(pendingFlag, "", current)
+	}
+
+	if strings.HasPrefix(current, "-") {
+		if i := strings.Index(current, "="); i >= 0 {
+			if flag := command.flag(current[:i]); flag != nil {
+				return c.completeFlagValue(flag, current[:i+1], current[i+1:])
+			}
+			return nil, nil
+		}
+
+		var completions []*Completion
+		for _, flag := range command.Flags {
+			if value := "--" + flag.Name; strings.HasPrefix(value, current) {
+				completions = append(completions, &Completion{Value: value, Description: flag.Description})
+			}
+		}
+		return completions, nil
+	}
+
+	if args == 0 && len(command.Subcommands) > 0 {
+		var completions []*Completion
+		for _, subcommand := range command.Subcommands {
+			if strings.HasPrefix(subcommand.Name, current) {
+				completions = append(completions, &Completion{Value: subcommand.Name, Description: subcommand.Description})
+			}
+		}
+		return completions, nil
+	}
+
+	if help {
+		return nil, nil
+	}
+
+	if len(command.ArgValues) > 0 && args == 0 {
+		var completions []*Completion
+		for _, value := range command.ArgValues {
+			if strings.HasPrefix(value, current) {
+				completions = append(completions, &Completion{Value: value})
+			}
+		}
+		return completions, nil
+	}
+
+	if command.ConfigKeyArg && args == 0 {
+		return completeConfigKey(current)
+	}
+
+	if command.QueryArgs && query {
+		return completePreset("", current)
+	}
+
+	if command.QueryArgs && strings.HasPrefix(current, command.QueryPrefix) {
+		return completePreset(command.QueryPrefix, strings.TrimPrefix(current, command.QueryPrefix))
+	}
+
+	if command.RFCArgs < 0 || args < command.RFCArgs {
+		return c.completeRFCNumber("", current)
+	}
+
+	return nil, nil
+}
+
+func (c *Completer) completeFlagValue(flag *completionFlag, prefix string, current string) ([]*Completion, error) {
+	if flag.RFC {
+		return c.completeRFCNumber(prefix, current)
+	}
+
+	if flag.Preset {
+		return completePreset(prefix, current)
+	}
+
+	var completions []*Completion
+	for _, value := range flag.Values {
+		if strings.HasPrefix(value, current) {
+			completions = append(completions, &Completion{Value: prefix + value})
+		}
+	}
+	return completions, nil
+}
+
+func (c *Completer) completeRFCNumber(prefix string, current string) ([]*Completion, error) {
+	if c.RFCRepository == nil {
+		return nil, nil
+	}
+
+	rfcs, err := c.RFCRepository.FindAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var completions []*Completion
+	for _, rfc := range rfcs {
+		if number := strconv.Itoa(rfc.Number); strings.HasPrefix(number, current) {
+			completions = append(completions, &Completion{Value: prefix + number, Description: rfc.Title})
+		}
+	}
+	return completions, nil
+}
+
+func completeConfigKey(current string) ([]*Completion, error) {
+	var completions []*Completion
+	for _, key := range configKeys {
+		if strings.HasPrefix(key.Name, current) {
+			completions = append(completions, &Completion{Value: key.Name, Description: key.Description})
+		}
+	}
+
+	config, err := LoadConfig()
+	if err != nil {
+		return completions, nil
+	}
+	for _, name := range config.Keys() {
+		if table, _ := splitConfigKey(name); table != "" && strings.HasPrefix(name, current) {
+			completions = append(completions, &Completion{Value: name})
+		}
+	}
+	return completions, nil
+}
+
+func completePreset(prefix string, current string) ([]*Completion, error) {
+	config, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var completions []*Completion
+	for _, name := range config.Presets() {
+		if strings.HasPrefix(name, current) {
+			value, _ := config.Get(joinConfigKey(configPreset, name))
+			completions = append(completions, &Completion{Value: prefix + name, Description: value})
+		}
+	}
+	return completions, nil
+}
+
+// flag returns the flag named by a word such as "-format", "--format" or
+// "--format=json".
+func (c *completionCommand) flag(word string) *completionFlag {
+	name := strings.TrimLeft(word, "-")
+	if i := strings.Index(name, "="); i >= 0 {
+		name = name[:i]
+	}
+
+	for _, flag := range c.Flags {
+		if flag.Name == name {
+			return flag
+		}
+	}
+	return nil
+}
+
+func (c *completionCommand) subcommand(name string) *completionCommand {
+	for _, subcommand := range c.Subcommands {
+		if subcommand.Name == name {
+			return subcommand
+		}
+	}
+	return nil
+}
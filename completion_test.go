@@ -0,0 +1,113 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompleterComplete(t *testing.T) {
+	rfcIndex, err := ParseRFCIndex([]byte(testRFCIndexAfter))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	configPath := filepath.Join(newTestCacheDirectory(t), "config.toml")
+	if err := ioutil.WriteFile(configPath, []byte("[presets]\nhttp = \"--wg httpbis\"\nhttp-current = \"--wg httpbis --exclude-obsolete\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Setenv(envConfig, os.Getenv(envConfig))
+	os.Setenv(envConfig, configPath)
+
+	completer := Completer{Commands: cliCommands, RFCRepository: &RFCIndexRFCRepository{RFCIndex: rfcIndex}}
+
+	tests := []struct {
+		words  string
+		values string
+	}{
+		{"li", "list"},
+		{"wat", "watch"},
+		{"watch ", "add remove list check"},
+		{"help wa", "watch"},
+		{"help watch c", "check"},
+		{"--off", "--offline"},
+		{"--offline ge", "get"},
+		{"get 9", "9110"},
+		{"get 9110 ", ""},
+		{"diff 2616 ", "2616 3986 7230 9110"},
+		{"cite 2616 3", "3986"},
+		{"list --categ", "--category"},
+		{"list --category i", "internet-standard informational"},
+		{"list --category=i", "--category=internet-standard --category=informational"},
+		{"list --category = i", "internet-standard informational"},
+		{"list --obsoleted-by 26", "2616"},
+		{"list --preset ", "http http-current"},
+		{"list @h", "@http @http-current"},
+		{"list @ http-", "http-current"},
+		{"list --exclude-obsolete ", ""},
+		{"config get ind", "index_ttl"},
+		{"config get presets.", "presets.http presets.http-current"},
+		{"completion ", "bash zsh fish"},
+		{"completion bash ", ""},
+	}
+
+	for _, test := range tests {
+		words := strings.Split(test.words, " ")
+
+		completions, err := completer.Complete(words)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var values []string
+		for _, completion := range completions {
+			values = append(values, completion.Value)
+		}
+		if got := strings.Join(values, " "); got != test.values {
+			t.Errorf("%q: got %q, want %q", test.words, got, test.values)
+		}
+	}
+}
+
+func TestCompleterCompleteDescriptions(t *testing.T) {
+	rfcIndex, err := ParseRFCIndex([]byte(testRFCIndexAfter))
+	if err != nil {
+		t.Fatal(err)
+	}
+	completer := Completer{Commands: cliCommands, RFCRepository: &RFCIndexRFCRepository{RFCIndex: rfcIndex}}
+
+	completions, err := completer.Complete([]string{"get", "91"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []*Completion{{Value: "9110", Description: "HTTP Semantics"}}; !reflect.DeepEqual(completions, want) {
+		t.Errorf("got RFC completions %+v", completions)
+	}
+
+	completions, err = completer.Complete([]string{"list", "--stre"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(completions) != 1 || completions[0].Description != "List RFCs in the specified document stream" {
+		t.Errorf("got flag completions %+v", completions)
+	}
+}
+
+func TestCompletionScript(t *testing.T) {
+	for _, name := range []string{"bash", "zsh", "fish"} {
+		shell, err := toCompletionShell(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if script := CompletionScript(shell); !strings.Contains(script, "rfcs __complete") {
+			t.Errorf("%s: script does not call rfcs __complete", name)
+		}
+	}
+
+	if _, err := toCompletionShell("powershell"); err == nil {
+		t.Error("got no error for an unknown shell")
+	}
+}
@@ -0,0 +1,521 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	envConfig    = "RFCS_CONFIG"
+	envIndexTTL  = "RFCS_INDEX_TTL"
+	envFormat    = "RFCS_FORMAT"
+	envPager     = "RFCS_PAGER"
+	configPreset = "presets"
+)
+
+type configKey struct {
+	Name        string
+	Env         string
+	Description string
+	// Bool keys are written as TOML booleans instead of strings.
+	Bool bool
+	// Path keys have a leading ~/ expanded to the home directory.
+	Path     bool
+	Validate func(value string) error
+}
+
+func validateConfigBool(value string) error {
+	_, err := strconv.ParseBool(value)
+	return err
+}
+
+func validateConfigChoice(choices ...string) func(string) error {
+	return func(value string) error {
+		for _, choice := range choices {
+			if value == choice {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(choices, ", "))
+	}
+}
+
+// configKeys are the settings of the config file. Each sets the environment
+// variable it overrides, unless the variable is set already.
+var configKeys = []*configKey{
+	{Name: "cache_dir", Env: envCacheDirectory, Description: "Cache directory", Path: true},
+	{Name: "offline", Env: envOffline, Description: "Only use cached data", Bool: true, Validate: validateConfigBool},
+	{Name: "base_url", Env: envBaseURL, Description: "Mirror to fetch RFCs and the RFC index from"},
+	{Name: "draft_url", Env: "RFCS_DRAFT_URL", Description: "Mirror to fetch Internet-Drafts from"},
+	{Name: "errata_url", Env: "RFCS_ERRATA_URL", Description: "URL of the errata feed"},
+	{Name: "cache_compression", Env: "RFCS_CACHE_COMPRESSION", Description: "Compression of cache entries", Validate: validateConfigChoice("none", "gzip")},
+	{Name: "index_ttl", Env: envIndexTTL, Description: "Fetch the RFC index again once it is older than this", Validate: func(value string) error {
+		_, err := ParseDuration(value)
+		return err
+	}},
+	{Name: "format", Env: envFormat, Description: "Default template of rfcs list"},
+	{Name: "output", Env: envOutput, Description: "Default output format", Validate: validateConfigChoice("text", "json")},
+	{Name: "pager", Env: envPager, Description: "Pager for rfcs get on a terminal"},
+	{Name: "color", Env: envColor, Description: "Color output", Validate: validateConfigChoice("auto", "always", "never")},
+	{Name: "verbose", Env: envVerbose, Description: "Log network requests", Bool: true, Validate: validateConfigBool},
+}
+
+func findConfigKey(name string) *configKey {
+	for _, key := range configKeys {
+		if key.Name == name {
+			return key
+		}
+	}
+	return nil
+}
+
+// ValidateConfigSetting checks that the key is a setting or a preset, such
+// as presets.http, and that the value is valid for it.
+func ValidateConfigSetting(name string, value string) error {
+	if table, _ := splitConfigKey(name); table == configPreset {
+		if _, err := SplitArguments(value); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		return nil
+	}
+
+	key := findConfigKey(name)
+	if key == nil {
+		return fmt.Errorf("unknown config key: %s", name)
+	}
+
+	if key.Validate != nil && value != "" {
+		if err := key.Validate(value); err != nil {
+			return fmt.Errorf("invalid value for %s: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidatePresetName checks that the name of a preset can be written as a
+// key of the config file.
+func ValidatePresetName(name string) error {
+	if !configNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid name: %q, use letters, digits, - and _", name)
+	}
+	return nil
+}
+
+var (
+	configNamePattern     = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	configTablePattern    = regexp.MustCompile(`^\[\s*([A-Za-z0-9_-]+)\s*\]$`)
+	configKeyValuePattern = regexp.MustCompile(`^([A-Za-z0-9_-]+)\s*=\s*(.*)$`)
+)
+
+// Config is the config file, a subset of TOML with string, boolean and
+// integer values and the presets table. Its lines are kept so that setting
+// a value preserves comments and layout.
+type Config struct {
+	Path string
+	// Warnings are about lines which are ignored, such as unknown keys.
+	Warnings []string
+	// Errors are about invalid lines, which are ignored as well. Only rfcs
+	// config and rfcs help run with them, so that the file can be fixed.
+	Errors []string
+	lines  []string
+	values map[string]string
+}
+
+// Err returns the first error of the file, if any.
+func (c *Config) Err() error {
+	if len(c.Errors) == 0 {
+		return nil
+	}
+	return errors.New(c.Errors[0])
+}
+
+// DefaultConfigPath returns the file in RFCS_CONFIG, or config.toml in the
+// rfcs config directory if it is not set.
+func DefaultConfigPath() string {
+	if path := os.Getenv(envConfig); path != "" {
+		return path
+	}
+
+	if dir := GetUserConfigDirectory("rfcs"); dir != "" {
+		return filepath.Join(dir, "config.toml")
+	}
+
+	return ""
+}
+
+// LoadConfig reads the config file, which is empty if it does not exist.
+func LoadConfig() (*Config, error) {
+	path := DefaultConfigPath()
+	if path == "" {
+		return nil, fmt.Errorf("cannot determine the config directory")
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Config{Path: path, values: make(map[string]string)}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return ParseConfig(path, content), nil
+}
+
+func ParseConfig(path string, content []byte) *Config {
+	config := Config{Path: path, values: make(map[string]string)}
+
+	text := strings.TrimSuffix(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	if text != "" {
+		config.lines = strings.Split(text, "\n")
+	}
+
+	table := ""
+	for i, line := range config.lines {
+		problem := func(problems *[]string, format string, a ...interface{}) {
+			*problems = append(*problems, fmt.Sprintf("%s:%d: ", path, i+1)+fmt.Sprintf(format, a...))
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if m := configTablePattern.FindStringSubmatch(line); m != nil {
+			table = m[1]
+			if table != configPreset {
+				problem(&config.Warnings, "ignoring unknown table: %s", table)
+			}
+			continue
+		}
+
+		m := configKeyValuePattern.FindStringSubmatch(line)
+		if m == nil {
+			problem(&config.Errors, "expected key = value")
+			continue
+		}
+
+		name := joinConfigKey(table, m[1])
+		if table != "" && table != configPreset {
+			continue
+		} else if table == "" && findConfigKey(name) == nil {
+			problem(&config.Warnings, "ignoring unknown config key: %s", name)
+			continue
+		}
+
+		value, err := parseConfigValue(m[2])
+		if err != nil {
+			problem(&config.Errors, "%v", err)
+			continue
+		}
+
+		if err := ValidateConfigSetting(name, value); err != nil {
+			problem(&config.Errors, "%v", err)
+			continue
+		}
+
+		config.values[name] = value
+	}
+
+	return &config
+}
+
+// parseConfigValue parses a basic or literal string, or a bare value such as
+// a boolean or an integer, followed by an optional comment.
+func parseConfigValue(raw string) (string, error) {
+	var value, rest string
+
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		end := 1
+		for ; end < len(raw) && raw[end] != '"'; end++ {
+			if raw[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(raw) {
+			return "", fmt.Errorf("unterminated string")
+		}
+
+		var err error
+		if value, err = strconv.Unquote(raw[:end+1]); err != nil {
+			return "", fmt.Errorf("invalid string: %s", raw[:end+1])
+		}
+		rest = raw[end+1:]
+	case strings.HasPrefix(raw, "'"):
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated string")
+		}
+		value, rest = raw[1:end+1], raw[end+2:]
+	default:
+		if i := strings.Index(raw, "#"); i >= 0 {
+			raw = raw[:i]
+		}
+		value = strings.TrimSpace(raw)
+		if value != "true" && value != "false" {
+			if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+				return "", fmt.Errorf("invalid value: %s", value)
+			}
+		}
+	}
+
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected text after value: %s", rest)
+	}
+
+	return value, nil
+}
+
+func formatConfigValue(name string, value string) string {
+	if key := findConfigKey(name); key != nil && key.Bool {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return strconv.FormatBool(b)
+		}
+	}
+	return strconv.Quote(value)
+}
+
+func splitConfigKey(name string) (string, string) {
+	if i := strings.Index(name, "."); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+func joinConfigKey(table string, name string) string {
+	if table == "" {
+		return name
+	}
+	return table + "." + name
+}
+
+func (c *Config) Get(name string) (string, bool) {
+	value, ok := c.values[name]
+	return value, ok
+}
+
+// Keys returns the keys set in the file, settings in the order of configKeys
+// first and then presets by name.
+func (c *Config) Keys() []string {
+	var keys, presets []string
+	for _, key := range configKeys {
+		if _, ok := c.values[key.Name]; ok {
+			keys = append(keys, key.Name)
+		}
+	}
+	for name := range c.values {
+		if table, _ := splitConfigKey(name); table == configPreset {
+			presets = append(presets, name)
+		}
+	}
+	sort.Strings(presets)
+	return append(keys, presets...)
+}
+
+// Preset returns the arguments of rfcs list saved under the name.
+func (c *Config) Preset(name string) ([]string, error) {
+	value, ok := c.values[joinConfigKey(configPreset, name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown query: %s", name)
+	}
+	return SplitArguments(value)
+}
+
+// Set sets the value of the key, replacing the line of the key or adding one
+// at the end of its table.
+func (c *Config) Set(name string, value string) {
+	table, key := splitConfigKey(name)
+	line := key + " = " + formatConfigValue(name, value)
+
+	if i := c.find(name); i >= 0 {
+		c.lines[i] = line
+	} else {
+		c.insert(table, line)
+	}
+
+	c.values[name] = value
+}
+
+// Unset removes the key, returning false if it was not set.
+func (c *Config) Unset(name string) bool {
+	i := c.find(name)
+	if i < 0 {
+		return false
+	}
+
+	c.lines = append(c.lines[:i], c.lines[i+1:]...)
+	delete(c.values, name)
+
+	return true
+}
+
+// Presets returns the names of the presets, sorted.
+func (c *Config) Presets() []string {
+	var names []string
+	for name := range c.values {
+		if table, key := splitConfigKey(name); table == configPreset {
+			names = append(names, key)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c *Config) Bytes() []byte {
+	content := strings.Join(c.lines, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	return []byte(content)
+}
+
+func (c *Config) Save() error {
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0755); err != nil {
+		return err
+	}
+
+	return WriteFileAtomic(c.Path, c.Bytes(), 0644)
+}
+
+// find returns the index of the line setting the key, or -1.
+func (c *Config) find(name string) int {
+	table := ""
+	for i, line := range c.lines {
+		line = strings.TrimSpace(line)
+		if m := configTablePattern.FindStringSubmatch(line); m != nil {
+			table = m[1]
+		} else if m := configKeyValuePattern.FindStringSubmatch(line); m != nil && joinConfigKey(table, m[1]) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *Config) insert(table string, line string) {
+	// Insert after the last key of the table, adding the table if there is
+	// none.
+	at := -1
+	current := ""
+	for i, l := range c.lines {
+		l = strings.TrimSpace(l)
+		if m := configTablePattern.FindStringSubmatch(l); m != nil {
+			current = m[1]
+			if current == table {
+				at = i + 1
+			}
+		} else if current == table && configKeyValuePattern.MatchString(l) {
+			at = i + 1
+		}
+	}
+
+	if at < 0 && table == "" {
+		// Keys outside tables have to come before the first table.
+		at = len(c.lines)
+		for i, l := range c.lines {
+			if configTablePattern.MatchString(strings.TrimSpace(l)) {
+				at = i
+				break
+			}
+		}
+		if at < len(c.lines) {
+			c.lines = append(c.lines[:at], append([]string{line, ""}, c.lines[at:]...)...)
+			return
+		}
+	}
+
+	if at < 0 {
+		if len(c.lines) > 0 {
+			c.lines = append(c.lines, "")
+		}
+		c.lines = append(c.lines, "["+table+"]", line)
+		return
+	}
+
+	c.lines = append(c.lines[:at], append([]string{line}, c.lines[at:]...)...)
+}
+
+// ApplyToEnvironment sets the environment variables of the settings in the
+// file which are not set already, so that the environment overrides the
+// file.
+func (c *Config) ApplyToEnvironment() {
+	for _, key := range configKeys {
+		value, ok := c.values[key.Name]
+		if !ok {
+			continue
+		}
+		if _, set := os.LookupEnv(key.Env); set {
+			continue
+		}
+
+		os.Setenv(key.Env, key.environmentValue(value))
+	}
+}
+
+// environmentValue returns the value of the environment variable of the key
+// for a value of the config file, expanding ~/ in paths.
+func (k *configKey) environmentValue(value string) string {
+	if k.Path && strings.HasPrefix(value, "~/") {
+		return filepath.Join(GetHomeDirectory(), value[2:])
+	}
+	return value
+}
+
+// SplitArguments splits a command line into arguments at spaces outside
+// single or double quotes.
+func SplitArguments(s string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg := false
+	quote := rune(0)
+
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %s", s)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args, nil
+}
+
+// JoinArguments is the inverse of SplitArguments, quoting arguments with
+// single quotes where needed.
+func JoinArguments(args []string) string {
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t'\"") {
+			quoted[i] = arg
+		} else {
+			quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'"'"'`) + "'"
+		}
+	}
+	return strings.Join(quoted, " ")
+}
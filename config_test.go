@@ -0,0 +1,163 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testConfig = `# rfcs settings
+cache_dir = "~/rfcs" # comment
+offline = true
+index_ttl = '24h'
+pager = "less -R"
+unknown = "ignored"
+color = "sometimes"
+output = json
+
+[presets]
+http = "--wg httpbis --exclude-obsolete"
+broken = "--wg 'httpbis"
+
+[other]
+key = "value"
+`
+
+func TestParseConfig(t *testing.T) {
+	config := ParseConfig("config.toml", []byte(testConfig))
+
+	values := make(map[string]string)
+	for _, key := range config.Keys() {
+		values[key], _ = config.Get(key)
+	}
+	want := map[string]string{
+		"cache_dir":    "~/rfcs",
+		"offline":      "true",
+		"index_ttl":    "24h",
+		"pager":        "less -R",
+		"presets.http": "--wg httpbis --exclude-obsolete",
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("got values %v, want %v", values, want)
+	}
+
+	if want := []string{"config.toml:6: ignoring unknown config key: unknown", "config.toml:14: ignoring unknown table: other"}; !reflect.DeepEqual(config.Warnings, want) {
+		t.Errorf("got warnings %q", config.Warnings)
+	}
+	if len(config.Errors) != 3 || !strings.HasPrefix(config.Errors[0], "config.toml:7: invalid value for color") || !strings.HasPrefix(config.Errors[1], "config.toml:8: invalid value: json") || !strings.HasPrefix(config.Errors[2], "config.toml:12: presets.broken") {
+		t.Errorf("got errors %q", config.Errors)
+	}
+
+	if args, err := config.Preset("http"); err != nil || !reflect.DeepEqual(args, []string{"--wg", "httpbis", "--exclude-obsolete"}) {
+		t.Errorf("got preset %q, %v", args, err)
+	}
+	if _, err := config.Preset("broken"); err == nil {
+		t.Error("got no error for an invalid preset")
+	}
+}
+
+func TestConfigSetPreservesLayout(t *testing.T) {
+	config := ParseConfig("config.toml", []byte("# rfcs settings\npager = \"less\" # my pager\n\n[presets]\n# HTTP\nhttp = \"--wg httpbis\"\n"))
+
+	config.Set("pager", "more")
+	config.Set("offline", "1")
+	config.Set("presets.tls", "--wg tls")
+	if !config.Unset("presets.http") || config.Unset("color") {
+		t.Error("got wrong results from Unset")
+	}
+
+	want := `# rfcs settings
+pager = "more"
+offline = true
+
+[presets]
+# HTTP
+tls = "--wg tls"
+`
+	if got := string(config.Bytes()); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	reparsed := ParseConfig("config.toml", config.Bytes())
+	if !reflect.DeepEqual(reparsed.Keys(), []string{"offline", "pager", "presets.tls"}) || len(reparsed.Errors) != 0 {
+		t.Errorf("got keys %v and errors %v after saving", reparsed.Keys(), reparsed.Errors)
+	}
+
+	config = ParseConfig("config.toml", nil)
+	config.Set("presets.http", "--wg httpbis")
+	config.Set("pager", "less")
+	if got, want := string(config.Bytes()), "pager = \"less\"\n\n[presets]\nhttp = \"--wg httpbis\"\n"; got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+// restoreEnv restores the environment variables when the test ends.
+func restoreEnv(t *testing.T, names ...string) {
+	for _, name := range names {
+		name := name
+		value, set := os.LookupEnv(name)
+		t.Cleanup(func() {
+			if set {
+				os.Setenv(name, value)
+			} else {
+				os.Unsetenv(name)
+			}
+		})
+	}
+}
+
+func TestConfigApplyToEnvironment(t *testing.T) {
+	restoreEnv(t, envCacheDirectory, envPager, envOffline, envIndexTTL, "HOME")
+
+	os.Setenv("HOME", "/home/test")
+	os.Unsetenv(envCacheDirectory)
+	os.Unsetenv(envOffline)
+	os.Unsetenv(envIndexTTL)
+	os.Setenv(envPager, "most")
+
+	ParseConfig("config.toml", []byte(testConfig)).ApplyToEnvironment()
+
+	tests := map[string]string{
+		envCacheDirectory: filepath.Join("/home/test", "rfcs"),
+		envOffline:        "true",
+		envIndexTTL:       "24h",
+		envPager:          "most",
+	}
+	for name, want := range tests {
+		if got := os.Getenv(name); got != want {
+			t.Errorf("got %s=%q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestSplitArguments(t *testing.T) {
+	tests := []struct {
+		s    string
+		args []string
+	}{
+		{"", nil},
+		{"  --wg   httpbis ", []string{"--wg", "httpbis"}},
+		{`--format "{{.Number}} {{.Title}}"`, []string{"--format", "{{.Number}} {{.Title}}"}},
+		{`--keyword 'it''s' ""`, []string{"--keyword", "its", ""}},
+		{`-q "a 'b' c"`, []string{"-q", "a 'b' c"}},
+	}
+
+	for _, test := range tests {
+		args, err := SplitArguments(test.s)
+		if err != nil || !reflect.DeepEqual(args, test.args) {
+			t.Errorf("%q: got %q, %v, want %q", test.s, args, err, test.args)
+		}
+
+		if test.args != nil {
+			if again, err := SplitArguments(JoinArguments(args)); err != nil || !reflect.DeepEqual(again, test.args) {
+				t.Errorf("%q: got %q, %v after joining as %s", test.s, again, err, JoinArguments(args))
+			}
+		}
+	}
+
+	if _, err := SplitArguments(`--wg "httpbis`); err == nil {
+		t.Error("got no error for an unterminated quote")
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+type DiffKind int
+
+const (
+	DiffEqual DiffKind = iota
+	DiffDelete
+	DiffInsert
+)
+
+// DiffEdit is one step of an edit script. Old is set for equal and deleted
+// elements, New for equal and inserted ones.
+type DiffEdit struct {
+	Kind DiffKind
+	Old  string
+	New  string
+}
+
+// DiffStrings returns the shortest edit script turning a into b, computed
+// with Myers' algorithm.
+func DiffStrings(a, b []string) []DiffEdit {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+
+	v := make([]int, 2*max+3)
+
+	// trace[d] holds v[-d..d] as it was before round d.
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				return backtrackDiff(a, b, trace)
+			}
+		}
+	}
+
+	return nil
+}
+
+func backtrackDiff(a, b []string, trace [][]int) []DiffEdit {
+	var edits []DiffEdit
+
+	x, y := len(a), len(b)
+
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		get := func(k int) int { return v[k+d] }
+
+		k := x - y
+
+		var prevK int
+		if k == -d || k != d && get(k-1) < get(k+1) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := get(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			edits = append(edits, DiffEdit{Kind: DiffEqual, Old: a[x-1], New: b[y-1]})
+			x--
+			y--
+		}
+
+		if x == prevX {
+			edits = append(edits, DiffEdit{Kind: DiffInsert, New: b[y-1]})
+			y--
+		} else {
+			edits = append(edits, DiffEdit{Kind: DiffDelete, Old: a[x-1]})
+			x--
+		}
+	}
+
+	for x > 0 && y > 0 {
+		edits = append(edits, DiffEdit{Kind: DiffEqual, Old: a[x-1], New: b[y-1]})
+		x--
+		y--
+	}
+
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+
+	return edits
+}
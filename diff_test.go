@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffStrings(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		changes int
+	}{
+		{"", "", 0},
+		{"abc", "abc", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"abc", "abd", 2},
+		{"abcabba", "cbabac", 5},
+		{"xaxbxc", "abc", 3},
+		{"abc", "xyz", 6},
+	}
+
+	for _, test := range tests {
+		a, b := strings.Split(test.a, ""), strings.Split(test.b, "")
+		edits := DiffStrings(a, b)
+
+		var old, new []string
+		changes := 0
+		for _, edit := range edits {
+			switch edit.Kind {
+			case DiffEqual:
+				if edit.Old != edit.New {
+					t.Errorf("%q -> %q: equal edit %q != %q", test.a, test.b, edit.Old, edit.New)
+				}
+				old = append(old, edit.Old)
+				new = append(new, edit.New)
+			case DiffDelete:
+				old = append(old, edit.Old)
+				changes++
+			case DiffInsert:
+				new = append(new, edit.New)
+				changes++
+			}
+		}
+
+		if got := strings.Join(old, ""); got != test.a {
+			t.Errorf("%q -> %q: edits give old %q", test.a, test.b, got)
+		}
+		if got := strings.Join(new, ""); got != test.b {
+			t.Errorf("%q -> %q: edits give new %q", test.a, test.b, got)
+		}
+		if changes != test.changes {
+			t.Errorf("%q -> %q: got %d changes, want %d", test.a, test.b, changes, test.changes)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package main
+
+// FileLock is a no-op on platforms without flock(2). Cache writes are still
+// atomic, but concurrent writers are not serialized.
+type FileLock struct{}
+
+func LockFile(path string, exclusive bool) (*FileLock, error) {
+	return &FileLock{}, nil
+}
+
+func (l *FileLock) Unlock() error {
+	return nil
+}
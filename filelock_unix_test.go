@@ -0,0 +1,51 @@
+//go:build unix
+
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockCacheDirectory(t *testing.T) {
+	dir := newTestCacheDirectory(t)
+
+	shared, err := lockCacheDirectory(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Readers share the lock.
+	other, err := lockCacheDirectory(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other.Unlock()
+
+	// A writer waits for the readers.
+	locked := make(chan *FileLock)
+	go func() {
+		lock, err := lockCacheDirectory(dir, true)
+		if err != nil {
+			t.Error(err)
+		}
+		locked <- lock
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("got the exclusive lock while the shared lock was held")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	shared.Unlock()
+
+	select {
+	case lock := <-locked:
+		if lock != nil {
+			lock.Unlock()
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("did not get the exclusive lock after the shared lock was released")
+	}
+}
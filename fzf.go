@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// fzfLineTemplate formats RFCs as tab-separated lines starting with the RFC
+// number, so that fzf can hide the number with --with-nth and pass the line
+// to rfcs preview.
+const fzfLineTemplate = "{{.Number}}\t{{.DocumentID}}\t{{.Title}}\t[{{.Status}}, {{.PublicationDate}}]"
+
+var fzfLineNumberPattern = regexp.MustCompile(`^\s*(?:RFC ?0*)?(\d+)\b`)
+
+// parseFZFLine returns the RFC number at the start of a line in the format of
+// fzfLineTemplate, or of any line starting with "RFC" and a number.
+func parseFZFLine(line string) (int, error) {
+	m := fzfLineNumberPattern.FindStringSubmatch(line)
+	if m == nil {
+		return 0, fmt.Errorf("no RFC number in %q", line)
+	}
+	return strconv.Atoi(m[1])
+}
+
+// fuzzyScore matches the characters of pattern in order against text, ignoring
+// case, the way fzf does. Matches at the start of words and runs of matching
+// characters score higher; gaps between matches lower the score.
+func fuzzyScore(pattern string, text string) (int, bool) {
+	p := []rune(strings.ToLower(strings.Join(strings.Fields(pattern), "")))
+	t := []rune(strings.ToLower(text))
+
+	score := 0
+	previous := -1
+	i := 0
+
+	for j := 0; i < len(p) && j < len(t); j++ {
+		if p[i] != t[j] {
+			continue
+		}
+
+		score++
+		if j == 0 || !unicode.IsLetter(t[j-1]) && !unicode.IsDigit(t[j-1]) {
+			score += 8
+		}
+		if previous >= 0 {
+			if j == previous+1 {
+				score += 4
+			} else {
+				score -= clamp(j-previous-1, 0, 3)
+			}
+		}
+
+		previous = j
+		i++
+	}
+
+	return score, i == len(p)
+}
+
+type fuzzyMatch struct {
+	RFC   *RFC
+	Score int
+}
+
+type ByFuzzyScore []*fuzzyMatch
+
+func (r ByFuzzyScore) Len() int {
+	return len(r)
+}
+
+func (r ByFuzzyScore) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
+
+func (r ByFuzzyScore) Less(i, j int) bool {
+	return r[i].Score > r[j].Score
+}
+
+// RankRFCs returns the RFCs whose number and title match the query, best
+// match first.
+func RankRFCs(rfcs []*RFC, query string) []*fuzzyMatch {
+	var matches []*fuzzyMatch
+
+	for _, rfc := range rfcs {
+		if score, ok := fuzzyScore(query, fmt.Sprintf("%d %s", rfc.Number, rfc.Title)); ok {
+			matches = append(matches, &fuzzyMatch{RFC: rfc, Score: score})
+		}
+	}
+
+	sort.Stable(ByFuzzyScore(matches))
+
+	return matches
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestParseFZFLine(t *testing.T) {
+	tests := []struct {
+		line   string
+		number int
+	}{
+		{"9110\tRFC9110\tHTTP Semantics\t[INTERNET STANDARD, June 2022]", 9110},
+		{"791\tRFC0791\tInternet Protocol", 791},
+		{"RFC0791 Internet Protocol", 791},
+		{"  RFC 2616 Hypertext Transfer Protocol", 2616},
+		{"rfc9110", 0},
+		{"HTTP Semantics", 0},
+		{"", 0},
+	}
+
+	for _, test := range tests {
+		number, err := parseFZFLine(test.line)
+		if test.number == 0 {
+			if err == nil {
+				t.Errorf("%q: got %d, want an error", test.line, number)
+			}
+			continue
+		}
+		if err != nil || number != test.number {
+			t.Errorf("%q: got %d, %v, want %d", test.line, number, err, test.number)
+		}
+	}
+}
+
+func TestFZFLineTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("").Parse(fzfLineTemplate))
+
+	var b strings.Builder
+	rfc := &RFC{Number: 791, DocumentID: "RFC0791", Title: "Internet Protocol", Status: "INTERNET STANDARD", PublicationDate: RFCPublicationDate{Year: 1981, Month: time.September}}
+	if err := tmpl.Execute(&b, rfc); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := b.String(), "791\tRFC0791\tInternet Protocol\t[INTERNET STANDARD, September 1981]"; got != want {
+		t.Errorf("got line %q, want %q", got, want)
+	}
+	if number, err := parseFZFLine(b.String()); err != nil || number != 791 {
+		t.Errorf("got %d, %v from the line", number, err)
+	}
+}
+
+func TestRankRFCs(t *testing.T) {
+	rfcs := []*RFC{
+		{Number: 2616, Title: "Hypertext Transfer Protocol -- HTTP/1.1"},
+		{Number: 7540, Title: "Hypertext Transfer Protocol Version 2 (HTTP/2)"},
+		{Number: 9110, Title: "HTTP Semantics"},
+		{Number: 9111, Title: "HTTP Caching"},
+		{Number: 3986, Title: "Uniform Resource Identifier (URI): Generic Syntax"},
+	}
+
+	tests := []struct {
+		query   string
+		numbers []int
+	}{
+		{"http sem", []int{9110}},
+		{"httpcach", []int{9111}},
+		{"9110", []int{9110}},
+		{"uri", []int{3986}},
+		{"HTTP/2", []int{7540}},
+		{"http", []int{9110, 9111, 2616, 7540}},
+		{"xyz", nil},
+	}
+
+	for _, test := range tests {
+		var numbers []int
+		for _, match := range RankRFCs(rfcs, test.query) {
+			numbers = append(numbers, match.RFC.Number)
+		}
+		if !reflect.DeepEqual(numbers, test.numbers) {
+			t.Errorf("%q: got %v, want %v", test.query, numbers, test.numbers)
+		}
+	}
+}
+
+func TestFuzzyScorePrefersWordStartsAndRuns(t *testing.T) {
+	start, _ := fuzzyScore("sem", "HTTP Semantics")
+	middle, _ := fuzzyScore("sem", "Disassembly Mechanisms")
+	if start <= middle {
+		t.Errorf("got score %d at a word start, %d in a word", start, middle)
+	}
+
+	run, _ := fuzzyScore("abc", "xabc")
+	gaps, _ := fuzzyScore("abc", "xaxbxc")
+	if run <= gaps {
+		t.Errorf("got score %d for a run, %d with gaps", run, gaps)
+	}
+
+	if _, ok := fuzzyScore("cba", "abc"); ok {
+		t.Error("matched characters out of order")
+	}
+}
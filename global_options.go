@@ -0,0 +1,176 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Global options can be given before the command or set in the environment.
+// Flags are written back to the environment, which is how they reach the
+// commands and the processes rfcs starts itself, such as rfcs preview run by
+// fzf.
+const (
+	envCacheDirectory = "RFCS_CACHE_DIR"
+	envOffline        = "RFCS_OFFLINE"
+	envBaseURL        = "RFCS_BASE_URL"
+	envColor          = "RFCS_COLOR"
+	envOutput         = "RFCS_OUTPUT"
+	envVerbose        = "RFCS_VERBOSE"
+)
+
+var ErrOffline = errors.New("network access is disabled in offline mode")
+
+type GlobalOptions struct {
+	CacheDirectory string
+	Offline        bool
+	BaseURL        string
+	Color          string
+	Output         string
+	Verbose        bool
+}
+
+func GlobalOptionsFromEnvironment() *GlobalOptions {
+	options := GlobalOptions{
+		CacheDirectory: os.Getenv(envCacheDirectory),
+		BaseURL:        os.Getenv(envBaseURL),
+		Color:          os.Getenv(envColor),
+		Output:         os.Getenv(envOutput),
+	}
+
+	options.Offline, _ = strconv.ParseBool(os.Getenv(envOffline))
+	options.Verbose, _ = strconv.ParseBool(os.Getenv(envVerbose))
+
+	if options.Color == "" {
+		options.Color = "auto"
+	}
+	if options.Output == "" {
+		options.Output = "text"
+	}
+
+	return &options
+}
+
+func (o *GlobalOptions) AddFlags(f *flag.FlagSet) {
+	f.StringVar(&o.CacheDirectory, "cache-dir", o.CacheDirectory, "Store cached RFCs, indexes and drafts in the given directory ($"+envCacheDirectory+")")
+	f.BoolVar(&o.Offline, "offline", o.Offline, "Only use cached data and never access the network ($"+envOffline+")")
+	f.StringVar(&o.BaseURL, "base-url", o.BaseURL, "Fetch RFCs and the RFC index from the given URL instead of "+defaultRFCEditorBaseURL+" ($"+envBaseURL+")")
+	f.StringVar(&o.Color, "color", o.Color, "Color output (auto, always, never) ($"+envColor+")")
+	f.StringVar(&o.Output, "output", o.Output, "Default output format of commands supporting JSON (text, json) ($"+envOutput+")")
+	f.BoolVar(&o.Verbose, "verbose", o.Verbose, "Log network requests to standard error ($"+envVerbose+")")
+	f.BoolVar(&o.Verbose, "v", o.Verbose, "Shorthand for -verbose")
+}
+
+func (o *GlobalOptions) Validate() error {
+	switch o.Color {
+	case "auto", "always", "never":
+	default:
+		return fmt.Errorf("unknown color mode: %s", o.Color)
+	}
+
+	switch o.Output {
+	case "text", "json":
+	default:
+		return fmt.Errorf("unknown output format: %s", o.Output)
+	}
+
+	return nil
+}
+
+// Apply makes the options take effect for this process and the processes it
+// starts.
+func (o *GlobalOptions) Apply() {
+	setenv := func(name string, value string) {
+		if value != "" {
+			os.Setenv(name, value)
+		} else {
+			os.Unsetenv(name)
+		}
+	}
+
+	setenv(envCacheDirectory, o.CacheDirectory)
+	setenv(envOffline, strconv.FormatBool(o.Offline))
+	setenv(envBaseURL, o.BaseURL)
+	setenv(envColor, o.Color)
+	setenv(envOutput, o.Output)
+	setenv(envVerbose, strconv.FormatBool(o.Verbose))
+
+	// The options are applied again if they are given after the command.
+	if t, ok := http.DefaultTransport.(*globalTransport); ok {
+		t.Offline = o.Offline
+		t.Verbose = o.Verbose
+	} else if o.Offline || o.Verbose {
+		http.DefaultTransport = &globalTransport{
+			Offline:   o.Offline,
+			Verbose:   o.Verbose,
+			Transport: http.DefaultTransport,
+		}
+	}
+}
+
+// isGlobalFlag reports whether the flag is added by AddFlags.
+func isGlobalFlag(name string) bool {
+	f := flag.NewFlagSet("", flag.ContinueOnError)
+	(&GlobalOptions{}).AddFlags(f)
+	return f.Lookup(name) != nil
+}
+
+// globalTransport is installed as the default HTTP transport, which all
+// fetchers use, to refuse requests in offline mode and to log them.
+type globalTransport struct {
+	Offline   bool
+	Verbose   bool
+	Transport http.RoundTripper
+}
+
+func (t *globalTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if !t.Verbose && !t.Offline {
+		return t.Transport.RoundTrip(request)
+	}
+
+	if t.Offline {
+		if t.Verbose {
+			fmt.Fprintf(os.Stderr, "%s %s: offline\n", request.Method, request.URL)
+		}
+		return nil, ErrOffline
+	}
+
+	start := time.Now()
+	response, err := t.Transport.RoundTrip(request)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s %s: %v\n", request.Method, request.URL, err)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s %s: %s (%s)\n", request.Method, request.URL, response.Status, time.Since(start).Round(time.Millisecond))
+	}
+
+	return response, err
+}
+
+// formatVar adds the -format flag of a command supporting JSON output, which
+// defaults to json if the output is set to json.
+func formatVar(f *flag.FlagSet, p *string, value string, usage string) {
+	if os.Getenv(envOutput) == "json" {
+		value = "json"
+	}
+	f.StringVar(p, "format", value, usage)
+}
+
+// jsonFormat returns the value of a -format flag added by formatVar, or json
+// if the output is set to json after the command and the flag was not given.
+func jsonFormat(f *flag.FlagSet, format string) string {
+	if os.Getenv(envOutput) != "json" {
+		return format
+	}
+
+	given := false
+	f.Visit(func(fl *flag.Flag) { given = given || fl.Name == "format" })
+	if given {
+		return format
+	}
+	return "json"
+}
@@ -0,0 +1,26 @@
+package main
+
+import "fmt"
+
+type OutputFormat int
+
+const (
+	OutputFormatText OutputFormat = iota
+	OutputFormatJSON
+	OutputFormatCSV
+	OutputFormatMarkdown
+)
+
+func toOutputFormat(format string) (OutputFormat, error) {
+	switch format {
+	case "text":
+		return OutputFormatText, nil
+	case "json":
+		return OutputFormatJSON, nil
+	case "csv":
+		return OutputFormatCSV, nil
+	case "markdown":
+		return OutputFormatMarkdown, nil
+	}
+	return OutputFormat(0), fmt.Errorf("unknown output format: %s", format)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// PageOutput shows the text with the pager command, such as "less -R", if
+// standard output is a terminal, and prints it otherwise.
+func PageOutput(pager string, text string) error {
+	args := strings.Fields(pager)
+	if len(args) == 0 || !IsTerminal(os.Stdout) {
+		fmt.Print(text)
+		return nil
+	}
+
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	return cmd.Run()
+}
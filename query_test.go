@@ -0,0 +1,118 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSelectRFCsCombinedFilters(t *testing.T) {
+	rfcIndex, err := ParseRFCIndex([]byte(testRFCIndexRelations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repository := &RFCIndexRFCRepository{RFCIndex: rfcIndex}
+
+	tests := []struct {
+		name    string
+		options SelectOptions
+		want    []int
+	}{
+		{"no filters", SelectOptions{}, []int{2616, 4949, 7230, 7231, 8615, 8820, 9110}},
+		{"working group", SelectOptions{WorkingGroup: "httpbis"}, []int{7230, 7231, 9110}},
+		{"working group and not obsolete", SelectOptions{WorkingGroup: "httpbis", ExcludeObsolete: true}, []int{9110}},
+		{"obsoleted by and working group", SelectOptions{ObsoletedBy: 9110, WorkingGroup: "httpbis"}, []int{7230, 7231}},
+		{"obsoleted by and updated by", SelectOptions{ObsoletedBy: 9110, UpdatedBy: 8615}, []int{7230}},
+		{"STD and not obsolete", SelectOptions{STDNumber: 97, ExcludeObsolete: true}, []int{9110}},
+		{"STD and other working group", SelectOptions{STDNumber: 97, WorkingGroup: "http"}, nil},
+		{"FYI and BCP", SelectOptions{FYINumber: 36, BCPNumber: 190}, nil},
+	}
+
+	for _, test := range tests {
+		rfcs, err := SelectRFCs(repository, test.options)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got := rfcNumbers(rfcs); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// executeCommand executes the command and returns its output, failing the
+// test if the command fails.
+func executeCommand(t *testing.T, command interface{ Execute() error }) []byte {
+	t.Helper()
+
+	var err error
+	output := captureStdout(t, func() { err = command.Execute() })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return output
+}
+
+func TestQueryCommands(t *testing.T) {
+	dir := newTestCacheDirectory(t)
+	configPath := filepath.Join(dir, "config.toml")
+	config := ParseConfig(configPath, nil)
+
+	save := &QuerySaveCommand{Config: config, Name: "http", Args: []string{"--wg", "httpbis", "--format", "{{.Number}} {{.Title}}"}}
+	executeCommand(t, save)
+	if err := (&QuerySaveCommand{Config: config, Name: "bad name", Args: []string{"--wg", "tls"}}).Execute(); err == nil {
+		t.Error("got no error for an invalid query name")
+	}
+
+	content, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args, err := ParseConfig(configPath, content).Preset("http"); err != nil || !reflect.DeepEqual(args, save.Args) {
+		t.Errorf("got saved query %q, %v, want %q", args, err, save.Args)
+	}
+
+	exportPath := filepath.Join(dir, "export.toml")
+	export := executeCommand(t, &QueryExportCommand{Config: config})
+	if err := ioutil.WriteFile(exportPath, export, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := (&QueryExportCommand{Config: config, Names: []string{"tls"}}).Execute(); err == nil {
+		t.Error("got no error exporting an unknown query")
+	}
+
+	// Importing a query with the same options leaves it alone, and one with
+	// different options is only replaced when asked to.
+	other := ParseConfig(filepath.Join(dir, "other.toml"), nil)
+	other.Set("presets.http", "--wg httpbis")
+	other.Set("presets.tls", "--wg tls")
+
+	if output := executeCommand(t, &QueryImportCommand{Config: config, Path: exportPath}); len(output) != 0 {
+		t.Errorf("got output %q importing the same queries", output)
+	}
+	if err := (&QueryImportCommand{Config: other, Path: exportPath}).Execute(); err == nil {
+		t.Error("got no error importing a conflicting query")
+	}
+	if got, _ := other.Get("presets.http"); got != "--wg httpbis" {
+		t.Errorf("got query %q after a failed import", got)
+	}
+
+	output := executeCommand(t, &QueryImportCommand{Config: other, Path: exportPath, Replace: true})
+	if got, want := string(output), "Imported query http\n"; got != want {
+		t.Errorf("got output %q, want %q", got, want)
+	}
+	if args, err := other.Preset("http"); err != nil || !reflect.DeepEqual(args, save.Args) {
+		t.Errorf("got imported query %q, %v", args, err)
+	}
+	if got := other.Presets(); !reflect.DeepEqual(got, []string{"http", "tls"}) {
+		t.Errorf("got queries %v", got)
+	}
+
+	executeCommand(t, &QueryRemoveCommand{Config: other, Names: []string{"http", "tls"}})
+	if got := other.Presets(); len(got) != 0 {
+		t.Errorf("got queries %v after removing them", got)
+	}
+	if err := (&QueryRemoveCommand{Config: other, Names: []string{"http"}}).Execute(); err == nil {
+		t.Error("got no error removing an unknown query")
+	}
+}
@@ -6,14 +6,37 @@ import (
 )
 
 type RFC struct {
-	Number          int
-	DocumentID      string
-	Title           string
-	PublicationDate RFCPublicationDate
+	Number          int                `json:"number"`
+	DocumentID      string             `json:"document_id"`
+	Title           string             `json:"title"`
+	Authors         []string           `json:"authors"`
+	Editors         []string           `json:"editors,omitempty"`
+	PublicationDate RFCPublicationDate `json:"publication_date"`
+	Keywords        []string           `json:"keywords,omitempty"`
+	Abstract        string             `json:"abstract,omitempty"`
+	Status          string             `json:"status"`
+	Stream          string             `json:"stream,omitempty"`
+	Area            string             `json:"area,omitempty"`
+	WorkingGroup    string             `json:"working_group,omitempty"`
+	Obsoletes       []int              `json:"obsoletes,omitempty"`
+	ObsoletedBy     []int              `json:"obsoleted_by,omitempty"`
+	Updates         []int              `json:"updates,omitempty"`
+	UpdatedBy       []int              `json:"updated_by,omitempty"`
+	SeeAlso         []int              `json:"see_also,omitempty"`
+	Series          []string           `json:"series,omitempty"`
+	ErrataURL       string             `json:"errata_url,omitempty"`
+	DOI             string             `json:"doi,omitempty"`
+	Draft           string             `json:"draft,omitempty"`
+}
+
+func (r *RFC) IsObsolete() bool {
+	return len(r.ObsoletedBy) > 0
 }
 
 type RFCRepository interface {
 	FindAll() ([]*RFC, error)
+	FindByNumber(number int) (*RFC, error)
+	Search(query string) ([]*RFC, error)
 	FindNonObsolete() ([]*RFC, error)
 	FindObsoletedBy(number int) ([]*RFC, error)
 	FindObsolete(number int) ([]*RFC, error)
@@ -22,8 +45,10 @@ type RFCRepository interface {
 	FindBySTDNumber(number int) ([]*RFC, error)
 	FindByBCPNumber(number int) ([]*RFC, error)
 	FindByFYINumber(number int) ([]*RFC, error)
+	FindByDraft(name string) (*RFC, error)
 	FindByCategory(category RFCCategory) ([]*RFC, error)
 	FindByStream(stream RFCStream) ([]*RFC, error)
+	FindByWorkingGroup(acronym string) ([]*RFC, error)
 }
 
 type RFCCategory int
@@ -65,6 +90,13 @@ func (d RFCPublicationDate) Before(o RFCPublicationDate) bool {
 	}
 }
 
+func (d RFCPublicationDate) MarshalText() ([]byte, error) {
+	if d.Day == 0 {
+		return []byte(fmt.Sprintf("%04d-%02d", d.Year, int(d.Month))), nil
+	}
+	return []byte(fmt.Sprintf("%04d-%02d-%02d", d.Year, int(d.Month), d.Day)), nil
+}
+
 func (d RFCPublicationDate) String() string {
 	if d.Day == 0 {
 		return fmt.Sprintf("%s %d", d.Month, d.Year)
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type CitationStyle int
+
+const (
+	CitationStyleBibTeX CitationStyle = iota
+	CitationStyleRIS
+	CitationStyleCSLJSON
+	CitationStyleXML2RFC
+	CitationStyleMarkdown
+)
+
+func toCitationStyle(style string) (CitationStyle, error) {
+	switch style {
+	case "bibtex":
+		return CitationStyleBibTeX, nil
+	case "ris":
+		return CitationStyleRIS, nil
+	case "csl-json":
+		return CitationStyleCSLJSON, nil
+	case "xml2rfc":
+		return CitationStyleXML2RFC, nil
+	case "markdown":
+		return CitationStyleMarkdown, nil
+	}
+	return CitationStyle(0), fmt.Errorf("unknown citation style: %s", style)
+}
+
+const rfcInfoURLFormat = "https://www.rfc-editor.org/info/rfc%d"
+
+// rfcDOI returns the DOI of the RFC, which the RFC Editor derives from the
+// document number for RFCs published without one in the index.
+func rfcDOI(rfc *RFC) string {
+	if rfc.DOI != "" {
+		return rfc.DOI
+	}
+	return fmt.Sprintf("10.17487/RFC%04d", rfc.Number)
+}
+
+var authorInitialsPattern = regexp.MustCompile(`^(?:[A-Z][a-z]?\.)(?:-?[A-Z][a-z]?\.)*$`)
+
+type citationAuthor struct {
+	FullName string
+	Initials string
+	Surname  string
+	Editor   bool
+}
+
+// citationAuthors splits author names such as "R. Fielding" or
+// "D. Eastlake 3rd" into initials and surname. Names without initials, such
+// as organizations, are kept whole as the surname.
+func citationAuthors(rfc *RFC) []citationAuthor {
+	var authors []citationAuthor
+
+	for _, name := range rfc.Authors {
+		author := citationAuthor{FullName: name}
+
+		words := strings.Fields(name)
+		i := 0
+		for i < len(words)-1 && authorInitialsPattern.MatchString(words[i]) {
+			i++
+		}
+		author.Initials = strings.Join(words[:i], " ")
+		author.Surname = strings.Join(words[i:], " ")
+
+		for _, editor := range rfc.Editors {
+			if editor == name {
+				author.Editor = true
+			}
+		}
+
+		authors = append(authors, author)
+	}
+
+	return authors
+}
+
+// citationSeries returns the series the RFC is part of as name and number,
+// e.g. "STD" and "97".
+func citationSeries(rfc *RFC) [][2]string {
+	var series [][2]string
+	for _, docID := range rfc.Series {
+		if len(docID) > 3 {
+			if number, err := strconv.Atoi(docID[3:]); err == nil {
+				series = append(series, [2]string{docID[:3], strconv.Itoa(number)})
+			}
+		}
+	}
+	return series
+}
+
+func FormatCitations(rfcs []*RFC, style CitationStyle) (string, error) {
+	switch style {
+	case CitationStyleBibTeX:
+		return formatBibTeX(rfcs), nil
+	case CitationStyleRIS:
+		return formatRIS(rfcs), nil
+	case CitationStyleCSLJSON:
+		return formatCSLJSON(rfcs)
+	case CitationStyleXML2RFC:
+		return formatXML2RFC(rfcs)
+	case CitationStyleMarkdown:
+		return formatMarkdownCitations(rfcs), nil
+	}
+	return "", fmt.Errorf("unknown citation style: %v", style)
+}
+
+var bibtexEscaper = strings.NewReplacer(`\`, `\textbackslash{}`, `&`, `\&`, `%`, `\%`, `$`, `\$`, `#`, `\#`, `_`, `\_`, `{`, `\{`, `}`, `\}`)
+
+func formatBibTeX(rfcs []*RFC) string {
+	var b bytes.Buffer
+
+	for i, rfc := range rfcs {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+
+		var names []string
+		for _, author := range citationAuthors(rfc) {
+			names = append(names, bibtexEscaper.Replace(author.FullName))
+		}
+
+		fmt.Fprintf(&b, "@misc{rfc%d,\n", rfc.Number)
+		fmt.Fprintf(&b, "  series = {Request for Comments},\n")
+		fmt.Fprintf(&b, "  number = %d,\n", rfc.Number)
+		fmt.Fprintf(&b, "  howpublished = {RFC %d},\n", rfc.Number)
+		fmt.Fprintf(&b, "  publisher = {RFC Editor},\n")
+		fmt.Fprintf(&b, "  doi = {%s},\n", rfcDOI(rfc))
+		fmt.Fprintf(&b, "  url = {%s},\n", fmt.Sprintf(rfcInfoURLFormat, rfc.Number))
+		if len(names) > 0 {
+			fmt.Fprintf(&b, "  author = {%s},\n", strings.Join(names, " and "))
+		}
+		fmt.Fprintf(&b, "  title = {{%s}},\n", bibtexEscaper.Replace(rfc.Title))
+		fmt.Fprintf(&b, "  year = %d,\n", rfc.PublicationDate.Year)
+		if rfc.PublicationDate.Month != 0 {
+			fmt.Fprintf(&b, "  month = %s,\n", strings.ToLower(rfc.PublicationDate.Month.String()[:3]))
+		}
+		b.WriteString("}\n")
+	}
+
+	return b.String()
+}
+
+func formatRIS(rfcs []*RFC) string {
+	var b bytes.Buffer
+
+	for _, rfc := range rfcs {
+		fmt.Fprintf(&b, "TY  - RPRT\n")
+		for _, author := range citationAuthors(rfc) {
+			if author.Initials != "" {
+				fmt.Fprintf(&b, "AU  - %s, %s\n", author.Surname, author.Initials)
+			} else {
+				fmt.Fprintf(&b, "AU  - %s\n", author.Surname)
+			}
+		}
+		fmt.Fprintf(&b, "TI  - %s\n", rfc.Title)
+		fmt.Fprintf(&b, "T3  - Request for Comments\n")
+		fmt.Fprintf(&b, "IS  - %d\n", rfc.Number)
+		fmt.Fprintf(&b, "PY  - %d\n", rfc.PublicationDate.Year)
+		if rfc.PublicationDate.Month != 0 {
+			fmt.Fprintf(&b, "DA  - %d/%02d/\n", rfc.PublicationDate.Year, int(rfc.PublicationDate.Month))
+		}
+		fmt.Fprintf(&b, "PB  - RFC Editor\n")
+		fmt.Fprintf(&b, "DO  - %s\n", rfcDOI(rfc))
+		fmt.Fprintf(&b, "UR  - %s\n", fmt.Sprintf(rfcInfoURLFormat, rfc.Number))
+		fmt.Fprintf(&b, "ER  - \n")
+	}
+
+	return b.String()
+}
+
+type cslName struct {
+	Family string `json:"family"`
+	Given  string `json:"given,omitempty"`
+}
+
+type cslDate struct {
+	DateParts [][]int `json:"date-parts"`
+}
+
+type cslItem struct {
+	ID              string    `json:"id"`
+	Type            string    `json:"type"`
+	Title           string    `json:"title"`
+	Author          []cslName `json:"author,omitempty"`
+	Issued          cslDate   `json:"issued"`
+	Publisher       string    `json:"publisher"`
+	CollectionTitle string    `json:"collection-title"`
+	Number          string    `json:"number"`
+	DOI             string    `json:"DOI"`
+	URL             string    `json:"URL"`
+}
+
+func formatCSLJSON(rfcs []*RFC) (string, error) {
+	items := []cslItem{}
+
+	for _, rfc := range rfcs {
+		item := cslItem{
+			ID:              fmt.Sprintf("rfc%d", rfc.Number),
+			Type:            "report",
+			Title:           rfc.Title,
+			Publisher:       "RFC Editor",
+			CollectionTitle: "Request for Comments",
+			Number:          strconv.Itoa(rfc.Number),
+			DOI:             rfcDOI(rfc),
+			URL:             fmt.Sprintf(rfcInfoURLFormat, rfc.Number),
+		}
+
+		for _, author := range citationAuthors(rfc) {
+			item.Author = append(item.Author, cslName{Family: author.Surname, Given: author.Initials})
+		}
+
+		date := []int{rfc.PublicationDate.Year}
+		if rfc.PublicationDate.Month != 0 {
+			date = append(date, int(rfc.PublicationDate.Month))
+		}
+		item.Issued.DateParts = [][]int{date}
+
+		items = append(items, item)
+	}
+
+	content, err := json.MarshalIndent(items, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	return string(content) + "\n", nil
+}
+
+type xml2rfcReference struct {
+	XMLName    xml.Name            `xml:"reference"`
+	Anchor     string              `xml:"anchor,attr"`
+	Target     string              `xml:"target,attr"`
+	Title      string              `xml:"front>title"`
+	Authors    []xml2rfcAuthor     `xml:"front>author"`
+	Date       xml2rfcDate         `xml:"front>date"`
+	SeriesInfo []xml2rfcSeriesInfo `xml:"seriesInfo"`
+}
+
+type xml2rfcAuthor struct {
+	Initials string `xml:"initials,attr,omitempty"`
+	Surname  string `xml:"surname,attr"`
+	Fullname string `xml:"fullname,attr"`
+	Role     string `xml:"role,attr,omitempty"`
+}
+
+type xml2rfcDate struct {
+	Year  int    `xml:"year,attr"`
+	Month string `xml:"month,attr,omitempty"`
+}
+
+type xml2rfcSeriesInfo struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+func formatXML2RFC(rfcs []*RFC) (string, error) {
+	var b bytes.Buffer
+
+	for _, rfc := range rfcs {
+		reference := xml2rfcReference{
+			Anchor: fmt.Sprintf("RFC%d", rfc.Number),
+			Target: fmt.Sprintf(rfcInfoURLFormat, rfc.Number),
+			Title:  rfc.Title,
+			Date:   xml2rfcDate{Year: rfc.PublicationDate.Year},
+		}
+
+		if rfc.PublicationDate.Month != 0 {
+			reference.Date.Month = rfc.PublicationDate.Month.String()
+		}
+
+		for _, author := range citationAuthors(rfc) {
+			a := xml2rfcAuthor{Initials: author.Initials, Surname: author.Surname, Fullname: author.FullName}
+			if author.Editor {
+				a.Role = "editor"
+			}
+			reference.Authors = append(reference.Authors, a)
+		}
+
+		for _, series := range citationSeries(rfc) {
+			reference.SeriesInfo = append(reference.SeriesInfo, xml2rfcSeriesInfo{Name: series[0], Value: series[1]})
+		}
+		reference.SeriesInfo = append(reference.SeriesInfo,
+			xml2rfcSeriesInfo{Name: "RFC", Value: strconv.Itoa(rfc.Number)},
+			xml2rfcSeriesInfo{Name: "DOI", Value: rfcDOI(rfc)})
+
+		content, err := xml.MarshalIndent(reference, "", "  ")
+		if err != nil {
+			return "", err
+		}
+
+		b.Write(content)
+		b.WriteString("\n")
+	}
+
+	return b.String(), nil
+}
+
+// formatMarkdownCitations formats references as in the reference sections
+// of RFCs, described in RFC 7322.
+func formatMarkdownCitations(rfcs []*RFC) string {
+	var b bytes.Buffer
+
+	for _, rfc := range rfcs {
+		var parts []string
+
+		authors := citationAuthors(rfc)
+		var names []string
+		for i, author := range authors {
+			name := author.Surname
+			if author.Initials != "" {
+				if i == len(authors)-1 && i > 0 {
+					name = author.Initials + " " + author.Surname
+				} else {
+					name = author.Surname + ", " + author.Initials
+				}
+			}
+			if author.Editor {
+				name += ", Ed."
+			}
+			names = append(names, name)
+		}
+
+		switch len(names) {
+		case 0:
+		case 1:
+			parts = append(parts, names[0])
+		case 2:
+			parts = append(parts, names[0]+" and "+names[1])
+		default:
+			parts = append(parts, strings.Join(names[:len(names)-1], ", ")+", and "+names[len(names)-1])
+		}
+
+		parts = append(parts, `"`+rfc.Title+`"`)
+		for _, series := range citationSeries(rfc) {
+			parts = append(parts, series[0]+" "+series[1])
+		}
+		parts = append(parts, fmt.Sprintf("RFC %d", rfc.Number), "DOI "+rfcDOI(rfc))
+
+		date := strconv.Itoa(rfc.PublicationDate.Year)
+		if rfc.PublicationDate.Month != 0 {
+			date = rfc.PublicationDate.Month.String() + " " + date
+		}
+		parts = append(parts, date, "<"+fmt.Sprintf(rfcInfoURLFormat, rfc.Number)+">")
+
+		fmt.Fprintf(&b, "- [RFC%d] %s.\n", rfc.Number, strings.Join(parts, ", "))
+	}
+
+	return b.String()
+}
+
+var citationIDPattern = regexp.MustCompile(`^(?i)(RFC|BCP|STD|FYI)?\s*0*(\d+)$`)
+
+// parseCitationID parses IDs such as "9110", "RFC9110" or "BCP14" into the
+// series and the number within it. Bare numbers are RFC numbers.
+func parseCitationID(id string) (string, int, error) {
+	m := citationIDPattern.FindStringSubmatch(strings.TrimSpace(id))
+	if m == nil {
+		return "", 0, fmt.Errorf("invalid document ID: %s", id)
+	}
+
+	number, err := strconv.Atoi(m[2])
+	if err != nil {
+		return "", 0, err
+	}
+
+	series := strings.ToUpper(m[1])
+	if series == "" {
+		series = "RFC"
+	}
+
+	return series, number, nil
+}
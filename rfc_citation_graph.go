@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	rfcReferencesHeadingPattern   = regexp.MustCompile(`(?i)^(?:(?:\d+\.(?:\d+\.?)*|[A-Z]\.(?:\d+\.?)*)\s+)?(?:(Normative|Informative)\s+)?References$`)
+	rfcReferenceEntryStartPattern = regexp.MustCompile(`^\s{2,}\[([^\]]+)\]\s*(.*)$`)
+	rfcReferenceEntryTitlePattern = regexp.MustCompile(`"[^"]*"`)
+	rfcReferenceEntryDraftPattern = regexp.MustCompile(`\bdraft-[a-z0-9-]*[a-z0-9]`)
+)
+
+// RFCReferenceEntry is an entry of the references sections of an RFC. Entries
+// under a plain "References" heading, as used by RFCs published before the
+// split into normative and informative references, are informative.
+type RFCReferenceEntry struct {
+	Label     string `json:"label"`
+	RFCNumber int    `json:"rfc,omitempty"`
+	Draft     string `json:"draft,omitempty"`
+	Normative bool   `json:"normative"`
+	Text      string `json:"text"`
+}
+
+// ParseRFCReferenceEntries returns the entries of the references sections of
+// the document in the order they appear.
+func ParseRFCReferenceEntries(doc *RFCDocument) []*RFCReferenceEntry {
+	var entries []*RFCReferenceEntry
+
+	inReferences := false
+	normative := false
+	var entry *RFCReferenceEntry
+
+	finish := func() {
+		if entry != nil {
+			entry.RFCNumber, entry.Draft = referenceEntryTarget(entry.Label, entry.Text)
+			entries = append(entries, entry)
+			entry = nil
+		}
+	}
+
+	for _, line := range doc.Lines {
+		if line != "" && line[0] != ' ' {
+			finish()
+			match := rfcReferencesHeadingPattern.FindStringSubmatch(line)
+			inReferences = match != nil
+			normative = match != nil && strings.EqualFold(match[1], "Normative")
+			continue
+		}
+
+		if !inReferences {
+			continue
+		}
+
+		if match := rfcReferenceEntryStartPattern.FindStringSubmatch(line); match != nil {
+			finish()
+			entry = &RFCReferenceEntry{Label: match[1], Normative: normative, Text: match[2]}
+		} else if strings.TrimSpace(line) == "" {
+			finish()
+		} else if entry != nil {
+			entry.Text = strings.TrimSpace(entry.Text + " " + strings.TrimSpace(line))
+		}
+	}
+
+	finish()
+
+	return entries
+}
+
+// referenceEntryTarget returns the RFC or Internet-Draft a reference entry
+// cites. Titles are ignored since they often mention other RFCs, as in
+// "Ambiguity of Uppercase vs Lowercase in RFC 2119 Key Words".
+func referenceEntryTarget(label string, text string) (int, string) {
+	if strings.HasPrefix(label, "RFC") {
+		if number, err := strconv.Atoi(label[3:]); err == nil {
+			return number, ""
+		}
+	}
+
+	text = rfcReferenceEntryTitlePattern.ReplaceAllString(text, "")
+
+	if match := rfcReferenceEntryRFCPattern.FindStringSubmatch(text); match != nil {
+		number, _ := strconv.Atoi(match[1])
+		return number, ""
+	}
+
+	return 0, rfcReferenceEntryDraftPattern.FindString(text)
+}
+
+type rfcCitationGraphDocument struct {
+	Size     int64                `json:"size"`
+	StoredAt int64                `json:"stored_at"`
+	Entries  []*RFCReferenceEntry `json:"entries"`
+}
+
+// RFCCitationGraph records the reference entries of each cached RFC, and so
+// which RFCs cite each other.
+type RFCCitationGraph struct {
+	Documents map[int]*rfcCitationGraphDocument `json:"documents"`
+}
+
+// RFCCitingEntry is a reference entry of the RFC From that cites another RFC.
+type RFCCitingEntry struct {
+	From int `json:"from"`
+	*RFCReferenceEntry
+}
+
+func (g *RFCCitationGraph) Contains(number int) bool {
+	_, ok := g.Documents[number]
+	return ok
+}
+
+func (g *RFCCitationGraph) References(number int) []*RFCReferenceEntry {
+	if document := g.Documents[number]; document != nil {
+		return document.Entries
+	}
+	return nil
+}
+
+// CitedBy returns the entries of other RFCs that cite the given RFC, ordered
+// by the number of the citing RFC.
+func (g *RFCCitationGraph) CitedBy(number int) []*RFCCitingEntry {
+	var citing []*RFCCitingEntry
+
+	for from, document := range g.Documents {
+		for _, entry := range document.Entries {
+			if entry.RFCNumber == number && from != number {
+				citing = append(citing, &RFCCitingEntry{From: from, RFCReferenceEntry: entry})
+			}
+		}
+	}
+
+	sort.Sort(ByCitingRFC(citing))
+
+	return citing
+}
+
+type ByCitingRFC []*RFCCitingEntry
+
+func (r ByCitingRFC) Len() int {
+	return len(r)
+}
+
+func (r ByCitingRFC) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
+
+func (r ByCitingRFC) Less(i, j int) bool {
+	return r[i].From < r[j].From
+}
+
+type RFCCitationGraphCacheStore struct {
+	CacheDirectory string
+	Compression    CacheCompression
+}
+
+func NewRFCCitationGraphCacheStore() *RFCCitationGraphCacheStore {
+	store := RFCCitationGraphCacheStore{
+		Compression: CacheCompressionFromEnvironment(),
+	}
+
+	return &store
+}
+
+func (s *RFCCitationGraphCacheStore) Put(graph *RFCCitationGraph) error {
+	content, err := json.Marshal(graph)
+	if err != nil {
+		return err
+	}
+
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return putCacheFile(s.cacheFile(cacheDir), content, s.Compression)
+}
+
+// Get returns the cached graph, or an empty graph if none is cached.
+func (s *RFCCitationGraphCacheStore) Get() (*RFCCitationGraph, error) {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, false)
+	if err != nil {
+		return nil, err
+	}
+
+	content, _, _, err := getCacheFile(s.cacheFile(cacheDir))
+	lock.Unlock()
+
+	graph := RFCCitationGraph{Documents: make(map[int]*rfcCitationGraphDocument)}
+
+	if err == ErrCorruptCacheEntry || content == nil {
+		return &graph, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(content, &graph); err != nil || graph.Documents == nil {
+		graph.Documents = make(map[int]*rfcCitationGraphDocument)
+	}
+
+	return &graph, nil
+}
+
+func (s *RFCCitationGraphCacheStore) Remove() error {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return removeCacheFile(s.cacheFile(cacheDir))
+}
+
+func (s *RFCCitationGraphCacheStore) cacheFile(cacheDir string) string {
+	return filepath.Join(cacheDir, "citations.json")
+}
+
+func (s *RFCCitationGraphCacheStore) Directory() (string, error) {
+	if s.CacheDirectory != "" {
+		return s.CacheDirectory, nil
+	}
+
+	if dir := DefaultCacheDirectory(); dir != "" {
+		return dir, nil
+	}
+
+	return "", fmt.Errorf("cannot determine the cache directory")
+}
+
+// RFCCitationGraphRepository keeps the citation graph in the cache up to date
+// with the cached RFCs, parsing only documents added or changed since the
+// graph was last stored.
+type RFCCitationGraphRepository struct {
+	ContentCacheStore *RFCContentCacheStore
+	CacheStore        *RFCCitationGraphCacheStore
+	graph             *RFCCitationGraph
+	mu                sync.Mutex
+}
+
+func NewRFCCitationGraphRepository() *RFCCitationGraphRepository {
+	return &RFCCitationGraphRepository{
+		ContentCacheStore: NewRFCContentCacheStore(),
+		CacheStore:        NewRFCCitationGraphCacheStore(),
+	}
+}
+
+func (r *RFCCitationGraphRepository) Graph() (*RFCCitationGraph, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.graph != nil {
+		return r.graph, nil
+	}
+
+	graph, err := r.CacheStore.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := r.ContentCacheStore.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	changed := false
+	cached := make(map[int]bool)
+
+	for _, entry := range entries {
+		if entry.FileFormat != RFCContentFileFormatASCII {
+			continue
+		}
+		cached[entry.Number] = true
+
+		document := graph.Documents[entry.Number]
+		if document != nil && document.Size == entry.Size && document.StoredAt == entry.StoredAt.Unix() {
+			continue
+		}
+
+		content, err := r.ContentCacheStore.Get(entry.Number)
+		if err != nil || content == nil {
+			continue
+		}
+
+		graph.Documents[entry.Number] = &rfcCitationGraphDocument{
+			Size:     entry.Size,
+			StoredAt: entry.StoredAt.Unix(),
+			Entries:  ParseRFCReferenceEntries(ParseRFCDocument(content)),
+		}
+		changed = true
+	}
+
+	for number := range graph.Documents {
+		if !cached[number] {
+			delete(graph.Documents, number)
+			changed = true
+		}
+	}
+
+	if changed {
+		if err := r.CacheStore.Put(graph); err != nil {
+			return nil, err
+		}
+	}
+
+	r.graph = graph
+
+	return graph, nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const testRFCReferences = `1.  Introduction
+
+   This document cites [HTTP] and [URI].
+
+2.  References
+
+2.1.  Normative References
+
+   [HTTP]     Fielding, R., Ed., Nottingham, M., Ed., and J. Reschke,
+              Ed., "HTTP Semantics", STD 97, RFC 9110,
+              DOI 10.17487/RFC9110, June 2022,
+              <https://www.rfc-editor.org/info/rfc9110>.
+
+   [RFC3986]  Berners-Lee, T., Fielding, R., and L. Masinter, "Uniform
+              Resource Identifier (URI): Generic Syntax", STD 66,
+              RFC 3986, DOI 10.17487/RFC3986, January 2005.
+
+2.2.  Informative References
+
+   [RFC8174]  Leiba, B., "Ambiguity of Uppercase vs Lowercase in RFC
+              2119 Key Words", BCP 14, RFC 8174, May 2017.
+
+   [QUIC]     Thomson, M., "QUIC", Work in Progress, Internet-Draft,
+              draft-ietf-quic-transport-34, 14 January 2021.
+
+   [URI]      "RFC 3986 is cited in this title only".
+
+Author's Address
+
+   Someone
+`
+
+func TestParseRFCReferenceEntries(t *testing.T) {
+	type entry struct {
+		label     string
+		rfc       int
+		draft     string
+		normative bool
+	}
+
+	var got []entry
+	for _, e := range ParseRFCReferenceEntries(ParseRFCDocument([]byte(testRFCReferences))) {
+		got = append(got, entry{e.Label, e.RFCNumber, e.Draft, e.Normative})
+	}
+
+	want := []entry{
+		{"HTTP", 9110, "", true},
+		{"RFC3986", 3986, "", true},
+		{"RFC8174", 8174, "", false},
+		{"QUIC", 0, "draft-ietf-quic-transport-34", false},
+		{"URI", 0, "", false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got entries\n%+v\nwant\n%+v", got, want)
+	}
+
+	old := ParseRFCDocument([]byte("1.  Introduction\n\n   Text.\n\n6.  References\n\n   [1]  Postel, J., \"Internet Protocol\", RFC 791, September 1981.\n"))
+	if entries := ParseRFCReferenceEntries(old); len(entries) != 1 || entries[0].RFCNumber != 791 || entries[0].Normative {
+		t.Errorf("got entries %+v for a plain references section", entries)
+	}
+}
+
+func TestRFCCitationGraphRepository(t *testing.T) {
+	dir := newTestCacheDirectory(t)
+	contentStore := &RFCContentCacheStore{CacheDirectory: dir, FileFormat: RFCContentFileFormatASCII}
+	graphStore := &RFCCitationGraphCacheStore{CacheDirectory: dir}
+
+	if err := contentStore.Put(9999, []byte(testRFCReferences)); err != nil {
+		t.Fatal(err)
+	}
+	if err := contentStore.Put(9112, []byte("1.  Normative References\n\n   [HTTP]  \"HTTP Semantics\", RFC 9110.\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	citedBy := func(graph *RFCCitationGraph, number int) map[int]bool {
+		from := make(map[int]bool)
+		for _, entry := range graph.CitedBy(number) {
+			from[entry.From] = entry.Normative
+		}
+		return from
+	}
+
+	repository := &RFCCitationGraphRepository{ContentCacheStore: contentStore, CacheStore: graphStore}
+	graph, err := repository.Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(graph.References(9999)); got != 5 {
+		t.Errorf("got %d references of RFC 9999, want 5", got)
+	}
+	if got, want := citedBy(graph, 9110), map[int]bool{9112: true, 9999: true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got RFC 9110 cited by %v, want %v", got, want)
+	}
+	if got, want := citedBy(graph, 8174), map[int]bool{9999: false}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got RFC 8174 cited by %v, want %v", got, want)
+	}
+
+	// The graph is stored and updated as RFCs leave the cache.
+	stored, err := graphStore.Get()
+	if err != nil || !stored.Contains(9999) || !stored.Contains(9112) {
+		t.Fatalf("got stored graph %+v, %v", stored, err)
+	}
+
+	if err := contentStore.Remove(9999); err != nil {
+		t.Fatal(err)
+	}
+
+	graph, err = (&RFCCitationGraphRepository{ContentCacheStore: contentStore, CacheStore: graphStore}).Graph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if graph.Contains(9999) {
+		t.Error("graph contains a removed RFC")
+	}
+	if got, want := citedBy(graph, 9110), map[int]bool{9112: true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got RFC 9110 cited by %v, want %v", got, want)
+	}
+}
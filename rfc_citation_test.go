@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testCitationRFC = &RFC{
+	Number:          9110,
+	Title:           "HTTP Semantics",
+	Authors:         []string{"R. Fielding", "M. Nottingham", "J. Reschke"},
+	Editors:         []string{"R. Fielding", "M. Nottingham", "J. Reschke"},
+	PublicationDate: RFCPublicationDate{Year: 2022, Month: time.June},
+	Series:          []string{"STD0097"},
+	DOI:             "10.17487/RFC9110",
+}
+
+func TestCitationAuthors(t *testing.T) {
+	rfc := &RFC{
+		Authors: []string{"R. Fielding", "D. Eastlake 3rd", "J.-L. Le Roux", "Internet Architecture Board", "Ph. Hallam-Baker"},
+		Editors: []string{"D. Eastlake 3rd"},
+	}
+
+	var got [][3]string
+	for _, author := range citationAuthors(rfc) {
+		editor := ""
+		if author.Editor {
+			editor = "editor"
+		}
+		got = append(got, [3]string{author.Initials, author.Surname, editor})
+	}
+
+	want := [][3]string{
+		{"R.", "Fielding", ""},
+		{"D.", "Eastlake 3rd", "editor"},
+		{"J.-L.", "Le Roux", ""},
+		{"", "Internet Architecture Board", ""},
+		{"Ph.", "Hallam-Baker", ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got authors %q, want %q", got, want)
+	}
+}
+
+func TestFormatCitations(t *testing.T) {
+	tests := []struct {
+		style CitationStyle
+		want  string
+	}{
+		{CitationStyleBibTeX, `@misc{rfc9110,
+  series = {Request for Comments},
+  number = 9110,
+  howpublished = {RFC 9110},
+  publisher = {RFC Editor},
+  doi = {10.17487/RFC9110},
+  url = {https://www.rfc-editor.org/info/rfc9110},
+  author = {R. Fielding and M. Nottingham and J. Reschke},
+  title = {{HTTP Semantics}},
+  year = 2022,
+  month = jun,
+}
+`},
+		{CitationStyleRIS, `TY  - RPRT
+AU  - Fielding, R.
+AU  - Nottingham, M.
+AU  - Reschke, J.
+TI  - HTTP Semantics
+T3  - Request for Comments
+IS  - 9110
+PY  - 2022
+DA  - 2022/06/
+PB  - RFC Editor
+DO  - 10.17487/RFC9110
+UR  - https://www.rfc-editor.org/info/rfc9110
+` + "ER  - \n"},
+		{CitationStyleXML2RFC, `<reference anchor="RFC9110" target="https://www.rfc-editor.org/info/rfc9110">
+  <front>
+    <title>HTTP Semantics</title>
+    <author initials="R." surname="Fielding" fullname="R. Fielding" role="editor"></author>
+    <author initials="M." surname="Nottingham" fullname="M. Nottingham" role="editor"></author>
+    <author initials="J." surname="Reschke" fullname="J. Reschke" role="editor"></author>
+    <date year="2022" month="June"></date>
+  </front>
+  <seriesInfo name="STD" value="97"></seriesInfo>
+  <seriesInfo name="RFC" value="9110"></seriesInfo>
+  <seriesInfo name="DOI" value="10.17487/RFC9110"></seriesInfo>
+</reference>
+`},
+		{CitationStyleMarkdown, `- [RFC9110] Fielding, R., Ed., Nottingham, M., Ed., and J. Reschke, Ed., "HTTP Semantics", STD 97, RFC 9110, DOI 10.17487/RFC9110, June 2022, <https://www.rfc-editor.org/info/rfc9110>.
+`},
+	}
+
+	for _, test := range tests {
+		got, err := FormatCitations([]*RFC{testCitationRFC}, test.style)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != test.want {
+			t.Errorf("style %d: got\n%s\nwant\n%s", test.style, got, test.want)
+		}
+	}
+
+	got, err := FormatCitations([]*RFC{testCitationRFC}, CitationStyleCSLJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var items []cslItem
+	if err := json.Unmarshal([]byte(got), &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].ID != "rfc9110" || items[0].Author[2] != (cslName{Family: "Reschke", Given: "J."}) || !reflect.DeepEqual(items[0].Issued.DateParts, [][]int{{2022, 6}}) {
+		t.Errorf("got CSL-JSON items %+v", items)
+	}
+}
+
+func TestFormatCitationsWithoutIndexDOI(t *testing.T) {
+	rfc := &RFC{Number: 791, Title: "Internet Protocol", Authors: []string{"J. Postel"}, PublicationDate: RFCPublicationDate{Year: 1981, Month: time.September}}
+
+	got, err := FormatCitations([]*RFC{rfc}, CitationStyleXML2RFC)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reference xml2rfcReference
+	if err := xml.Unmarshal([]byte(got), &reference); err != nil {
+		t.Fatal(err)
+	}
+	if doi := reference.SeriesInfo[len(reference.SeriesInfo)-1]; doi.Value != "10.17487/RFC0791" {
+		t.Errorf("got DOI %q", doi.Value)
+	}
+}
+
+func TestParseCitationID(t *testing.T) {
+	tests := []struct {
+		id     string
+		series string
+		number int
+	}{
+		{"9110", "RFC", 9110},
+		{"RFC9110", "RFC", 9110},
+		{"rfc 0791", "RFC", 791},
+		{"BCP14", "BCP", 14},
+		{"std0097", "STD", 97},
+		{"FYI 36", "FYI", 36},
+		{"RFCX", "", 0},
+		{"draft-ietf-httpbis-semantics", "", 0},
+	}
+
+	for _, test := range tests {
+		series, number, err := parseCitationID(test.id)
+		if test.series == "" {
+			if err == nil {
+				t.Errorf("%q: got %s %d, want an error", test.id, series, number)
+			}
+			continue
+		}
+		if err != nil || series != test.series || number != test.number {
+			t.Errorf("%q: got %s %d, %v, want %s %d", test.id, series, number, err, test.series, test.number)
+		}
+	}
+}
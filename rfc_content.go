@@ -1,14 +1,28 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
 )
 
+var ErrRFCContentNotCached = errors.New("RFC is not cached")
+
+// ErrRFCContentNotFound is returned when the RFC Editor has no text for an RFC.
+var ErrRFCContentNotFound = errors.New("RFC is not found")
+
+// ErrUnverifiedCacheEntry is returned along with the content of a cached RFC
+// stored without a checksum whose size differs from the one in the RFC index.
+// The entry may be truncated, or the index may be out of date.
+var ErrUnverifiedCacheEntry = errors.New("cached RFC does not match the size in the RFC index")
+
 type DefaultRFCContentRepository struct {
 	Fetcher    *RFCContentFetcher
 	CacheStore *RFCContentCacheStore
@@ -16,11 +30,31 @@ type DefaultRFCContentRepository struct {
 
 func (r *DefaultRFCContentRepository) FindByNumber(number int) ([]byte, error) {
 	if r.CacheStore != nil {
-		if content, _ := r.CacheStore.Get(number); content != nil {
+		content, err := r.CacheStore.Get(number)
+		switch {
+		case err == nil && content != nil:
 			return content, nil
+		case err == ErrUnverifiedCacheEntry:
+			// The entry is only replaced if the RFC can be fetched again,
+			// which is not the case offline or without a fetcher.
+			if fetched, err := r.fetch(number); err == nil {
+				return fetched, nil
+			}
+			fmt.Fprintf(os.Stderr, "warning: RFC %d: %v\n", number, err)
+			return content, nil
+		case err == ErrCorruptCacheEntry:
+			r.CacheStore.Remove(number)
 		}
 	}
 
+	return r.fetch(number)
+}
+
+func (r *DefaultRFCContentRepository) fetch(number int) ([]byte, error) {
+	if r.Fetcher == nil {
+		return nil, ErrRFCContentNotCached
+	}
+
 	content, err := r.Fetcher.Fetch(number)
 	if err != nil {
 		return nil, err
@@ -36,7 +70,7 @@ func (r *DefaultRFCContentRepository) FindByNumber(number int) ([]byte, error) {
 func NewDefaultRFCContentRepository() *DefaultRFCContentRepository {
 	repository := DefaultRFCContentRepository{
 		Fetcher:    &RFCContentFetcher{FileFormat: RFCContentFileFormatASCII},
-		CacheStore: &RFCContentCacheStore{},
+		CacheStore: NewRFCContentCacheStore(),
 	}
 
 	return &repository
@@ -58,6 +92,12 @@ func (f *RFCContentFetcher) Fetch(number int) ([]byte, error) {
 	}
 	defer response.Body.Close()
 
+	if response.StatusCode == http.StatusNotFound {
+		return nil, ErrRFCContentNotFound
+	} else if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", rfcURL, response.Status)
+	}
+
 	return ioutil.ReadAll(response.Body)
 }
 
@@ -69,60 +109,397 @@ const (
 	RFCContentFileFormatPdf
 )
 
+const defaultRFCEditorBaseURL = "http://www.rfc-editor.org"
+
+// RFCEditorBaseURL returns the URL in RFCS_BASE_URL, or the RFC Editor
+// website if it is not set. RFCs and the RFC index are fetched from it.
+func RFCEditorBaseURL() string {
+	if baseURL := os.Getenv(envBaseURL); baseURL != "" {
+		return strings.TrimSuffix(baseURL, "/")
+	}
+	return defaultRFCEditorBaseURL
+}
+
 func (f RFCContentFileFormat) URLFor(number int) (string, error) {
 	switch f {
 	case RFCContentFileFormatASCII:
-		return fmt.Sprintf("http://www.rfc-editor.org/rfc/rfc%d.txt", number), nil
+		return fmt.Sprintf("%s/rfc/rfc%d.txt", RFCEditorBaseURL(), number), nil
 	case RFCContentFileFormatPs:
-		return fmt.Sprintf("http://www.rfc-editor.org/rfc/rfc%d.ps", number), nil
+		return fmt.Sprintf("%s/rfc/rfc%d.ps", RFCEditorBaseURL(), number), nil
 	case RFCContentFileFormatPdf:
-		return fmt.Sprintf("http://www.rfc-editor.org/rfc/rfc%d.pdf", number), nil
+		return fmt.Sprintf("%s/rfc/rfc%d.pdf", RFCEditorBaseURL(), number), nil
 	}
 	return "", fmt.Errorf("no URL available for file format: %v", f)
 }
 
+func (f RFCContentFileFormat) Extension() (string, error) {
+	switch f {
+	case RFCContentFileFormatASCII:
+		return "txt", nil
+	case RFCContentFileFormatPs:
+		return "ps", nil
+	case RFCContentFileFormatPdf:
+		return "pdf", nil
+	}
+	return "", fmt.Errorf("no extension available for file format: %v", f)
+}
+
+func (f RFCContentFileFormat) String() string {
+	switch f {
+	case RFCContentFileFormatASCII:
+		return "ASCII"
+	case RFCContentFileFormatPs:
+		return "PS"
+	case RFCContentFileFormatPdf:
+		return "PDF"
+	}
+	return fmt.Sprintf("RFCContentFileFormat(%d)", int(f))
+}
+
+func toRFCContentFileFormat(extension string) (RFCContentFileFormat, error) {
+	switch extension {
+	case "txt":
+		return RFCContentFileFormatASCII, nil
+	case "ps":
+		return RFCContentFileFormatPs, nil
+	case "pdf":
+		return RFCContentFileFormatPdf, nil
+	}
+	return RFCContentFileFormat(0), fmt.Errorf("unknown file extension: %s", extension)
+}
+
+type RFCContentSizeProvider interface {
+	ContentSize(number int, format RFCContentFileFormat) (int, bool)
+}
+
+type RFCContentCacheEntry struct {
+	Number      int
+	FileFormat  RFCContentFileFormat
+	Compression CacheCompression
+	Path        string
+	Size        int64
+	StoredAt    time.Time
+	LastUsedAt  time.Time
+}
+
+func (e *RFCContentCacheEntry) Name() string {
+	return filepath.Base(e.Path) + e.Compression.Extension()
+}
+
+var rfcContentCacheFileNamePattern = regexp.MustCompile(`^(?:rfc(\d+)\.(txt|ps|pdf)|(\d+))(\.gz)?$`)
+
 type RFCContentCacheStore struct {
 	CacheDirectory string
+	FileFormat     RFCContentFileFormat
+	Compression    CacheCompression
+	SizeProvider   RFCContentSizeProvider
+}
+
+func NewRFCContentCacheStore() *RFCContentCacheStore {
+	store := RFCContentCacheStore{
+		Compression:  CacheCompressionFromEnvironment(),
+		SizeProvider: &RFCIndexContentSizeProvider{Loader: &RFCIndexLoader{DataFormat: RFCIndexDataFormatXML, CacheStore: NewRFCIndexCacheStore()}},
+	}
+
+	return &store
 }
 
 func (s *RFCContentCacheStore) Put(number int, content []byte) error {
-	cacheDir, err := s.cacheDirectory()
+	cacheDir, err := s.Directory()
 	if err != nil {
 		return err
 	}
 
-	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+	cacheFile, err := s.cacheFile(cacheDir, number)
+	if err != nil {
 		return err
 	}
 
-	cacheFile := filepath.Join(cacheDir, strconv.Itoa(number))
+	lock, err := lockCacheDirectory(cacheDir, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
 
-	return ioutil.WriteFile(cacheFile, content, 0644)
+	return putCacheFile(cacheFile, content, s.Compression)
 }
 
+// Get returns nil content if the RFC is not cached. A legacy entry not
+// matching the RFC index is returned along with ErrUnverifiedCacheEntry.
 func (s *RFCContentCacheStore) Get(number int) ([]byte, error) {
-	cacheDir, err := s.cacheDirectory()
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return nil, err
+	}
+
+	cacheFile, err := s.cacheFile(cacheDir, number)
 	if err != nil {
 		return nil, err
 	}
 
-	cacheFile := filepath.Join(cacheDir, strconv.Itoa(number))
+	content, hasChecksum, compression, err := s.read(cacheDir, cacheFile, number)
+	if err != nil || content == nil {
+		return content, err
+	}
+
+	// Rewriting the entry converts it to the configured compression and
+	// stores a checksum for entries written before checksums were stored.
+	if compression != s.Compression || !hasChecksum {
+		s.Put(number, content)
+	}
+
+	return content, nil
+}
+
+func (s *RFCContentCacheStore) read(cacheDir string, cacheFile string, number int) ([]byte, bool, CacheCompression, error) {
+	if err := s.migrateLegacyEntry(cacheDir, cacheFile, number); err != nil {
+		return nil, false, CacheCompressionNone, err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, false)
+	if err != nil {
+		return nil, false, CacheCompressionNone, err
+	}
+
+	content, hasChecksum, compression, err := getCacheFile(cacheFile)
+	lock.Unlock()
+	if err != nil || content == nil {
+		return content, hasChecksum, compression, err
+	}
+
+	touchCacheFile(cacheDir, cacheFile)
+
+	if hasChecksum {
+		return content, hasChecksum, compression, nil
+	}
 
-	if _, err = os.Stat(cacheFile); os.IsNotExist(err) {
+	// Entries written before checksums were stored can only be checked
+	// against the size recorded in the RFC index.
+	if err := s.verifySize(number, s.FileFormat, content); err != nil {
+		return content, hasChecksum, compression, ErrUnverifiedCacheEntry
+	}
+
+	return content, hasChecksum, compression, nil
+}
+
+func (s *RFCContentCacheStore) Remove(number int) error {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return err
+	}
+
+	cacheFile, err := s.cacheFile(cacheDir, number)
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return removeCacheFile(cacheFile)
+}
+
+func (s *RFCContentCacheStore) Entries() ([]*RFCContentCacheEntry, error) {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(cacheDir)
+	if os.IsNotExist(err) {
 		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var entries []*RFCContentCacheEntry
+
+	// An RFC may be stored twice, as a legacy entry not migrated yet or with
+	// two compressions if converting it was interrupted. Only the copy read
+	// by Get is listed: the files are sorted by name, so legacy entries come
+	// first and uncompressed files before compressed ones.
+	type listedEntry struct {
+		index  int
+		legacy bool
+	}
+	listed := make(map[string]listedEntry)
+
+	for _, file := range files {
+		match := rfcContentCacheFileNamePattern.FindStringSubmatch(file.Name())
+		if match == nil || file.IsDir() {
+			continue
+		}
+
+		entry := RFCContentCacheEntry{
+			Path:       filepath.Join(cacheDir, strings.TrimSuffix(file.Name(), match[4])),
+			Size:       file.Size(),
+			StoredAt:   file.ModTime(),
+			LastUsedAt: file.ModTime(),
+		}
+
+		if match[4] != "" {
+			entry.Compression = CacheCompressionGzip
+		}
+
+		if match[3] != "" {
+			entry.Number, _ = strconv.Atoi(match[3])
+			entry.FileFormat = RFCContentFileFormatASCII
+		} else {
+			entry.Number, _ = strconv.Atoi(match[1])
+			entry.FileFormat, _ = toRFCContentFileFormat(match[2])
+		}
+
+		if info, err := os.Stat(checksumFileFor(entry.Path)); err == nil {
+			entry.LastUsedAt = info.ModTime()
+		}
+
+		key := fmt.Sprintf("%d.%s", entry.Number, entry.FileFormat)
+		legacy := match[3] != ""
+		if previous, ok := listed[key]; ok {
+			if previous.legacy && !legacy {
+				entries[previous.index] = &entry
+				listed[key] = listedEntry{index: previous.index}
+			}
+			continue
+		}
+
+		listed[key] = listedEntry{index: len(entries), legacy: legacy}
+		entries = append(entries, &entry)
+	}
+
+	return entries, nil
+}
+
+func (s *RFCContentCacheStore) Verify(entry *RFCContentCacheEntry) error {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, false)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	content, hasChecksum, _, err := getCacheFile(entry.Path)
+	if err != nil {
+		return err
+	} else if content == nil {
+		return os.ErrNotExist
+	}
+
+	if hasChecksum {
+		return nil
+	}
+
+	return s.verifySize(entry.Number, entry.FileFormat, content)
+}
+
+func (s *RFCContentCacheStore) Recompress(entry *RFCContentCacheEntry) error {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	content, _, compression, err := getCacheFile(entry.Path)
+	if err != nil {
+		return err
+	} else if content == nil {
+		return os.ErrNotExist
+	}
+
+	if compression == s.Compression {
+		return nil
+	}
+
+	return putCacheFile(entry.Path, content, s.Compression)
+}
+
+func (s *RFCContentCacheStore) RemoveEntry(entry *RFCContentCacheEntry) error {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return err
 	}
 
-	return ioutil.ReadFile(cacheFile)
+	lock, err := lockCacheDirectory(cacheDir, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return removeCacheFile(entry.Path)
 }
 
-func (s *RFCContentCacheStore) cacheDirectory() (string, error) {
+func (s *RFCContentCacheStore) Directory() (string, error) {
 	if s.CacheDirectory != "" {
 		return s.CacheDirectory, nil
 	}
 
-	if dir := GetUserCacheDirectory("rfcs"); dir != "" {
+	if dir := DefaultCacheDirectory(); dir != "" {
 		return dir, nil
 	}
 
 	return "", fmt.Errorf("cannot determine the cache directory")
 }
+
+func (s *RFCContentCacheStore) cacheFile(cacheDir string, number int) (string, error) {
+	extension, err := s.FileFormat.Extension()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(cacheDir, fmt.Sprintf("rfc%d.%s", number, extension)), nil
+}
+
+// Older versions stored plain-text RFCs in files named only by number. They
+// are renamed with the cache directory locked exclusively, or removed if the
+// RFC has been cached under its new name in the meantime.
+func (s *RFCContentCacheStore) migrateLegacyEntry(cacheDir string, cacheFile string, number int) error {
+	if s.FileFormat != RFCContentFileFormatASCII {
+		return nil
+	}
+
+	legacyFile := filepath.Join(cacheDir, strconv.Itoa(number))
+	if info, _ := statCacheFile(legacyFile); info == nil {
+		return nil
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	// Another process may have migrated the entry in the meantime.
+	if info, _ := statCacheFile(legacyFile); info == nil {
+		return nil
+	}
+
+	if info, _ := statCacheFile(cacheFile); info != nil {
+		return removeCacheFile(legacyFile)
+	}
+
+	return renameCacheFile(legacyFile, cacheFile)
+}
+
+func (s *RFCContentCacheStore) verifySize(number int, format RFCContentFileFormat, content []byte) error {
+	if s.SizeProvider == nil {
+		return nil
+	}
+
+	if size, ok := s.SizeProvider.ContentSize(number, format); ok && size != len(content) {
+		return ErrCorruptCacheEntry
+	}
+
+	return nil
+}
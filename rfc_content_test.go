@@ -0,0 +1,156 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fixedContentSize reports the same size for every RFC.
+type fixedContentSize int
+
+func (s fixedContentSize) ContentSize(number int, format RFCContentFileFormat) (int, bool) {
+	return int(s), true
+}
+
+// newLegacyContentCacheStore returns a cache store in a temporary directory
+// holding RFC 9110 as a legacy entry, without a checksum, whose size does not
+// match the RFC index.
+func newLegacyContentCacheStore(t *testing.T) *RFCContentCacheStore {
+	dir, err := ioutil.TempDir("", "rfcs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "9110"), []byte("cached RFC 9110\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return &RFCContentCacheStore{
+		CacheDirectory: dir,
+		FileFormat:     RFCContentFileFormatASCII,
+		SizeProvider:   fixedContentSize(100000),
+	}
+}
+
+func TestRFCContentRepositoryKeepsUnverifiedEntryWithoutFetcher(t *testing.T) {
+	store := newLegacyContentCacheStore(t)
+	repository := DefaultRFCContentRepository{CacheStore: store}
+
+	content, err := repository.FindByNumber(9110)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "cached RFC 9110\n" {
+		t.Errorf("got content %q", content)
+	}
+
+	if _, err := os.Stat(filepath.Join(store.CacheDirectory, "rfc9110.txt")); err != nil {
+		t.Errorf("cached entry was not kept: %v", err)
+	}
+}
+
+func TestRFCContentRepositoryReplacesUnverifiedEntry(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rfc/rfc9110.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("fetched RFC 9110\n"))
+	}))
+	defer ts.Close()
+
+	defer os.Setenv(envBaseURL, os.Getenv(envBaseURL))
+	os.Setenv(envBaseURL, ts.URL)
+
+	store := newLegacyContentCacheStore(t)
+	repository := DefaultRFCContentRepository{
+		Fetcher:    &RFCContentFetcher{FileFormat: RFCContentFileFormatASCII},
+		CacheStore: store,
+	}
+
+	content, err := repository.FindByNumber(9110)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "fetched RFC 9110\n" {
+		t.Errorf("got content %q", content)
+	}
+
+	store.SizeProvider = nil
+	if content, err := store.Get(9110); err != nil || string(content) != "fetched RFC 9110\n" {
+		t.Errorf("got cached content %q, %v", content, err)
+	}
+}
+
+func TestRFCContentRepositoryKeepsUnverifiedEntryIfFetchFails(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	defer os.Setenv(envBaseURL, os.Getenv(envBaseURL))
+	os.Setenv(envBaseURL, ts.URL)
+
+	store := newLegacyContentCacheStore(t)
+	repository := DefaultRFCContentRepository{
+		Fetcher:    &RFCContentFetcher{FileFormat: RFCContentFileFormatASCII},
+		CacheStore: store,
+	}
+
+	if content, err := repository.FindByNumber(9110); err != nil || string(content) != "cached RFC 9110\n" {
+		t.Errorf("got content %q, %v", content, err)
+	}
+}
+
+func TestRFCContentCacheStoreRemovesMigratedLegacyEntry(t *testing.T) {
+	store := newLegacyContentCacheStore(t)
+	store.SizeProvider = nil
+
+	if err := store.Put(9110, []byte("fetched RFC 9110\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if content, err := store.Get(9110); err != nil || string(content) != "fetched RFC 9110\n" {
+		t.Errorf("got content %q, %v", content, err)
+	}
+
+	if _, err := os.Stat(filepath.Join(store.CacheDirectory, "9110")); !os.IsNotExist(err) {
+		t.Errorf("legacy entry was not removed: %v", err)
+	}
+}
+
+func TestRFCContentCacheStoreEntriesListsEachRFCOnce(t *testing.T) {
+	store := newLegacyContentCacheStore(t)
+
+	for _, name := range []string{"rfc9110.txt", "rfc9110.txt.gz", "3986.gz"} {
+		if err := ioutil.WriteFile(filepath.Join(store.CacheDirectory, name), []byte("RFC\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := store.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want 2", len(entries))
+	}
+	for _, entry := range entries {
+		switch entry.Number {
+		case 3986:
+			if entry.Name() != "3986.gz" {
+				t.Errorf("got entry %s for RFC 3986", entry.Name())
+			}
+		case 9110:
+			if entry.Name() != "rfc9110.txt" {
+				t.Errorf("got entry %s for RFC 9110", entry.Name())
+			}
+		default:
+			t.Errorf("got entry %s", entry.Name())
+		}
+	}
+}
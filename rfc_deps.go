@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	RFCDependencyNormative = "normative"
+	RFCDependencyUpdatedBy = "updated-by"
+)
+
+type RFCDependencyNode struct {
+	Number      int    `json:"number"`
+	Title       string `json:"title"`
+	Status      string `json:"status"`
+	Obsolete    bool   `json:"obsolete"`
+	ObsoletedBy []int  `json:"obsoleted_by,omitempty"`
+	Depth       int    `json:"depth"`
+	// Error is set if the text of the RFC could not be read, in which case
+	// its references are not followed.
+	Error string `json:"error,omitempty"`
+}
+
+type RFCDependencyEdge struct {
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+	Relation string `json:"relation"`
+}
+
+// RFCDependencies is the transitive closure of the normative references of an
+// RFC, and optionally of the RFCs updating them. Nodes are ordered by depth.
+type RFCDependencies struct {
+	Root   int                  `json:"root"`
+	Nodes  []*RFCDependencyNode `json:"nodes"`
+	Edges  []*RFCDependencyEdge `json:"edges"`
+	Cycles [][]int              `json:"cycles"`
+}
+
+type RFCDependencyResolver struct {
+	RFCContentRepository RFCContentRepository
+	RFCRepository        RFCRepository
+	FollowUpdates        bool
+	// MaxDepth limits how many references deep the closure goes; zero means
+	// no limit.
+	MaxDepth int
+}
+
+func (r *RFCDependencyResolver) Resolve(number int) (*RFCDependencies, error) {
+	deps := RFCDependencies{Root: number, Nodes: []*RFCDependencyNode{}, Edges: []*RFCDependencyEdge{}, Cycles: [][]int{}}
+
+	nodes := make(map[int]*RFCDependencyNode)
+	edges := make(map[RFCDependencyEdge]bool)
+
+	addNode := func(number int, depth int) (*RFCDependencyNode, error) {
+		node := RFCDependencyNode{Number: number, Depth: depth}
+
+		rfc, err := r.RFCRepository.FindByNumber(number)
+		if err != nil {
+			return nil, err
+		}
+
+		if rfc != nil {
+			node.Title = rfc.Title
+			node.Status = rfc.Status
+			node.Obsolete = rfc.IsObsolete()
+			node.ObsoletedBy = rfc.ObsoletedBy
+		}
+
+		nodes[number] = &node
+		deps.Nodes = append(deps.Nodes, &node)
+
+		return &node, nil
+	}
+
+	if _, err := addNode(number, 0); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < len(deps.Nodes); i++ {
+		node := deps.Nodes[i]
+
+		if r.MaxDepth > 0 && node.Depth >= r.MaxDepth {
+			continue
+		}
+
+		targets, err := r.dependenciesOf(node)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, edge := range targets {
+			if edge.To == node.Number || edges[edge] {
+				continue
+			}
+			edges[edge] = true
+			deps.Edges = append(deps.Edges, &RFCDependencyEdge{From: edge.From, To: edge.To, Relation: edge.Relation})
+
+			if nodes[edge.To] == nil {
+				if _, err := addNode(edge.To, node.Depth+1); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	deps.Cycles = deps.findCycles()
+
+	return &deps, nil
+}
+
+func (r *RFCDependencyResolver) dependenciesOf(node *RFCDependencyNode) ([]RFCDependencyEdge, error) {
+	var edges []RFCDependencyEdge
+
+	content, err := r.RFCContentRepository.FindByNumber(node.Number)
+	if err != nil && node.Depth == 0 {
+		return nil, err
+	} else if err != nil {
+		node.Error = err.Error()
+	} else {
+		for _, entry := range ParseRFCReferenceEntries(ParseRFCDocument(content)) {
+			if entry.Normative && entry.RFCNumber != 0 {
+				edges = append(edges, RFCDependencyEdge{From: node.Number, To: entry.RFCNumber, Relation: RFCDependencyNormative})
+			}
+		}
+	}
+
+	if r.FollowUpdates {
+		rfc, err := r.RFCRepository.FindByNumber(node.Number)
+		if err != nil {
+			return nil, err
+		}
+
+		if rfc != nil {
+			for _, other := range rfc.UpdatedBy {
+				edges = append(edges, RFCDependencyEdge{From: node.Number, To: other, Relation: RFCDependencyUpdatedBy})
+			}
+		}
+	}
+
+	return edges, nil
+}
+
+func (d *RFCDependencies) Node(number int) *RFCDependencyNode {
+	for _, node := range d.Nodes {
+		if node.Number == number {
+			return node
+		}
+	}
+	return nil
+}
+
+// Dependencies returns the edges leaving the given RFC.
+func (d *RFCDependencies) Dependencies(number int) []*RFCDependencyEdge {
+	var edges []*RFCDependencyEdge
+	for _, edge := range d.Edges {
+		if edge.From == number {
+			edges = append(edges, edge)
+		}
+	}
+	return edges
+}
+
+// findCycles returns each cycle found by a depth-first search from the root
+// as the RFCs on it, starting and ending with the same RFC.
+func (d *RFCDependencies) findCycles() [][]int {
+	cycles := [][]int{}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[int]int)
+	var path []int
+
+	var visit func(number int)
+	visit = func(number int) {
+		state[number] = visiting
+		path = append(path, number)
+
+		for _, edge := range d.Dependencies(number) {
+			switch state[edge.To] {
+			case unvisited:
+				visit(edge.To)
+			case visiting:
+				for i := len(path) - 1; i >= 0; i-- {
+					if path[i] == edge.To {
+						cycle := append([]int{}, path[i:]...)
+						cycles = append(cycles, append(cycle, edge.To))
+						break
+					}
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[number] = visited
+	}
+
+	visit(d.Root)
+
+	return cycles
+}
+
+// ReadingOrder returns the RFCs so that each comes after the RFCs it depends
+// on, ending with the root. RFCs on a cycle are ordered as they are first
+// reached.
+func (d *RFCDependencies) ReadingOrder() []*RFCDependencyNode {
+	var order []*RFCDependencyNode
+	visited := make(map[int]bool)
+
+	var visit func(number int)
+	visit = func(number int) {
+		visited[number] = true
+		for _, edge := range d.Dependencies(number) {
+			if !visited[edge.To] {
+				visit(edge.To)
+			}
+		}
+		order = append(order, d.Node(number))
+	}
+
+	visit(d.Root)
+
+	return order
+}
+
+// WriteTree writes the dependencies as an indented tree. RFCs reached again
+// are not expanded a second time.
+func (d *RFCDependencies) WriteTree(w io.Writer) {
+	expanded := make(map[int]bool)
+	onPath := make(map[int]bool)
+
+	var write func(number int, relation string, indent int)
+	write = func(number int, relation string, indent int) {
+		node := d.Node(number)
+
+		var notes []string
+		if relation == RFCDependencyUpdatedBy {
+			notes = append(notes, "updates the above")
+		}
+		if node.Obsolete {
+			notes = append(notes, "obsoleted by "+formatRFCNumbers(node.ObsoletedBy))
+		}
+		if node.Error != "" {
+			notes = append(notes, "not available")
+		}
+
+		children := d.Dependencies(number)
+		if onPath[number] {
+			notes = append(notes, "cycle")
+			children = nil
+		} else if expanded[number] && len(children) > 0 {
+			notes = append(notes, "see above")
+			children = nil
+		}
+
+		line := strings.Repeat("  ", indent) + fmt.Sprintf("RFC %d", number)
+		if node.Title != "" {
+			line += " " + node.Title
+		}
+		if len(notes) > 0 {
+			line += " (" + strings.Join(notes, ", ") + ")"
+		}
+		fmt.Fprintln(w, line)
+
+		expanded[number] = true
+		onPath[number] = true
+		for _, edge := range children {
+			write(edge.To, edge.Relation, indent+1)
+		}
+		onPath[number] = false
+	}
+
+	write(d.Root, "", 0)
+}
+
+func formatRFCNumbers(numbers []int) string {
+	var names []string
+	for _, number := range numbers {
+		names = append(names, fmt.Sprintf("RFC %d", number))
+	}
+	return strings.Join(names, ", ")
+}
+
+type RFCDependencyFormat int
+
+const (
+	RFCDependencyFormatTree RFCDependencyFormat = iota
+	RFCDependencyFormatOrder
+	RFCDependencyFormatJSON
+)
+
+func toRFCDependencyFormat(format string) (RFCDependencyFormat, error) {
+	switch format {
+	case "tree":
+		return RFCDependencyFormatTree, nil
+	case "order":
+		return RFCDependencyFormatOrder, nil
+	case "json":
+		return RFCDependencyFormatJSON, nil
+	}
+	return RFCDependencyFormat(0), fmt.Errorf("unknown output format: %s", format)
+}
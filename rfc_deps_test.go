@@ -0,0 +1,118 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestRFCDependencyResolver returns a resolver over RFC 9999, citing RFC
+// 9110 and RFC 3986, which is not cached, and RFC 9110, citing RFC 2616, RFC
+// 7230 and RFC 9999 back.
+func newTestRFCDependencyResolver(t *testing.T) *RFCDependencyResolver {
+	rfcIndex, err := ParseRFCIndex([]byte(testRFCIndexRelations))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	noReferences := "1.  Introduction\n\n   Text.\n"
+
+	return &RFCDependencyResolver{
+		RFCRepository: &RFCIndexRFCRepository{RFCIndex: rfcIndex},
+		RFCContentRepository: testRFCContents{
+			9999: testRFCReferences,
+			9110: `1.  Normative References
+
+   [RFC2616]  Fielding, R., "HTTP/1.1", RFC 2616, June 1999.
+
+   [RFC7230]  Fielding, R., "HTTP/1.1", RFC 7230, June 2014.
+
+   [SELF]     "Citing back", RFC 9999.
+
+2.  Informative References
+
+   [RFC4949]  Shirey, R., "Glossary", RFC 4949, August 2007.
+`,
+			2616: noReferences,
+			7230: noReferences,
+			8615: noReferences,
+		},
+	}
+}
+
+func TestRFCDependencyResolverResolve(t *testing.T) {
+	resolver := newTestRFCDependencyResolver(t)
+
+	deps, err := resolver.Resolve(9999)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	depths := make(map[int]int)
+	for _, node := range deps.Nodes {
+		depths[node.Number] = node.Depth
+	}
+	if want := map[int]int{9999: 0, 9110: 1, 3986: 1, 2616: 2, 7230: 2}; !reflect.DeepEqual(depths, want) {
+		t.Errorf("got depths %v, want %v", depths, want)
+	}
+
+	if node := deps.Node(3986); node.Error == "" {
+		t.Error("got no error for an RFC that is not cached")
+	}
+	if node := deps.Node(2616); !node.Obsolete {
+		t.Error("RFC 2616 is not obsolete")
+	}
+
+	if want := [][]int{{9999, 9110, 9999}}; !reflect.DeepEqual(deps.Cycles, want) {
+		t.Errorf("got cycles %v, want %v", deps.Cycles, want)
+	}
+
+	var order []int
+	for _, node := range deps.ReadingOrder() {
+		order = append(order, node.Number)
+	}
+	if want := []int{2616, 7230, 9110, 3986, 9999}; !reflect.DeepEqual(order, want) {
+		t.Errorf("got reading order %v, want %v", order, want)
+	}
+
+	var b strings.Builder
+	deps.WriteTree(&b)
+	want := `RFC 9999
+  RFC 9110
+    RFC 2616 (obsoleted by RFC 7231, RFC 7230)
+    RFC 7230 (obsoleted by RFC 9110)
+    RFC 9999 (cycle)
+  RFC 3986 (not available)
+`
+	if got := b.String(); got != want {
+		t.Errorf("got tree\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestRFCDependencyResolverOptions(t *testing.T) {
+	resolver := newTestRFCDependencyResolver(t)
+	resolver.FollowUpdates = true
+
+	deps, err := resolver.Resolve(9110)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if edges := deps.Dependencies(7230); len(edges) != 1 || edges[0].To != 8615 || edges[0].Relation != RFCDependencyUpdatedBy {
+		t.Errorf("got dependencies of RFC 7230 %+v", edges)
+	}
+
+	resolver = newTestRFCDependencyResolver(t)
+	resolver.MaxDepth = 1
+
+	deps, err = resolver.Resolve(9999)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deps.Nodes) != 3 || len(deps.Cycles) != 0 {
+		t.Errorf("got %d nodes and cycles %v with a maximum depth of 1", len(deps.Nodes), deps.Cycles)
+	}
+
+	if _, err := resolver.Resolve(3986); err != ErrRFCContentNotCached {
+		t.Errorf("got %v for a root that is not cached", err)
+	}
+}
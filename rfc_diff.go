@@ -0,0 +1,484 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strings"
+)
+
+// RFCSectionDiff is the difference between a section of the old document and
+// the matching section of the new document. Old is nil for added sections
+// and New for removed ones. The edits are over sentences.
+type RFCSectionDiff struct {
+	Old   *RFCSection
+	New   *RFCSection
+	Edits []DiffEdit
+}
+
+func (d *RFCSectionDiff) Changed() bool {
+	if d.Old == nil || d.New == nil || d.Old.Number != d.New.Number || d.Old.Title != d.New.Title {
+		return true
+	}
+	for _, edit := range d.Edits {
+		if edit.Kind != DiffEqual {
+			return true
+		}
+	}
+	return false
+}
+
+// RequirementChanges returns the number of deleted or inserted sentences that
+// contain BCP 14 keywords.
+func (d *RFCSectionDiff) RequirementChanges() int {
+	n := 0
+	for _, edit := range d.Edits {
+		if edit.Kind != DiffEqual && isRequirementSentence(edit.Old+edit.New) {
+			n++
+		}
+	}
+	return n
+}
+
+func isRequirementSentence(sentence string) bool {
+	return bcp14KeywordPattern.MatchString(sentence)
+}
+
+type RFCDiff struct {
+	Sections []*RFCSectionDiff
+}
+
+// DiffRFCDocuments aligns the sections of the documents by title and diffs
+// the sentences of each pair of sections, so that reflowed or renumbered text
+// is not reported as changed. Text before the first section is ignored.
+func DiffRFCDocuments(old, new *RFCDocument) *RFCDiff {
+	var diff RFCDiff
+
+	titles := func(doc *RFCDocument) []string {
+		var titles []string
+		for _, section := range doc.Sections {
+			titles = append(titles, strings.ToLower(strings.Join(strings.Fields(section.Title), " ")))
+		}
+		return titles
+	}
+
+	sentences := func(doc *RFCDocument, section *RFCSection) []string {
+		if section == nil {
+			return nil
+		}
+		return splitSentences(doc.SectionLines(section))
+	}
+
+	// Sections removed and added between two aligned sections are paired
+	// up when their text is mostly the same, as happens when a section is
+	// renamed.
+	var removed, added []*RFCSection
+	flush := func() {
+		paired := make(map[*RFCSection]*RFCSection)
+		for _, o := range removed {
+			best, bestScore := (*RFCSection)(nil), 0.5
+			for _, n := range added {
+				if _, used := paired[n]; used {
+					continue
+				}
+				if score := diffSimilarity(sentences(old, o), sentences(new, n)); score >= bestScore {
+					best, bestScore = n, score
+				}
+			}
+			if best != nil {
+				paired[best] = o
+			}
+		}
+
+		isPaired := make(map[*RFCSection]bool)
+		for _, o := range paired {
+			isPaired[o] = true
+		}
+
+		for _, o := range removed {
+			if !isPaired[o] {
+				diff.Sections = append(diff.Sections, &RFCSectionDiff{Old: o, Edits: DiffStrings(sentences(old, o), nil)})
+			}
+		}
+		for _, n := range added {
+			o := paired[n]
+			diff.Sections = append(diff.Sections, &RFCSectionDiff{Old: o, New: n, Edits: DiffStrings(sentences(old, o), sentences(new, n))})
+		}
+
+		removed, added = nil, nil
+	}
+
+	i, j := 0, 0
+	for _, edit := range DiffStrings(titles(old), titles(new)) {
+		switch edit.Kind {
+		case DiffEqual:
+			flush()
+			o, n := old.Sections[i], new.Sections[j]
+			diff.Sections = append(diff.Sections, &RFCSectionDiff{Old: o, New: n, Edits: DiffStrings(sentences(old, o), sentences(new, n))})
+			i++
+			j++
+		case DiffDelete:
+			removed = append(removed, old.Sections[i])
+			i++
+		case DiffInsert:
+			added = append(added, new.Sections[j])
+			j++
+		}
+	}
+	flush()
+
+	return &diff
+}
+
+// diffSimilarity returns the share of the sentences of a and b that are
+// common to both.
+func diffSimilarity(a, b []string) float64 {
+	if len(a)+len(b) == 0 {
+		return 1
+	}
+
+	equal := 0
+	for _, edit := range DiffStrings(a, b) {
+		if edit.Kind == DiffEqual {
+			equal++
+		}
+	}
+
+	return float64(2*equal) / float64(len(a)+len(b))
+}
+
+type RFCDiffFormat int
+
+const (
+	RFCDiffFormatUnified RFCDiffFormat = iota
+	RFCDiffFormatSideBySide
+	RFCDiffFormatHTML
+)
+
+func toRFCDiffFormat(format string) (RFCDiffFormat, error) {
+	switch format {
+	case "unified":
+		return RFCDiffFormatUnified, nil
+	case "side-by-side":
+		return RFCDiffFormatSideBySide, nil
+	case "html":
+		return RFCDiffFormatHTML, nil
+	}
+	return RFCDiffFormat(0), fmt.Errorf("unknown diff format: %s", format)
+}
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiCyan   = "\x1b[36m"
+	ansiYellow = "\x1b[33m"
+)
+
+type RFCDiffRenderer struct {
+	Format  RFCDiffFormat
+	OldName string
+	NewName string
+	Color   bool
+	Width   int
+	Context int
+}
+
+func (r *RFCDiffRenderer) Render(w io.Writer, diff *RFCDiff) error {
+	switch r.Format {
+	case RFCDiffFormatHTML:
+		return r.renderHTML(w, diff)
+	case RFCDiffFormatSideBySide:
+		return r.renderText(w, diff, r.renderSideBySideHunk)
+	}
+	return r.renderText(w, diff, r.renderUnifiedHunk)
+}
+
+func (r *RFCDiffRenderer) width() int {
+	if r.Width > 0 {
+		return r.Width
+	}
+	if r.Format == RFCDiffFormatSideBySide {
+		return 160
+	}
+	return 80
+}
+
+func (r *RFCDiffRenderer) color(code string, s string) string {
+	if !r.Color || s == "" {
+		return s
+	}
+	return code + s + ansiReset
+}
+
+func (r *RFCDiffRenderer) renderText(w io.Writer, diff *RFCDiff, renderHunk func(io.Writer, []DiffEdit)) error {
+	changed, added, removed, requirements := 0, 0, 0, 0
+	for _, d := range diff.Sections {
+		switch {
+		case d.Old == nil:
+			added++
+		case d.New == nil:
+			removed++
+		case d.Changed():
+			changed++
+		}
+		requirements += d.RequirementChanges()
+	}
+
+	fmt.Fprintf(w, "%s\n", r.color(ansiBold, fmt.Sprintf("--- %s", r.OldName)))
+	fmt.Fprintf(w, "%s\n", r.color(ansiBold, fmt.Sprintf("+++ %s", r.NewName)))
+	fmt.Fprintf(w, "%d sections changed, %d added, %d removed, %d requirement sentences changed\n",
+		changed, added, removed, requirements)
+
+	for _, d := range diff.Sections {
+		if !d.Changed() {
+			continue
+		}
+
+		fmt.Fprintf(w, "\n%s\n", r.color(ansiCyan, "@@ "+sectionDiffHeading(d)+" @@"))
+
+		for i, hunk := range diffHunks(d.Edits, r.Context) {
+			if i > 0 {
+				fmt.Fprintln(w, r.color(ansiCyan, "   ..."))
+			}
+			renderHunk(w, hunk)
+		}
+	}
+
+	return nil
+}
+
+func sectionDiffHeading(d *RFCSectionDiff) string {
+	name := func(s *RFCSection) string {
+		return s.Number + ". " + s.Title
+	}
+
+	switch {
+	case d.Old == nil:
+		return "added " + name(d.New)
+	case d.New == nil:
+		return "removed " + name(d.Old)
+	case d.Old.Number != d.New.Number || d.Old.Title != d.New.Title:
+		return name(d.Old) + " -> " + name(d.New)
+	}
+	return name(d.New)
+}
+
+// diffHunks splits the edits into runs of changes with up to context equal
+// edits around them.
+func diffHunks(edits []DiffEdit, context int) [][]DiffEdit {
+	var hunks [][]DiffEdit
+
+	start, end := -1, -1
+	for i, edit := range edits {
+		if edit.Kind == DiffEqual {
+			continue
+		}
+
+		from := i - context
+		if from < 0 {
+			from = 0
+		}
+
+		if start >= 0 && from > end {
+			hunks = append(hunks, edits[start:end])
+			start = -1
+		}
+		if start < 0 {
+			start = from
+		}
+
+		end = i + context + 1
+		if end > len(edits) {
+			end = len(edits)
+		}
+	}
+
+	if start >= 0 {
+		hunks = append(hunks, edits[start:end])
+	}
+
+	return hunks
+}
+
+func (r *RFCDiffRenderer) renderUnifiedHunk(w io.Writer, hunk []DiffEdit) {
+	for _, edit := range hunk {
+		sign, text, code := " ", edit.Old, ""
+		switch edit.Kind {
+		case DiffDelete:
+			sign, code = "-", ansiRed
+		case DiffInsert:
+			sign, text, code = "+", edit.New, ansiGreen
+		}
+
+		marker := " "
+		if edit.Kind != DiffEqual && isRequirementSentence(text) {
+			marker = "!"
+		}
+
+		for _, line := range wrapText(text, r.width()-3) {
+			fmt.Fprintln(w, r.color(code, sign+marker+" "+r.highlightKeywords(line, code)))
+			marker = " "
+		}
+	}
+}
+
+func (r *RFCDiffRenderer) renderSideBySideHunk(w io.Writer, hunk []DiffEdit) {
+	column := (r.width() - 3) / 2
+
+	for i := 0; i < len(hunk); {
+		var left, right []string
+		var leftChanged, rightChanged bool
+
+		if hunk[i].Kind == DiffEqual {
+			left = wrapText(hunk[i].Old, column)
+			right = left
+			i++
+		} else {
+			for ; i < len(hunk) && hunk[i].Kind != DiffEqual; i++ {
+				if hunk[i].Kind == DiffDelete {
+					left = append(left, wrapText(hunk[i].Old, column)...)
+					leftChanged = true
+				} else {
+					right = append(right, wrapText(hunk[i].New, column)...)
+					rightChanged = true
+				}
+			}
+		}
+
+		separator := " "
+		switch {
+		case leftChanged && rightChanged:
+			separator = "|"
+		case leftChanged:
+			separator = "<"
+		case rightChanged:
+			separator = ">"
+		}
+
+		for n := 0; n < len(left) || n < len(right); n++ {
+			var l, rr string
+			if n < len(left) {
+				l = left[n]
+			}
+			if n < len(right) {
+				rr = right[n]
+			}
+
+			padding := ""
+			if n := column - len([]rune(l)); n > 0 {
+				padding = strings.Repeat(" ", n)
+			}
+
+			if leftChanged {
+				l = r.color(ansiRed, r.highlightKeywords(l, ansiRed))
+			}
+			if rightChanged {
+				rr = r.color(ansiGreen, r.highlightKeywords(rr, ansiGreen))
+			}
+
+			fmt.Fprintln(w, strings.TrimRight(l+padding+" "+separator+" "+rr, " "))
+		}
+	}
+}
+
+// highlightKeywords makes BCP 14 keywords bold, restoring the color of the
+// surrounding text after each of them.
+func (r *RFCDiffRenderer) highlightKeywords(text string, code string) string {
+	if !r.Color || code == "" {
+		return text
+	}
+	return bcp14KeywordPattern.ReplaceAllString(text, ansiBold+ansiYellow+"$1"+ansiReset+code)
+}
+
+func (r *RFCDiffRenderer) renderHTML(w io.Writer, diff *RFCDiff) error {
+	fmt.Fprintf(w, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>%s</title>\n<style>%s%s</style>\n</head>\n<body>\n",
+		html.EscapeString(r.OldName+" vs "+r.NewName), serverPageStyle, diffPageStyle)
+	fmt.Fprintf(w, "<h1>%s &rarr; %s</h1>\n", html.EscapeString(r.OldName), html.EscapeString(r.NewName))
+
+	for _, d := range diff.Sections {
+		if !d.Changed() {
+			continue
+		}
+
+		fmt.Fprintf(w, "<h2>%s</h2>\n<table class=\"diff\">\n", html.EscapeString(sectionDiffHeading(d)))
+
+		for i, hunk := range diffHunks(d.Edits, r.Context) {
+			if i > 0 {
+				fmt.Fprintf(w, "<tr><td colspan=\"2\" class=\"skip\">&hellip;</td></tr>\n")
+			}
+
+			for _, edit := range hunk {
+				var old, new string
+				class := "equal"
+
+				switch edit.Kind {
+				case DiffEqual:
+					old, new = r.htmlSentence(edit.Old), r.htmlSentence(edit.New)
+				case DiffDelete:
+					old, class = r.htmlSentence(edit.Old), "delete"
+				case DiffInsert:
+					new, class = r.htmlSentence(edit.New), "insert"
+				}
+
+				if edit.Kind != DiffEqual && isRequirementSentence(edit.Old+edit.New) {
+					class += " requirement"
+				}
+
+				fmt.Fprintf(w, "<tr class=\"%s\"><td>%s</td><td>%s</td></tr>\n", class, old, new)
+			}
+		}
+
+		fmt.Fprintf(w, "</table>\n")
+	}
+
+	_, err := fmt.Fprintf(w, "</body>\n</html>\n")
+	return err
+}
+
+func (r *RFCDiffRenderer) htmlSentence(sentence string) string {
+	return bcp14KeywordPattern.ReplaceAllString(html.EscapeString(sentence), "<strong>$1</strong>")
+}
+
+const diffPageStyle = `
+table.diff { width: 100%; table-layout: fixed; }
+table.diff td { width: 50%; border-bottom: 1px solid #eee; }
+tr.delete td:first-child { background: #fdd; }
+tr.insert td:last-child { background: #dfd; }
+tr.requirement td { border-left: 3px solid #d90; }
+td.skip { color: #888; text-align: center; }
+`
+
+// wrapText breaks text into lines of at most width characters at spaces,
+// breaking words longer than a line.
+func wrapText(text string, width int) []string {
+	var lines []string
+	var line string
+
+	for _, word := range strings.Fields(text) {
+		for runes := []rune(word); width > 0 && len(runes) > width; runes = []rune(word) {
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			lines = append(lines, string(runes[:width]))
+			word = string(runes[width:])
+		}
+
+		if line != "" && len([]rune(line))+1+len([]rune(word)) > width {
+			lines = append(lines, line)
+			line = ""
+		}
+		if line != "" {
+			line += " "
+		}
+		line += word
+	}
+
+	if line != "" || lines == nil {
+		lines = append(lines, line)
+	}
+
+	return lines
+}
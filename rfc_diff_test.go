@@ -0,0 +1,191 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffRFCDocuments(t *testing.T) {
+	old := ParseRFCDocument([]byte(`1.  Introduction
+
+   This document defines HTTP. It is a protocol.
+
+2.  Terminology
+
+   The key words are defined in BCP 14.
+
+3.  Caching
+
+   A cache MAY store responses.
+
+4.  Old Name
+
+   This section is renamed. Its text is the same. Only the title differs.
+`))
+	new := ParseRFCDocument([]byte(`1.  Introduction
+
+   This document defines HTTP.
+   It is a protocol.
+
+2.  Caching
+
+   A cache MUST store responses.
+
+3.  New Name
+
+   This section is renamed. Its text is the same. Only the title differs.
+
+4.  Security Considerations
+
+   None.
+`))
+
+	diff := DiffRFCDocuments(old, new)
+
+	type section struct {
+		old, new string
+		changed  bool
+	}
+	name := func(s *RFCSection) string {
+		if s == nil {
+			return ""
+		}
+		return s.Number + " " + s.Title
+	}
+
+	var got []section
+	for _, d := range diff.Sections {
+		got = append(got, section{name(d.Old), name(d.New), d.Changed()})
+	}
+
+	want := []section{
+		{"1 Introduction", "1 Introduction", false},
+		{"2 Terminology", "", true},
+		{"3 Caching", "2 Caching", true},
+		{"4 Old Name", "3 New Name", true},
+		{"", "4 Security Considerations", true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got sections %+v, want %+v", got, want)
+	}
+
+	if n := diff.Sections[2].RequirementChanges(); n != 2 {
+		t.Errorf("got %d requirement changes in Caching, want 2", n)
+	}
+	if n := diff.Sections[3].RequirementChanges(); n != 0 {
+		t.Errorf("got %d requirement changes in the renamed section, want 0", n)
+	}
+	for _, edit := range diff.Sections[3].Edits {
+		if edit.Kind != DiffEqual {
+			t.Errorf("got edit %+v in the renamed section", edit)
+		}
+	}
+}
+
+func TestDiffHunks(t *testing.T) {
+	// kinds spells the edits: "=" equal, "-" delete and "+" insert.
+	edits := func(kinds string) []DiffEdit {
+		var edits []DiffEdit
+		for i, c := range kinds {
+			edit := DiffEdit{Kind: DiffEqual}
+			switch c {
+			case '-':
+				edit.Kind = DiffDelete
+			case '+':
+				edit.Kind = DiffInsert
+			}
+			edit.Old = string(rune('a' + i))
+			edits = append(edits, edit)
+		}
+		return edits
+	}
+	kinds := func(hunks [][]DiffEdit) []string {
+		var got []string
+		for _, hunk := range hunks {
+			var s []byte
+			for _, edit := range hunk {
+				s = append(s, "=-+"[edit.Kind])
+			}
+			got = append(got, string(s))
+		}
+		return got
+	}
+
+	tests := []struct {
+		edits   string
+		context int
+		want    []string
+	}{
+		{"====", 2, nil},
+		{"-", 2, []string{"-"}},
+		{"===-===", 0, []string{"-"}},
+		{"===-===", 1, []string{"=-="}},
+		{"===-===", 5, []string{"===-==="}},
+		{"-+====-", 1, []string{"-+=", "=-"}},
+		{"-+===-", 1, []string{"-+=", "=-"}},
+		{"-+==-", 1, []string{"-+==-"}},
+		{"=-=====+=", 2, []string{"=-==", "==+="}},
+		{"=-====+=", 2, []string{"=-====+="}},
+	}
+
+	for _, test := range tests {
+		if got := kinds(diffHunks(edits(test.edits), test.context)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s with context %d: got hunks %q, want %q", test.edits, test.context, got, test.want)
+		}
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  []string
+	}{
+		{"", 10, []string{""}},
+		{"short", 10, []string{"short"}},
+		{"  spaced   out  ", 20, []string{"spaced out"}},
+		{"the quick brown fox", 9, []string{"the quick", "brown fox"}},
+		{"the quick brown fox", 10, []string{"the quick", "brown fox"}},
+		{"a abcdefghij b", 4, []string{"a", "abcd", "efgh", "ij b"}},
+		{"abcdefgh", 4, []string{"abcd", "efgh"}},
+		{"día über straße", 5, []string{"día", "über", "straß", "e"}},
+		{"no wrapping at all", 0, []string{"no", "wrapping", "at", "all"}},
+	}
+
+	for _, test := range tests {
+		got := wrapText(test.text, test.width)
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q at %d: got %q, want %q", test.text, test.width, got, test.want)
+		}
+		for _, line := range got {
+			if test.width > 0 && len([]rune(line)) > test.width {
+				t.Errorf("%q at %d: line %q is too long", test.text, test.width, line)
+			}
+		}
+	}
+}
+
+func TestRFCDiffRendererUnified(t *testing.T) {
+	old := ParseRFCDocument([]byte("1.  Caching\n\n   A cache MAY store responses. Caches are common.\n"))
+	new := ParseRFCDocument([]byte("1.  Caching\n\n   A cache MUST store responses. Caches are common.\n"))
+
+	var b strings.Builder
+	renderer := RFCDiffRenderer{Format: RFCDiffFormatUnified, OldName: "old", NewName: "new", Context: 1}
+	if err := renderer.Render(&b, DiffRFCDocuments(old, new)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `--- old
++++ new
+1 sections changed, 0 added, 0 removed, 2 requirement sentences changed
+
+@@ 1. Caching @@
+-! A cache MAY store responses.
++! A cache MUST store responses.
+   Caches are common.
+`
+	if got := b.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	rfcDocumentPageFooterPattern = regexp.MustCompile(`^\S.*\[Page [0-9ivxlc]+\]\s*$`)
+	rfcDocumentPageHeaderPattern = regexp.MustCompile(`^(?:RFC|Internet-Draft) {1,2}\S.*\s{2,}\S.*\s{2,}(?:\d{1,2} )?[A-Z][a-z]+ \d{4}\s*$`)
+	rfcDocumentHeadingPattern    = regexp.MustCompile(`^(\d+\.(?:\d+\.?)*|[A-Z]\.(?:\d+\.?)*|Appendix [A-Z]\.?)\s+(\S.*)$`)
+)
+
+type RFCSection struct {
+	Number string
+	Title  string
+	Line   int
+	Level  int
+}
+
+// Anchor returns the fragment identifier the RFC Editor uses for the section
+// in HTML renderings, e.g. "section-4.2" or "appendix-A.1".
+func (s *RFCSection) Anchor() string {
+	return rfcSectionAnchor(s.Number)
+}
+
+func rfcSectionAnchor(number string) string {
+	if number != "" && number[0] >= 'A' && number[0] <= 'Z' {
+		return "appendix-" + number
+	}
+	return "section-" + number
+}
+
+// RFCDocument is the plain text of an RFC with page headers, footers and
+// form feeds removed, paragraphs split across pages joined, and the section
+// headings found in it.
+type RFCDocument struct {
+	Lines    []string
+	Sections []*RFCSection
+}
+
+func ParseRFCDocument(content []byte) *RFCDocument {
+	var doc RFCDocument
+
+	text := strings.Replace(string(content), "\r\n", "\n", -1)
+
+	pageBreak := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.Contains(line, "\f") {
+			pageBreak = true
+		}
+
+		line = strings.TrimRight(strings.Replace(line, "\f", "", -1), " \t")
+
+		if rfcDocumentPageFooterPattern.MatchString(line) || rfcDocumentPageHeaderPattern.MatchString(line) {
+			pageBreak = true
+			continue
+		}
+
+		if line == "" {
+			if !pageBreak && len(doc.Lines) > 0 && doc.Lines[len(doc.Lines)-1] != "" {
+				doc.Lines = append(doc.Lines, line)
+			}
+			continue
+		}
+
+		// Join paragraphs split by a page break unless the text before
+		// the break ends a sentence.
+		if pageBreak {
+			for len(doc.Lines) > 0 && doc.Lines[len(doc.Lines)-1] == "" {
+				doc.Lines = doc.Lines[:len(doc.Lines)-1]
+			}
+			if n := len(doc.Lines); n > 0 && strings.ContainsAny(doc.Lines[n-1][len(doc.Lines[n-1])-1:], ".:;") {
+				doc.Lines = append(doc.Lines, "")
+			}
+			pageBreak = false
+		}
+
+		doc.Lines = append(doc.Lines, line)
+	}
+
+	for len(doc.Lines) > 0 && doc.Lines[len(doc.Lines)-1] == "" {
+		doc.Lines = doc.Lines[:len(doc.Lines)-1]
+	}
+
+	for i, line := range doc.Lines {
+		match := rfcDocumentHeadingPattern.FindStringSubmatch(line)
+		if match == nil || len(match[2]) > 72 {
+			continue
+		}
+
+		number := strings.TrimSuffix(strings.TrimPrefix(match[1], "Appendix "), ".")
+
+		doc.Sections = append(doc.Sections, &RFCSection{
+			Number: number,
+			Title:  strings.TrimSpace(match[2]),
+			Line:   i,
+			Level:  strings.Count(number, ".") + 1,
+		})
+	}
+
+	return &doc
+}
+
+func (d *RFCDocument) Section(number string) *RFCSection {
+	for _, section := range d.Sections {
+		if section.Number == number {
+			return section
+		}
+	}
+	return nil
+}
+
+// SectionAt returns the innermost section containing the given line, or nil
+// for lines before the first section.
+func (d *RFCDocument) SectionAt(line int) *RFCSection {
+	var current *RFCSection
+	for _, section := range d.Sections {
+		if section.Line > line {
+			break
+		}
+		current = section
+	}
+	return current
+}
+
+// SectionLines returns the lines of the section, excluding its heading, up to
+// the next heading of any level.
+func (d *RFCDocument) SectionLines(section *RFCSection) []string {
+	end := len(d.Lines)
+	for _, other := range d.Sections {
+		if other.Line > section.Line {
+			end = other.Line
+			break
+		}
+	}
+	return d.Lines[section.Line+1 : end]
+}
+
+func (d *RFCDocument) String() string {
+	return strings.Join(d.Lines, "\n") + "\n"
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultDraftBaseURL = "https://www.ietf.org/archive/id/"
+
+var (
+	draftNamePattern          = regexp.MustCompile(`^(draft-[a-z0-9]+(?:-[a-z0-9]+)*?)(?:-(\d{2}))?$`)
+	draftCacheFileNamePattern = regexp.MustCompile(`^(draft-[a-z0-9-]+)-(\d{2})\.txt(\.gz)?$`)
+)
+
+// ParseDraftName splits a draft name such as "draft-ietf-httpbis-semantics-19"
+// into the name without revision and the revision, which is empty if the
+// name has none.
+func ParseDraftName(name string) (string, string, error) {
+	m := draftNamePattern.FindStringSubmatch(strings.TrimSuffix(strings.ToLower(name), ".txt"))
+	if m == nil {
+		return "", "", fmt.Errorf("invalid draft name: %s", name)
+	}
+	return m[1], m[2], nil
+}
+
+type DraftFetcher struct {
+	BaseURL string
+}
+
+// NewDraftFetcher returns a fetcher for the URL in RFCS_DRAFT_URL, or the
+// Internet-Draft archive of the IETF if it is not set.
+func NewDraftFetcher() *DraftFetcher {
+	fetcher := DraftFetcher{BaseURL: os.Getenv("RFCS_DRAFT_URL")}
+	if fetcher.BaseURL == "" {
+		fetcher.BaseURL = defaultDraftBaseURL
+	}
+	return &fetcher
+}
+
+func (f *DraftFetcher) URLFor(name string, revision string) string {
+	return strings.TrimSuffix(f.BaseURL, "/") + "/" + name + "-" + revision + ".txt"
+}
+
+func (f *DraftFetcher) Fetch(name string, revision string) ([]byte, error) {
+	draftURL := f.URLFor(name, revision)
+
+	response, err := http.Get(draftURL)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", draftURL, response.Status)
+	}
+
+	return ioutil.ReadAll(response.Body)
+}
+
+// LatestRevision probes the revisions of the draft starting at the given one
+// and returns the last revision that exists. It returns an empty revision if
+// the starting one does not exist.
+func (f *DraftFetcher) LatestRevision(name string, from string) (string, error) {
+	start, _ := strconv.Atoi(from)
+
+	latest := ""
+	for revision := start; revision < 100; revision++ {
+		draftURL := f.URLFor(name, fmt.Sprintf("%02d", revision))
+
+		response, err := http.Head(draftURL)
+		if err != nil {
+			return "", err
+		}
+		response.Body.Close()
+
+		if response.StatusCode == http.StatusNotFound {
+			break
+		} else if response.StatusCode != http.StatusOK {
+			return "", fmt.Errorf("failed to fetch %s: %s", draftURL, response.Status)
+		}
+
+		latest = fmt.Sprintf("%02d", revision)
+	}
+
+	return latest, nil
+}
+
+type DraftCacheEntry struct {
+	Name        string
+	Revision    string
+	Compression CacheCompression
+	Path        string
+	Size        int64
+	StoredAt    time.Time
+	LastUsedAt  time.Time
+}
+
+func (e *DraftCacheEntry) FileName() string {
+	return filepath.Base(e.Path) + e.Compression.Extension()
+}
+
+// DraftCacheStore stores Internet-Drafts next to the cached RFCs, one file per
+// revision. Revisions never change once published, so cached revisions are
+// never fetched again.
+type DraftCacheStore struct {
+	CacheDirectory string
+	Compression    CacheCompression
+}
+
+func NewDraftCacheStore() *DraftCacheStore {
+	store := DraftCacheStore{
+		Compression: CacheCompressionFromEnvironment(),
+	}
+
+	return &store
+}
+
+func (s *DraftCacheStore) Put(name string, revision string, content []byte) error {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return putCacheFile(s.cacheFile(cacheDir, name, revision), content, s.Compression)
+}
+
+func (s *DraftCacheStore) Get(name string, revision string) ([]byte, error) {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, false)
+	if err != nil {
+		return nil, err
+	}
+
+	cacheFile := s.cacheFile(cacheDir, name, revision)
+	content, hasChecksum, compression, err := getCacheFile(cacheFile)
+	lock.Unlock()
+
+	if err != nil || content == nil {
+		return content, err
+	}
+
+	touchCacheFile(cacheDir, cacheFile)
+
+	if compression != s.Compression || !hasChecksum {
+		s.Put(name, revision, content)
+	}
+
+	return content, nil
+}
+
+func (s *DraftCacheStore) Remove(name string, revision string) error {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return removeCacheFile(s.cacheFile(cacheDir, name, revision))
+}
+
+// Entries returns the cached drafts ordered by name and revision.
+func (s *DraftCacheStore) Entries() ([]*DraftCacheEntry, error) {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := ioutil.ReadDir(cacheDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var entries []*DraftCacheEntry
+
+	for _, file := range files {
+		match := draftCacheFileNamePattern.FindStringSubmatch(file.Name())
+		if match == nil || file.IsDir() {
+			continue
+		}
+
+		entry := DraftCacheEntry{
+			Name:       match[1],
+			Revision:   match[2],
+			Path:       filepath.Join(cacheDir, strings.TrimSuffix(file.Name(), match[3])),
+			Size:       file.Size(),
+			StoredAt:   file.ModTime(),
+			LastUsedAt: file.ModTime(),
+		}
+
+		if match[3] != "" {
+			entry.Compression = CacheCompressionGzip
+		}
+
+		if info, err := os.Stat(checksumFileFor(entry.Path)); err == nil {
+			entry.LastUsedAt = info.ModTime()
+		}
+
+		entries = append(entries, &entry)
+	}
+
+	sort.Sort(ByDraftRevision(entries))
+
+	return entries, nil
+}
+
+// LatestRevision returns the highest cached revision of the draft, or an
+// empty revision if none is cached.
+func (s *DraftCacheStore) LatestRevision(name string) (string, error) {
+	entries, err := s.Entries()
+	if err != nil {
+		return "", err
+	}
+
+	latest := ""
+	for _, entry := range entries {
+		if entry.Name == name {
+			latest = entry.Revision
+		}
+	}
+
+	return latest, nil
+}
+
+// Verify reads the cached draft, which fails if its checksum does not match.
+func (s *DraftCacheStore) Verify(entry *DraftCacheEntry) error {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, false)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	content, _, _, err := getCacheFile(entry.Path)
+	if err != nil {
+		return err
+	} else if content == nil {
+		return os.ErrNotExist
+	}
+
+	return nil
+}
+
+func (s *DraftCacheStore) RemoveEntry(entry *DraftCacheEntry) error {
+	return s.Remove(entry.Name, entry.Revision)
+}
+
+func (s *DraftCacheStore) Directory() (string, error) {
+	if s.CacheDirectory != "" {
+		return s.CacheDirectory, nil
+	}
+
+	if dir := DefaultCacheDirectory(); dir != "" {
+		return dir, nil
+	}
+
+	return "", fmt.Errorf("cannot determine the cache directory")
+}
+
+func (s *DraftCacheStore) cacheFile(cacheDir string, name string, revision string) string {
+	return filepath.Join(cacheDir, name+"-"+revision+".txt")
+}
+
+type ByDraftRevision []*DraftCacheEntry
+
+func (r ByDraftRevision) Len() int {
+	return len(r)
+}
+
+func (r ByDraftRevision) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+}
+
+func (r ByDraftRevision) Less(i, j int) bool {
+	if r[i].Name != r[j].Name {
+		return r[i].Name < r[j].Name
+	}
+	return r[i].Revision < r[j].Revision
+}
+
+type DraftContentRepository struct {
+	Fetcher    *DraftFetcher
+	CacheStore *DraftCacheStore
+}
+
+func NewDraftContentRepository() *DraftContentRepository {
+	return &DraftContentRepository{
+		Fetcher:    NewDraftFetcher(),
+		CacheStore: NewDraftCacheStore(),
+	}
+}
+
+// FindByName returns the given revision of the draft, or the latest one if
+// revision is empty, along with the revision returned. The latest revision
+// is looked up online, falling back to the latest cached revision if that
+// fails.
+func (r *DraftContentRepository) FindByName(name string, revision string) ([]byte, string, error) {
+	if revision == "" {
+		var err error
+		if revision, err = r.LatestRevision(name); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if content, err := r.CacheStore.Get(name, revision); err == nil && content != nil {
+		return content, revision, nil
+	} else if err == ErrCorruptCacheEntry {
+		r.CacheStore.Remove(name, revision)
+	}
+
+	content, err := r.Fetcher.Fetch(name, revision)
+	if err != nil {
+		return nil, "", err
+	}
+
+	r.CacheStore.Put(name, revision, content)
+
+	return content, revision, nil
+}
+
+func (r *DraftContentRepository) LatestRevision(name string) (string, error) {
+	cached, err := r.CacheStore.LatestRevision(name)
+	if err != nil {
+		return "", err
+	}
+
+	latest, err := r.Fetcher.LatestRevision(name, cached)
+	if err != nil && cached != "" {
+		return cached, nil
+	} else if err != nil {
+		return "", err
+	}
+
+	if latest == "" {
+		if cached != "" {
+			return cached, nil
+		}
+		return "", fmt.Errorf("draft not found: %s", name)
+	}
+
+	return latest, nil
+}
+
+// PreviousDraftRevision returns the revision before the given one, or an
+// empty revision for the first one.
+func PreviousDraftRevision(revision string) string {
+	number, err := strconv.Atoi(revision)
+	if err != nil || number == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%02d", number-1)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseDraftName(t *testing.T) {
+	tests := []struct {
+		name     string
+		draft    string
+		revision string
+	}{
+		{"draft-ietf-httpbis-semantics-19", "draft-ietf-httpbis-semantics", "19"},
+		{"draft-ietf-httpbis-semantics", "draft-ietf-httpbis-semantics", ""},
+		{"Draft-IETF-QUIC-Transport-34.txt", "draft-ietf-quic-transport", "34"},
+		{"draft-ietf-tls-tls13-28", "draft-ietf-tls-tls13", "28"},
+		{"draft-ietf-tls-tls13", "draft-ietf-tls-tls13", ""},
+		{"rfc9110", "", ""},
+		{"draft-", "", ""},
+		{"draft-ietf-foo-1", "draft-ietf-foo-1", ""},
+	}
+
+	for _, test := range tests {
+		draft, revision, err := ParseDraftName(test.name)
+		if test.draft == "" {
+			if err == nil {
+				t.Errorf("%q: got %q %q, want an error", test.name, draft, revision)
+			}
+			continue
+		}
+		if err != nil || draft != test.draft || revision != test.revision {
+			t.Errorf("%q: got %q %q, %v, want %q %q", test.name, draft, revision, err, test.draft, test.revision)
+		}
+	}
+}
+
+func TestPreviousDraftRevision(t *testing.T) {
+	for revision, want := range map[string]string{"00": "", "01": "00", "10": "09", "": ""} {
+		if got := PreviousDraftRevision(revision); got != want {
+			t.Errorf("%q: got %q, want %q", revision, got, want)
+		}
+	}
+}
+
+// draftServer serves revisions 00 to 02 of draft-ietf-example-protocol,
+// counting the requests for each draft.
+type draftServer struct {
+	mu       sync.Mutex
+	requests map[string]int
+}
+
+func (s *draftServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	if r.Method == http.MethodGet {
+		s.requests[r.URL.Path]++
+	}
+	s.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/draft-ietf-example-protocol-00.txt", "/draft-ietf-example-protocol-01.txt", "/draft-ietf-example-protocol-02.txt":
+		w.Write([]byte("Revision " + strings.TrimSuffix(r.URL.Path[len(r.URL.Path)-6:], ".txt") + "\n"))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *draftServer) requestCount(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+func TestDraftContentRepository(t *testing.T) {
+	server := draftServer{requests: make(map[string]int)}
+	ts := httptest.NewServer(&server)
+	defer ts.Close()
+
+	store := &DraftCacheStore{CacheDirectory: newTestCacheDirectory(t)}
+	repository := DraftContentRepository{Fetcher: &DraftFetcher{BaseURL: ts.URL + "/"}, CacheStore: store}
+
+	findByName := func(revision string) (string, string) {
+		t.Helper()
+		content, revision, err := repository.FindByName("draft-ietf-example-protocol", revision)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content), revision
+	}
+
+	if content, revision := findByName(""); content != "Revision 02\n" || revision != "02" {
+		t.Errorf("got latest revision %q: %q", revision, content)
+	}
+	if content, revision := findByName("01"); content != "Revision 01\n" || revision != "01" {
+		t.Errorf("got revision %q: %q", revision, content)
+	}
+
+	// Cached revisions are not fetched again.
+	findByName("01")
+	if got := server.requestCount("/draft-ietf-example-protocol-01.txt"); got != 1 {
+		t.Errorf("got %d requests for a cached revision, want 1", got)
+	}
+
+	entries, err := store.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name+"-"+entry.Revision)
+	}
+	if got := strings.Join(names, " "); got != "draft-ietf-example-protocol-01 draft-ietf-example-protocol-02" {
+		t.Errorf("got cached drafts %s", got)
+	}
+
+	if _, _, err := repository.FindByName("draft-ietf-example-missing", ""); err == nil {
+		t.Error("got no error for a missing draft")
+	}
+
+	// The latest cached revision is used if the archive cannot be reached.
+	ts.Close()
+	if content, revision := findByName(""); content != "Revision 02\n" || revision != "02" {
+		t.Errorf("got latest revision %q: %q offline", revision, content)
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const defaultRFCErrataURL = "https://www.rfc-editor.org/errata.json"
+
+const (
+	RFCErratumStatusVerified = "Verified"
+	RFCErratumStatusReported = "Reported"
+	RFCErratumStatusHeld     = "Held for Document Update"
+	RFCErratumStatusRejected = "Rejected"
+)
+
+// RFCErratum is an entry of the errata feed of the RFC Editor.
+type RFCErratum struct {
+	ID            string `json:"errata_id"`
+	DocumentID    string `json:"doc-id"`
+	Status        string `json:"errata_status_code"`
+	Type          string `json:"errata_type_code"`
+	Section       string `json:"section"`
+	OriginalText  string `json:"orig_text"`
+	CorrectedText string `json:"correct_text"`
+	Notes         string `json:"notes"`
+	SubmitDate    string `json:"submit_date"`
+	SubmitterName string `json:"submitter_name"`
+}
+
+func (e *RFCErratum) RFCNumber() int {
+	number, _ := strconv.Atoi(strings.TrimPrefix(strings.ToUpper(e.DocumentID), "RFC"))
+	return number
+}
+
+var rfcErratumSectionPattern = regexp.MustCompile(`\d+(?:\.\d+)*|\b[A-Z](?:\.\d+)*\b`)
+
+// SectionNumber returns the number of the first section the erratum names,
+// which is free text such as "4.2", "Section 4.2.1" or "GLOBAL".
+func (e *RFCErratum) SectionNumber() string {
+	section := strings.TrimSpace(e.Section)
+	section = strings.TrimPrefix(strings.TrimPrefix(section, "Section"), "Appendix")
+	return rfcErratumSectionPattern.FindString(section)
+}
+
+func ParseRFCErrata(content []byte) ([]*RFCErratum, error) {
+	var errata []*RFCErratum
+	if err := json.Unmarshal(content, &errata); err != nil {
+		return nil, err
+	}
+	return errata, nil
+}
+
+type RFCErrataFetcher struct {
+	URL string
+}
+
+// NewRFCErrataFetcher returns a fetcher for the URL in RFCS_ERRATA_URL, or
+// the errata feed of the RFC Editor if it is not set.
+func NewRFCErrataFetcher() *RFCErrataFetcher {
+	fetcher := RFCErrataFetcher{URL: os.Getenv("RFCS_ERRATA_URL")}
+	if fetcher.URL == "" {
+		fetcher.URL = defaultRFCErrataURL
+	}
+	return &fetcher
+}
+
+func (f *RFCErrataFetcher) Fetch() ([]byte, error) {
+	response, err := http.Get(f.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", f.URL, response.Status)
+	}
+
+	return ioutil.ReadAll(response.Body)
+}
+
+type RFCErrataCacheStore struct {
+	CacheDirectory string
+	Compression    CacheCompression
+}
+
+func NewRFCErrataCacheStore() *RFCErrataCacheStore {
+	store := RFCErrataCacheStore{
+		Compression: CacheCompressionFromEnvironment(),
+	}
+
+	return &store
+}
+
+func (s *RFCErrataCacheStore) Put(content []byte) error {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return putCacheFile(s.cacheFile(cacheDir), content, s.Compression)
+}
+
+func (s *RFCErrataCacheStore) Get() ([]byte, error) {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, false)
+	if err != nil {
+		return nil, err
+	}
+
+	content, hasChecksum, compression, err := getCacheFile(s.cacheFile(cacheDir))
+	lock.Unlock()
+
+	if err != nil || content == nil {
+		return content, err
+	}
+
+	if compression != s.Compression || !hasChecksum {
+		s.Put(content)
+	}
+
+	return content, nil
+}
+
+func (s *RFCErrataCacheStore) Remove() error {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return removeCacheFile(s.cacheFile(cacheDir))
+}
+
+func (s *RFCErrataCacheStore) cacheFile(cacheDir string) string {
+	return filepath.Join(cacheDir, "errata.json")
+}
+
+func (s *RFCErrataCacheStore) Directory() (string, error) {
+	if s.CacheDirectory != "" {
+		return s.CacheDirectory, nil
+	}
+
+	if dir := DefaultCacheDirectory(); dir != "" {
+		return dir, nil
+	}
+
+	return "", fmt.Errorf("cannot determine the cache directory")
+}
+
+// RFCErrataRepository serves errata from the cached feed, fetching it if it
+// is not cached or Refresh is set.
+type RFCErrataRepository struct {
+	Fetcher    *RFCErrataFetcher
+	CacheStore *RFCErrataCacheStore
+	Refresh    bool
+	errata     map[int][]*RFCErratum
+	mu         sync.Mutex
+}
+
+func NewRFCErrataRepository() *RFCErrataRepository {
+	return &RFCErrataRepository{
+		Fetcher:    NewRFCErrataFetcher(),
+		CacheStore: NewRFCErrataCacheStore(),
+	}
+}
+
+func (r *RFCErrataRepository) FindByNumber(number int) ([]*RFCErratum, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.errata == nil {
+		if err := r.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.errata[number], nil
+}
+
+func (r *RFCErrataRepository) load() error {
+	var content []byte
+	var err error
+
+	if !r.Refresh {
+		content, err = r.CacheStore.Get()
+		if err == ErrCorruptCacheEntry {
+			r.CacheStore.Remove()
+		} else if err != nil {
+			return err
+		}
+	}
+
+	fetched := false
+	if content == nil {
+		content, err = r.Fetcher.Fetch()
+		if err != nil {
+			return err
+		}
+		fetched = true
+	}
+
+	errata, err := ParseRFCErrata(content)
+	if err != nil {
+		return err
+	}
+
+	if fetched {
+		if err := r.CacheStore.Put(content); err != nil {
+			return err
+		}
+	}
+
+	r.errata = make(map[int][]*RFCErratum)
+	for _, erratum := range errata {
+		number := erratum.RFCNumber()
+		r.errata[number] = append(r.errata[number], erratum)
+	}
+
+	return nil
+}
+
+// AnnotateRFCErrata returns a copy of the document with each erratum inserted
+// below the heading of the section it applies to. Errata that do not name a
+// section of the document are listed at the end.
+func AnnotateRFCErrata(doc *RFCDocument, errata []*RFCErratum) *RFCDocument {
+	bySection := make(map[string][]*RFCErratum)
+	var unplaced []*RFCErratum
+
+	for _, erratum := range errata {
+		if number := erratum.SectionNumber(); number != "" && doc.Section(number) != nil {
+			bySection[number] = append(bySection[number], erratum)
+		} else {
+			unplaced = append(unplaced, erratum)
+		}
+	}
+
+	headings := make(map[int]string)
+	for _, section := range doc.Sections {
+		headings[section.Line] = section.Number
+	}
+
+	var lines []string
+	for i, line := range doc.Lines {
+		lines = append(lines, line)
+		for _, erratum := range bySection[headings[i]] {
+			lines = append(lines, "")
+			lines = append(lines, formatRFCErratumAnnotation(erratum)...)
+		}
+	}
+
+	if len(unplaced) > 0 {
+		lines = append(lines, "", "Errata")
+		for _, erratum := range unplaced {
+			lines = append(lines, "")
+			lines = append(lines, formatRFCErratumAnnotation(erratum)...)
+		}
+	}
+
+	return ParseRFCDocument([]byte(strings.Join(lines, "\n")))
+}
+
+func formatRFCErratumAnnotation(erratum *RFCErratum) []string {
+	lines := []string{fmt.Sprintf("   | Erratum %s (%s, %s)", erratum.ID, erratum.Status, erratum.Type)}
+	if erratum.SectionNumber() == "" && erratum.Section != "" {
+		lines = append(lines, "   | Section: "+erratum.Section)
+	}
+
+	for _, line := range formatRFCErratumText(erratum) {
+		lines = append(lines, strings.TrimRight("   | "+line, " "))
+	}
+
+	return lines
+}
+
+// formatRFCErratumText returns the original and corrected text of the
+// erratum, each under its own label.
+func formatRFCErratumText(erratum *RFCErratum) []string {
+	var lines []string
+
+	block := func(name string, text string) {
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		lines = append(lines, name+":")
+		for _, line := range strings.Split(strings.TrimRight(strings.Replace(text, "\r\n", "\n", -1), "\n"), "\n") {
+			lines = append(lines, "  "+line)
+		}
+	}
+
+	block("Original", erratum.OriginalText)
+	block("Corrected", erratum.CorrectedText)
+
+	return lines
+}
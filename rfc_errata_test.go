@@ -0,0 +1,142 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+)
+
+// errataServer serves an errata feed that can be replaced, counting the
+// requests for it.
+type errataServer struct {
+	mu       sync.Mutex
+	feed     string
+	requests int
+}
+
+func (s *errataServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests++
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(s.feed))
+}
+
+func (s *errataServer) setFeed(feed string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.feed = feed
+}
+
+func (s *errataServer) requestCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+const (
+	testErrataFeed = `[
+		{"errata_id": "7105", "doc-id": "RFC9110", "errata_status_code": "Verified", "errata_type_code": "Editorial", "section": "8.6"},
+		{"errata_id": "7138", "doc-id": "RFC9110", "errata_status_code": "Reported", "errata_type_code": "Technical", "section": "Section 15.4.9"},
+		{"errata_id": "5588", "doc-id": "RFC3986", "errata_status_code": "Held for Document Update", "errata_type_code": "Technical", "section": "3.2.2"}
+	]`
+	testErrataFeedUpdated = `[
+		{"errata_id": "7105", "doc-id": "RFC9110", "errata_status_code": "Verified", "errata_type_code": "Editorial", "section": "8.6"},
+		{"errata_id": "7138", "doc-id": "RFC9110", "errata_status_code": "Verified", "errata_type_code": "Technical", "section": "Section 15.4.9"},
+		{"errata_id": "7840", "doc-id": "RFC9110", "errata_status_code": "Reported", "errata_type_code": "Editorial", "section": "GLOBAL"},
+		{"errata_id": "5588", "doc-id": "RFC3986", "errata_status_code": "Held for Document Update", "errata_type_code": "Technical", "section": "3.2.2"}
+	]`
+)
+
+func TestRFCErrataRepository(t *testing.T) {
+	server := errataServer{feed: testErrataFeed}
+	ts := httptest.NewServer(&server)
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "rfcs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	newRepository := func(refresh bool) *RFCErrataRepository {
+		return &RFCErrataRepository{
+			Fetcher:    &RFCErrataFetcher{URL: ts.URL + "/errata.json"},
+			CacheStore: &RFCErrataCacheStore{CacheDirectory: dir},
+			Refresh:    refresh,
+		}
+	}
+
+	findByNumber := func(repository *RFCErrataRepository, number int) []*RFCErratum {
+		t.Helper()
+		errata, err := repository.FindByNumber(number)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return errata
+	}
+
+	// The feed is fetched once and cached.
+	repository := newRepository(false)
+	if errata := findByNumber(repository, 9110); len(errata) != 2 || errata[0].ID != "7105" || errata[1].Status != RFCErratumStatusReported {
+		t.Errorf("got errata %+v", errata)
+	}
+	if errata := findByNumber(repository, 3986); len(errata) != 1 || errata[0].SectionNumber() != "3.2.2" {
+		t.Errorf("got errata %+v", errata)
+	}
+	if got := server.requestCount(); got != 1 {
+		t.Errorf("got %d requests after the first lookup, want 1", got)
+	}
+
+	// The cached feed is reused even if the feed changed.
+	server.setFeed(testErrataFeedUpdated)
+	if errata := findByNumber(newRepository(false), 9110); len(errata) != 2 {
+		t.Errorf("got %d errata from the cache, want 2", len(errata))
+	}
+	if got := server.requestCount(); got != 1 {
+		t.Errorf("got %d requests with the feed cached, want 1", got)
+	}
+
+	// Refreshing fetches the feed again and replaces the cached one.
+	if errata := findByNumber(newRepository(true), 9110); len(errata) != 3 || errata[1].Status != RFCErratumStatusVerified {
+		t.Errorf("got errata %+v after refreshing", errata)
+	}
+	if got := server.requestCount(); got != 2 {
+		t.Errorf("got %d requests after refreshing, want 2", got)
+	}
+
+	if errata := findByNumber(newRepository(false), 9110); len(errata) != 3 {
+		t.Errorf("got %d errata from the refreshed cache, want 3", len(errata))
+	}
+	if got := server.requestCount(); got != 2 {
+		t.Errorf("got %d requests after refreshing, want 2", got)
+	}
+}
+
+func TestRFCErrataRepositoryFetchError(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "rfcs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	repository := RFCErrataRepository{
+		Fetcher:    &RFCErrataFetcher{URL: ts.URL + "/errata.json"},
+		CacheStore: &RFCErrataCacheStore{CacheDirectory: dir},
+	}
+
+	if _, err := repository.FindByNumber(9110); err == nil {
+		t.Error("got no error for a missing feed")
+	}
+
+	if content, err := repository.CacheStore.Get(); err != nil || content != nil {
+		t.Errorf("got cached feed %q, %v after a failed fetch", content, err)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+type RFCGraphNode struct {
+	Number   int    `json:"number"`
+	Title    string `json:"title"`
+	Status   string `json:"status"`
+	Obsolete bool   `json:"obsolete"`
+	Depth    int    `json:"depth"`
+}
+
+type RFCGraphEdge struct {
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+	Relation string `json:"relation"`
+}
+
+type RFCGraph struct {
+	Root  int             `json:"root"`
+	Nodes []*RFCGraphNode `json:"nodes"`
+	Edges []*RFCGraphEdge `json:"edges"`
+}
+
+// BuildRFCGraph walks the obsoletes and updates relationships in both
+// directions from the given RFC up to the given depth. It returns nil if the
+// RFC does not exist.
+func BuildRFCGraph(repository RFCRepository, number int, depth int) (*RFCGraph, error) {
+	root, err := repository.FindByNumber(number)
+	if err != nil || root == nil {
+		return nil, err
+	}
+
+	graph := RFCGraph{Root: number}
+	depths := map[int]int{number: 0}
+	edges := make(map[RFCGraphEdge]bool)
+	queue := []*RFC{root}
+
+	addEdge := func(from, to int, relation string) {
+		edge := RFCGraphEdge{From: from, To: to, Relation: relation}
+		if !edges[edge] {
+			edges[edge] = true
+			graph.Edges = append(graph.Edges, &edge)
+		}
+	}
+
+	for len(queue) > 0 {
+		rfc := queue[0]
+		queue = queue[1:]
+
+		d := depths[rfc.Number]
+		graph.Nodes = append(graph.Nodes, &RFCGraphNode{
+			Number:   rfc.Number,
+			Title:    rfc.Title,
+			Status:   rfc.Status,
+			Obsolete: rfc.IsObsolete(),
+			Depth:    d,
+		})
+
+		if d >= depth {
+			continue
+		}
+
+		var neighbors []int
+		for _, other := range rfc.Obsoletes {
+			addEdge(rfc.Number, other, "obsoletes")
+			neighbors = append(neighbors, other)
+		}
+		for _, other := range rfc.ObsoletedBy {
+			addEdge(other, rfc.Number, "obsoletes")
+			neighbors = append(neighbors, other)
+		}
+		for _, other := range rfc.Updates {
+			addEdge(rfc.Number, other, "updates")
+			neighbors = append(neighbors, other)
+		}
+		for _, other := range rfc.UpdatedBy {
+			addEdge(other, rfc.Number, "updates")
+			neighbors = append(neighbors, other)
+		}
+
+		for _, other := range neighbors {
+			if _, seen := depths[other]; seen {
+				continue
+			}
+
+			neighbor, err := repository.FindByNumber(other)
+			if err != nil {
+				return nil, err
+			} else if neighbor == nil {
+				continue
+			}
+
+			depths[other] = d + 1
+			queue = append(queue, neighbor)
+		}
+	}
+
+	// Drop edges to RFCs missing from the index.
+	var kept []*RFCGraphEdge
+	for _, edge := range graph.Edges {
+		if _, ok := depths[edge.From]; !ok {
+			continue
+		}
+		if _, ok := depths[edge.To]; !ok {
+			continue
+		}
+		kept = append(kept, edge)
+	}
+	graph.Edges = kept
+
+	return &graph, nil
+}
@@ -1,13 +1,18 @@
 package main
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -29,6 +34,36 @@ type RFCIndexDocumentRef struct {
 	DocIDs []RFCIndexDocumentID `xml:"doc-id"`
 }
 
+func (r *RFCIndexDocumentRef) RFCNumbers() []int {
+	if r == nil {
+		return nil
+	}
+
+	var numbers []int
+	for _, docID := range r.DocIDs {
+		if strings.HasPrefix(string(docID), "RFC") {
+			if number, err := docID.Number(); err == nil {
+				numbers = append(numbers, number)
+			}
+		}
+	}
+	return numbers
+}
+
+func (r *RFCIndexDocumentRef) NonRFCDocIDs() []string {
+	if r == nil {
+		return nil
+	}
+
+	var docIDs []string
+	for _, docID := range r.DocIDs {
+		if !strings.HasPrefix(string(docID), "RFC") {
+			docIDs = append(docIDs, string(docID))
+		}
+	}
+	return docIDs
+}
+
 type RFCIndexStream string
 
 type RFCIndexAuthor struct {
@@ -79,78 +114,24 @@ type RFCIndexSTDEntry struct {
 	IsAlso *RFCIndexDocumentRef `xml:"is-also,omitempty"`
 }
 
-func (e *RFCIndexSTDEntry) Include(rfcEntry *RFCIndexRFCEntry) bool {
-	for _, docID := range e.IsAlso.DocIDs {
-		if docID == rfcEntry.DocID {
-			return true
-		}
-	}
-	return false
-}
-
 type RFCIndexSTDEntries []*RFCIndexSTDEntry
 
-func (es RFCIndexSTDEntries) Get(docID RFCIndexDocumentID) *RFCIndexSTDEntry {
-	for _, entry := range es {
-		if entry.DocID == docID {
-			return entry
-		}
-	}
-	return nil
-}
-
 type RFCIndexBCPEntry struct {
 	DocID  RFCIndexDocumentID   `xml:"doc-id"`
 	Title  string               `xml:"title,omitempty"`
 	IsAlso *RFCIndexDocumentRef `xml:"is-also,omitempty"`
 }
 
-func (e *RFCIndexBCPEntry) Include(rfcEntry *RFCIndexRFCEntry) bool {
-	for _, docID := range e.IsAlso.DocIDs {
-		if docID == rfcEntry.DocID {
-			return true
-		}
-	}
-	return false
-}
-
 type RFCIndexBCPEntries []*RFCIndexBCPEntry
 
-func (es RFCIndexBCPEntries) Get(docID RFCIndexDocumentID) *RFCIndexBCPEntry {
-	for _, entry := range es {
-		if entry.DocID == docID {
-			return entry
-		}
-	}
-	return nil
-}
-
 type RFCIndexFYIEntry struct {
 	DocID  RFCIndexDocumentID   `xml:"doc-id"`
 	Title  string               `xml:"title,omitempty"`
 	IsAlso *RFCIndexDocumentRef `xml:"is-also,omitempty"`
 }
 
-func (e *RFCIndexFYIEntry) Include(rfcEntry *RFCIndexRFCEntry) bool {
-	for _, docID := range e.IsAlso.DocIDs {
-		if docID == rfcEntry.DocID {
-			return true
-		}
-	}
-	return false
-}
-
 type RFCIndexFYIEntries []*RFCIndexFYIEntry
 
-func (es RFCIndexFYIEntries) Get(docID RFCIndexDocumentID) *RFCIndexFYIEntry {
-	for _, entry := range es {
-		if entry.DocID == docID {
-			return entry
-		}
-	}
-	return nil
-}
-
 type RFCIndexRFCEntry struct {
 	DocID             RFCIndexDocumentID   `xml:"doc-id"`
 	Title             string               `xml:"title"`
@@ -173,12 +154,23 @@ type RFCIndexRFCEntry struct {
 	Area              string               `xml:"area,omitempty"`
 	WgAcronym         string               `xml:"wg_acronym,omitempty"`
 	ErrataURL         string               `xml:"errata-url,omitempty"`
+	DOI               string               `xml:"doi,omitempty"`
 }
 
 func (e *RFCIndexRFCEntry) IsObsolete() bool {
 	return e.ObsoletedBy != nil && len(e.ObsoletedBy.DocIDs) > 0
 }
 
+// DraftName returns the name of the Internet-Draft the RFC was published
+// from, without revision, or an empty name if the index does not record it.
+func (e *RFCIndexRFCEntry) DraftName() string {
+	name, _, err := ParseDraftName(e.Draft)
+	if err != nil {
+		return ""
+	}
+	return name
+}
+
 func (e *RFCIndexRFCEntry) IsObsoletedBy(other *RFCIndexRFCEntry) bool {
 	if e.ObsoletedBy == nil {
 		return false
@@ -205,6 +197,37 @@ func (e *RFCIndexRFCEntry) IsUpdatedBy(other *RFCIndexRFCEntry) bool {
 	return false
 }
 
+// Matches reports whether the lower-cased term occurs in the document ID,
+// number, title, keywords or abstract of the entry.
+func (e *RFCIndexRFCEntry) Matches(term string) bool {
+	docID := strings.ToLower(string(e.DocID))
+	if term == docID || strings.TrimLeft(docID[3:], "0") == strings.TrimLeft(strings.TrimPrefix(term, "rfc"), "0") {
+		return true
+	}
+
+	if strings.Contains(strings.ToLower(e.Title), term) {
+		return true
+	}
+
+	if e.Keywords != nil {
+		for _, kw := range e.Keywords.Kws {
+			if strings.Contains(strings.ToLower(kw), term) {
+				return true
+			}
+		}
+	}
+
+	if e.Abstract != nil {
+		for _, p := range e.Abstract.Ps {
+			if strings.Contains(strings.ToLower(p), term) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 func (e *RFCIndexRFCEntry) ToRFC() (*RFC, error) {
 	number, err := e.DocID.Number()
 	if err != nil {
@@ -221,6 +244,34 @@ func (e *RFCIndexRFCEntry) ToRFC() (*RFC, error) {
 		DocumentID:      string(e.DocID),
 		Title:           e.Title,
 		PublicationDate: date,
+		Status:          string(e.CurrentStatus),
+		Stream:          string(e.Stream),
+		Area:            e.Area,
+		WorkingGroup:    e.WgAcronym,
+		Obsoletes:       e.Obsoletes.RFCNumbers(),
+		ObsoletedBy:     e.ObsoletedBy.RFCNumbers(),
+		Updates:         e.Updates.RFCNumbers(),
+		UpdatedBy:       e.UpdatedBy.RFCNumbers(),
+		SeeAlso:         e.SeeAlso.RFCNumbers(),
+		Series:          e.IsAlso.NonRFCDocIDs(),
+		ErrataURL:       e.ErrataURL,
+		DOI:             e.DOI,
+		Draft:           e.Draft,
+	}
+
+	for _, author := range e.Authors {
+		rfc.Authors = append(rfc.Authors, author.Name)
+		if author.Title == "Editor" {
+			rfc.Editors = append(rfc.Editors, author.Name)
+		}
+	}
+
+	if e.Keywords != nil {
+		rfc.Keywords = e.Keywords.Kws
+	}
+
+	if e.Abstract != nil {
+		rfc.Abstract = strings.Join(e.Abstract.Ps, "\n\n")
 	}
 
 	return &rfc, nil
@@ -242,15 +293,6 @@ func (es RFCIndexRFCEntries) ToRFCs() ([]*RFC, error) {
 	return rfcs, nil
 }
 
-func (es RFCIndexRFCEntries) Get(docID RFCIndexDocumentID) *RFCIndexRFCEntry {
-	for _, entry := range es {
-		if entry.DocID == docID {
-			return entry
-		}
-	}
-	return nil
-}
-
 func (es RFCIndexRFCEntries) Select(predicate RFCIndexRFCEntryPredicate) RFCIndexRFCEntries {
 	var entries RFCIndexRFCEntries
 
@@ -278,6 +320,142 @@ type RFCIndex struct {
 	FYIEntries          RFCIndexFYIEntries          `xml:"fyi-entry"`
 	RFCEntries          RFCIndexRFCEntries          `xml:"rfc-entry"`
 	RFCNotIssuedEntries RFCIndexRFCNotIssuedEntries `xml:"rfc-not-issued-entry"`
+
+	lookupOnce   sync.Once
+	lookupTables *rfcIndexLookupTables
+}
+
+// rfcIndexLookupTables are built once from the parsed entries so that
+// lookups by document ID and relationship traversals do not have to scan
+// every entry.
+type rfcIndexLookupTables struct {
+	rfcEntries   map[RFCIndexDocumentID]*RFCIndexRFCEntry
+	rfcPositions map[RFCIndexDocumentID]int
+	stdEntries   map[RFCIndexDocumentID]*RFCIndexSTDEntry
+	bcpEntries   map[RFCIndexDocumentID]*RFCIndexBCPEntry
+	fyiEntries   map[RFCIndexDocumentID]*RFCIndexFYIEntry
+	obsoletedBy  map[RFCIndexDocumentID]RFCIndexRFCEntries
+	updatedBy    map[RFCIndexDocumentID]RFCIndexRFCEntries
+	drafts       map[string]*RFCIndexRFCEntry
+	wgs          map[string]RFCIndexRFCEntries
+}
+
+func (i *RFCIndex) lookup() *rfcIndexLookupTables {
+	i.lookupOnce.Do(func() {
+		t := rfcIndexLookupTables{
+			rfcEntries:   make(map[RFCIndexDocumentID]*RFCIndexRFCEntry, len(i.RFCEntries)),
+			rfcPositions: make(map[RFCIndexDocumentID]int, len(i.RFCEntries)),
+			stdEntries:   make(map[RFCIndexDocumentID]*RFCIndexSTDEntry, len(i.STDEntries)),
+			bcpEntries:   make(map[RFCIndexDocumentID]*RFCIndexBCPEntry, len(i.BCPEntries)),
+			fyiEntries:   make(map[RFCIndexDocumentID]*RFCIndexFYIEntry, len(i.FYIEntries)),
+			obsoletedBy:  make(map[RFCIndexDocumentID]RFCIndexRFCEntries),
+			updatedBy:    make(map[RFCIndexDocumentID]RFCIndexRFCEntries),
+			drafts:       make(map[string]*RFCIndexRFCEntry),
+			wgs:          make(map[string]RFCIndexRFCEntries),
+		}
+
+		for position, entry := range i.RFCEntries {
+			t.rfcEntries[entry.DocID] = entry
+			t.rfcPositions[entry.DocID] = position
+
+			if entry.ObsoletedBy != nil {
+				for _, docID := range entry.ObsoletedBy.DocIDs {
+					t.obsoletedBy[docID] = append(t.obsoletedBy[docID], entry)
+				}
+			}
+
+			if entry.UpdatedBy != nil {
+				for _, docID := range entry.UpdatedBy.DocIDs {
+					t.updatedBy[docID] = append(t.updatedBy[docID], entry)
+				}
+			}
+
+			if name := entry.DraftName(); name != "" {
+				if _, ok := t.drafts[name]; !ok {
+					t.drafts[name] = entry
+				}
+			}
+
+			if entry.WgAcronym != "" {
+				wg := strings.ToLower(entry.WgAcronym)
+				t.wgs[wg] = append(t.wgs[wg], entry)
+			}
+		}
+
+		for _, entry := range i.STDEntries {
+			t.stdEntries[entry.DocID] = entry
+		}
+		for _, entry := range i.BCPEntries {
+			t.bcpEntries[entry.DocID] = entry
+		}
+		for _, entry := range i.FYIEntries {
+			t.fyiEntries[entry.DocID] = entry
+		}
+
+		i.lookupTables = &t
+	})
+
+	return i.lookupTables
+}
+
+func (i *RFCIndex) RFCEntry(docID RFCIndexDocumentID) *RFCIndexRFCEntry {
+	return i.lookup().rfcEntries[docID]
+}
+
+func (i *RFCIndex) STDEntry(docID RFCIndexDocumentID) *RFCIndexSTDEntry {
+	return i.lookup().stdEntries[docID]
+}
+
+func (i *RFCIndex) BCPEntry(docID RFCIndexDocumentID) *RFCIndexBCPEntry {
+	return i.lookup().bcpEntries[docID]
+}
+
+func (i *RFCIndex) FYIEntry(docID RFCIndexDocumentID) *RFCIndexFYIEntry {
+	return i.lookup().fyiEntries[docID]
+}
+
+// RFCEntriesObsoletedBy returns the entries listing docID in obsoleted-by.
+func (i *RFCIndex) RFCEntriesObsoletedBy(docID RFCIndexDocumentID) RFCIndexRFCEntries {
+	return i.lookup().obsoletedBy[docID]
+}
+
+// RFCEntriesUpdatedBy returns the entries listing docID in updated-by.
+func (i *RFCIndex) RFCEntriesUpdatedBy(docID RFCIndexDocumentID) RFCIndexRFCEntries {
+	return i.lookup().updatedBy[docID]
+}
+
+// RFCEntryByDraft returns the entry published from the Internet-Draft with
+// the given name, without revision.
+func (i *RFCIndex) RFCEntryByDraft(name string) *RFCIndexRFCEntry {
+	return i.lookup().drafts[name]
+}
+
+// RFCEntriesByWorkingGroup returns the entries of the working group with the
+// given acronym, in any case.
+func (i *RFCIndex) RFCEntriesByWorkingGroup(acronym string) RFCIndexRFCEntries {
+	return i.lookup().wgs[strings.ToLower(acronym)]
+}
+
+// RFCEntriesIn returns the RFC entries referenced by ref in index order.
+func (i *RFCIndex) RFCEntriesIn(ref *RFCIndexDocumentRef) RFCIndexRFCEntries {
+	if ref == nil {
+		return nil
+	}
+
+	t := i.lookup()
+
+	var entries RFCIndexRFCEntries
+	for _, docID := range ref.DocIDs {
+		if entry := t.rfcEntries[docID]; entry != nil {
+			entries = append(entries, entry)
+		}
+	}
+
+	sort.Slice(entries, func(a, b int) bool {
+		return t.rfcPositions[entries[a].DocID] < t.rfcPositions[entries[b].DocID]
+	})
+
+	return entries
 }
 
 func ParseRFCIndex(doc []byte) (*RFCIndex, error) {
@@ -290,155 +468,247 @@ func ParseRFCIndex(doc []byte) (*RFCIndex, error) {
 	return &rfcIndex, nil
 }
 
+func ParseRFCIndexData(doc []byte, format RFCIndexDataFormat) (*RFCIndex, error) {
+	switch format {
+	case RFCIndexDataFormatASCII:
+		return ParseRFCIndexText(doc)
+	case RFCIndexDataFormatXML:
+		return ParseRFCIndexReader(bytes.NewReader(doc))
+	}
+	return nil, fmt.Errorf("no parser available for data format: %v", format)
+}
+
+func (i *RFCIndex) ContentSize(number int, format RFCContentFileFormat) (int, bool) {
+	entry := i.RFCEntry(toRFCIndexDocumentID(number))
+	if entry == nil {
+		return 0, false
+	}
+
+	fileFormat, err := toRFCIndexFileFormat(format)
+	if err != nil {
+		return 0, false
+	}
+
+	for _, f := range entry.Formats {
+		if f.FileFormat == fileFormat && f.CharCount > 0 {
+			return f.CharCount, true
+		}
+	}
+	return 0, false
+}
+
 type RFCIndexRFCRepository struct {
 	RFCIndex *RFCIndex
+	Loader   *RFCIndexLoader
+	mu       sync.Mutex
 }
 
-func NewRFCIndexRFCRepository() (*RFCIndexRFCRepository, error) {
-	cache := RFCIndexCacheStore{}
+func NewRFCIndexRFCRepository(format RFCIndexDataFormat) (*RFCIndexRFCRepository, error) {
+	repository := RFCIndexRFCRepository{
+		Loader: NewRFCIndexLoader(format),
+	}
+
+	return &repository, nil
+}
 
-	doc, err := cache.Get(RFCIndexDataFormatXML)
-	if doc == nil {
-		fetcher := RFCIndexFetcher{DataFormat: RFCIndexDataFormatXML}
+func (r *RFCIndexRFCRepository) rfcIndex() (*RFCIndex, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
 
-		doc, err = fetcher.Fetch()
+	if r.RFCIndex == nil {
+		rfcIndex, err := r.Loader.Load()
 		if err != nil {
 			return nil, err
 		}
+		r.RFCIndex = rfcIndex
 	}
 
-	cache.Put(doc, RFCIndexDataFormatXML)
+	return r.RFCIndex, nil
+}
 
-	rfcIndex, err := ParseRFCIndex(doc)
+func (r *RFCIndexRFCRepository) FindAll() ([]*RFC, error) {
+	rfcIndex, err := r.rfcIndex()
 	if err != nil {
 		return nil, err
 	}
 
-	repository := RFCIndexRFCRepository{
-		RFCIndex: rfcIndex,
+	return rfcIndex.RFCEntries.ToRFCs()
+}
+
+func (r *RFCIndexRFCRepository) FindByNumber(number int) (*RFC, error) {
+	rfcIndex, err := r.rfcIndex()
+	if err != nil {
+		return nil, err
 	}
 
-	return &repository, nil
+	entry := rfcIndex.RFCEntry(toRFCIndexDocumentID(number))
+	if entry == nil {
+		return nil, nil
+	}
+
+	return entry.ToRFC()
 }
 
-func (r *RFCIndexRFCRepository) FindAll() ([]*RFC, error) {
-	return r.RFCIndex.RFCEntries.ToRFCs()
+func (r *RFCIndexRFCRepository) Search(query string) ([]*RFC, error) {
+	rfcIndex, err := r.rfcIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	terms := strings.Fields(strings.ToLower(query))
+
+	predicate := func(entry *RFCIndexRFCEntry) bool {
+		for _, term := range terms {
+			if !entry.Matches(term) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return rfcIndex.RFCEntries.Select(predicate).ToRFCs()
 }
 
 func (r *RFCIndexRFCRepository) FindNonObsolete() ([]*RFC, error) {
+	rfcIndex, err := r.rfcIndex()
+	if err != nil {
+		return nil, err
+	}
+
 	predicate := func(entry *RFCIndexRFCEntry) bool {
 		return !entry.IsObsolete()
 	}
 
-	return r.RFCIndex.RFCEntries.Select(predicate).ToRFCs()
+	return rfcIndex.RFCEntries.Select(predicate).ToRFCs()
 }
 
 func (r *RFCIndexRFCRepository) FindObsoletedBy(number int) ([]*RFC, error) {
+	rfcIndex, err := r.rfcIndex()
+	if err != nil {
+		return nil, err
+	}
+
 	docID := toRFCIndexDocumentID(number)
 
-	other := r.RFCIndex.RFCEntries.Get(docID)
-	if other == nil {
+	if rfcIndex.RFCEntry(docID) == nil {
 		return nil, nil
 	}
 
-	predicate := func(entry *RFCIndexRFCEntry) bool {
-		return entry.IsObsoletedBy(other)
-	}
-
-	return r.RFCIndex.RFCEntries.Select(predicate).ToRFCs()
+	return rfcIndex.RFCEntriesObsoletedBy(docID).ToRFCs()
 }
 
 func (r *RFCIndexRFCRepository) FindObsolete(number int) ([]*RFC, error) {
-	docID := toRFCIndexDocumentID(number)
+	rfcIndex, err := r.rfcIndex()
+	if err != nil {
+		return nil, err
+	}
 
-	other := r.RFCIndex.RFCEntries.Get(docID)
+	other := rfcIndex.RFCEntry(toRFCIndexDocumentID(number))
 	if other == nil {
 		return nil, nil
 	}
 
-	predicate := func(entry *RFCIndexRFCEntry) bool {
-		return other.IsObsoletedBy(entry)
-	}
-
-	return r.RFCIndex.RFCEntries.Select(predicate).ToRFCs()
+	return rfcIndex.RFCEntriesIn(other.ObsoletedBy).ToRFCs()
 }
 
 func (r *RFCIndexRFCRepository) FindUpdatedBy(number int) ([]*RFC, error) {
+	rfcIndex, err := r.rfcIndex()
+	if err != nil {
+		return nil, err
+	}
+
 	docID := toRFCIndexDocumentID(number)
 
-	other := r.RFCIndex.RFCEntries.Get(docID)
-	if other == nil {
+	if rfcIndex.RFCEntry(docID) == nil {
 		return nil, nil
 	}
 
-	predicate := func(entry *RFCIndexRFCEntry) bool {
-		return entry.IsUpdatedBy(other)
-	}
-
-	return r.RFCIndex.RFCEntries.Select(predicate).ToRFCs()
+	return rfcIndex.RFCEntriesUpdatedBy(docID).ToRFCs()
 }
 
 func (r *RFCIndexRFCRepository) FindUpdate(number int) ([]*RFC, error) {
-	docID := toRFCIndexDocumentID(number)
+	rfcIndex, err := r.rfcIndex()
+	if err != nil {
+		return nil, err
+	}
 
-	other := r.RFCIndex.RFCEntries.Get(docID)
+	other := rfcIndex.RFCEntry(toRFCIndexDocumentID(number))
 	if other == nil {
 		return nil, nil
 	}
 
-	predicate := func(entry *RFCIndexRFCEntry) bool {
-		return other.IsUpdatedBy(entry)
-	}
-
-	return r.RFCIndex.RFCEntries.Select(predicate).ToRFCs()
+	return rfcIndex.RFCEntriesIn(other.UpdatedBy).ToRFCs()
 }
 
 func (r *RFCIndexRFCRepository) FindBySTDNumber(number int) ([]*RFC, error) {
-	docID := toSTDIndexDocumentID(number)
+	rfcIndex, err := r.rfcIndex()
+	if err != nil {
+		return nil, err
+	}
 
-	stdEntry := r.RFCIndex.STDEntries.Get(docID)
+	stdEntry := rfcIndex.STDEntry(toSTDIndexDocumentID(number))
 	if stdEntry == nil {
 		return nil, nil
 	}
 
-	predicate := func(entry *RFCIndexRFCEntry) bool {
-		return stdEntry.Include(entry)
-	}
-
-	return r.RFCIndex.RFCEntries.Select(predicate).ToRFCs()
+	return rfcIndex.RFCEntriesIn(stdEntry.IsAlso).ToRFCs()
 }
 
 func (r *RFCIndexRFCRepository) FindByBCPNumber(number int) ([]*RFC, error) {
-	docID := toBCPIndexDocumentID(number)
+	rfcIndex, err := r.rfcIndex()
+	if err != nil {
+		return nil, err
+	}
 
-	bcpEntry := r.RFCIndex.BCPEntries.Get(docID)
+	bcpEntry := rfcIndex.BCPEntry(toBCPIndexDocumentID(number))
 	if bcpEntry == nil {
 		return nil, nil
 	}
 
-	predicate := func(entry *RFCIndexRFCEntry) bool {
-		return bcpEntry.Include(entry)
-	}
-
-	return r.RFCIndex.RFCEntries.Select(predicate).ToRFCs()
+	return rfcIndex.RFCEntriesIn(bcpEntry.IsAlso).ToRFCs()
 }
 
 func (r *RFCIndexRFCRepository) FindByFYINumber(number int) ([]*RFC, error) {
-	docID := toFYIIndexDocumentID(number)
+	rfcIndex, err := r.rfcIndex()
+	if err != nil {
+		return nil, err
+	}
 
-	fyiEntry := r.RFCIndex.FYIEntries.Get(docID)
+	fyiEntry := rfcIndex.FYIEntry(toFYIIndexDocumentID(number))
 	if fyiEntry == nil {
 		return nil, nil
 	}
 
-	predicate := func(entry *RFCIndexRFCEntry) bool {
-		return fyiEntry.Include(entry)
+	return rfcIndex.RFCEntriesIn(fyiEntry.IsAlso).ToRFCs()
+}
+
+// FindByDraft returns the RFC published from the Internet-Draft with the
+// given name, with or without revision.
+func (r *RFCIndexRFCRepository) FindByDraft(name string) (*RFC, error) {
+	rfcIndex, err := r.rfcIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	name, _, err = ParseDraftName(name)
+	if err != nil {
+		return nil, err
 	}
 
-	return r.RFCIndex.RFCEntries.Select(predicate).ToRFCs()
+	entry := rfcIndex.RFCEntryByDraft(name)
+	if entry == nil {
+		return nil, nil
+	}
+
+	return entry.ToRFC()
 }
 
 func (r *RFCIndexRFCRepository) FindByCategory(category RFCCategory) ([]*RFC, error) {
+	rfcIndex, err := r.rfcIndex()
+	if err != nil {
+		return nil, err
+	}
+
 	rfcIndexStatus, err := toRFCIndexStatus(category)
 	if err != nil {
 		return nil, err
@@ -448,10 +718,15 @@ func (r *RFCIndexRFCRepository) FindByCategory(category RFCCategory) ([]*RFC, er
 		return entry.CurrentStatus == rfcIndexStatus
 	}
 
-	return r.RFCIndex.RFCEntries.Select(predicate).ToRFCs()
+	return rfcIndex.RFCEntries.Select(predicate).ToRFCs()
 }
 
 func (r *RFCIndexRFCRepository) FindByStream(stream RFCStream) ([]*RFC, error) {
+	rfcIndex, err := r.rfcIndex()
+	if err != nil {
+		return nil, err
+	}
+
 	rfcIndexStream, err := toRFCIndexStream(stream)
 	if err != nil {
 		return nil, err
@@ -461,7 +736,16 @@ func (r *RFCIndexRFCRepository) FindByStream(stream RFCStream) ([]*RFC, error) {
 		return entry.Stream == rfcIndexStream
 	}
 
-	return r.RFCIndex.RFCEntries.Select(predicate).ToRFCs()
+	return rfcIndex.RFCEntries.Select(predicate).ToRFCs()
+}
+
+func (r *RFCIndexRFCRepository) FindByWorkingGroup(acronym string) ([]*RFC, error) {
+	rfcIndex, err := r.rfcIndex()
+	if err != nil {
+		return nil, err
+	}
+
+	return rfcIndex.RFCEntriesByWorkingGroup(acronym).ToRFCs()
 }
 
 func toRFCIndexDocumentID(number int) RFCIndexDocumentID {
@@ -518,11 +802,33 @@ func toRFCIndexStream(stream RFCStream) (RFCIndexStream, error) {
 	return "", fmt.Errorf("cannot recognize RFC stream: %v", stream)
 }
 
+func toRFCIndexFileFormat(format RFCContentFileFormat) (RFCIndexFileFormat, error) {
+	switch format {
+	case RFCContentFileFormatASCII:
+		return "ASCII", nil
+	case RFCContentFileFormatPs:
+		return "PS", nil
+	case RFCContentFileFormatPdf:
+		return "PDF", nil
+	}
+	return "", fmt.Errorf("cannot recognize RFC content file format: %v", format)
+}
+
 type RFCIndexFetcher struct {
 	DataFormat RFCIndexDataFormat
 }
 
 func (f *RFCIndexFetcher) Fetch() ([]byte, error) {
+	body, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return ioutil.ReadAll(body)
+}
+
+func (f *RFCIndexFetcher) Open() (io.ReadCloser, error) {
 	indexURL, err := f.DataFormat.URL()
 	if err != nil {
 		return nil, err
@@ -532,9 +838,13 @@ func (f *RFCIndexFetcher) Fetch() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	defer response.Body.Close()
 
-	return ioutil.ReadAll(response.Body)
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("failed to fetch %s: %s", indexURL, response.Status)
+	}
+
+	return response.Body, nil
 }
 
 type RFCIndexDataFormat int
@@ -547,13 +857,23 @@ const (
 func (f RFCIndexDataFormat) URL() (string, error) {
 	switch f {
 	case RFCIndexDataFormatASCII:
-		return "http://www.rfc-editor.org/in-notes/rfc-index.txt", nil
+		return RFCEditorBaseURL() + "/in-notes/rfc-index.txt", nil
 	case RFCIndexDataFormatXML:
-		return "http://www.rfc-editor.org/in-notes/rfc-index.xml", nil
+		return RFCEditorBaseURL() + "/in-notes/rfc-index.xml", nil
 	}
 	return "", fmt.Errorf("no URL available for file format: %v", f)
 }
 
+func toRFCIndexDataFormat(format string) (RFCIndexDataFormat, error) {
+	switch format {
+	case "txt":
+		return RFCIndexDataFormatASCII, nil
+	case "xml":
+		return RFCIndexDataFormatXML, nil
+	}
+	return RFCIndexDataFormat(0), fmt.Errorf("unknown index format: %s", format)
+}
+
 func (f RFCIndexDataFormat) FileName() (string, error) {
 	switch f {
 	case RFCIndexDataFormatASCII:
@@ -566,56 +886,375 @@ func (f RFCIndexDataFormat) FileName() (string, error) {
 
 type RFCIndexCacheStore struct {
 	CacheDirectory string
+	Compression    CacheCompression
+}
+
+func NewRFCIndexCacheStore() *RFCIndexCacheStore {
+	store := RFCIndexCacheStore{
+		Compression: CacheCompressionFromEnvironment(),
+	}
+
+	return &store
 }
 
 func (s *RFCIndexCacheStore) Put(content []byte, format RFCIndexDataFormat) error {
-	cacheDir, err := s.cacheDirectory()
+	cacheDir, err := s.Directory()
 	if err != nil {
 		return err
 	}
 
-	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+	cacheFile, err := s.cacheFile(cacheDir, format)
+	if err != nil {
 		return err
 	}
 
-	fileName, err := format.FileName()
+	lock, err := lockCacheDirectory(cacheDir, true)
 	if err != nil {
 		return err
 	}
+	defer lock.Unlock()
+
+	return putCacheFile(cacheFile, content, s.Compression)
+}
+
+// Create returns a writer that replaces the cached index when committed.
+func (s *RFCIndexCacheStore) Create(format RFCIndexDataFormat) (*CacheFileWriter, error) {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return nil, err
+	}
 
-	cacheFile := filepath.Join(cacheDir, fileName)
+	cacheFile, err := s.cacheFile(cacheDir, format)
+	if err != nil {
+		return nil, err
+	}
 
-	return ioutil.WriteFile(cacheFile, content, 0644)
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, err
+	}
+
+	w, err := createCacheFile(cacheFile, s.Compression)
+	if err != nil {
+		return nil, err
+	}
+	w.lockDir = cacheDir
+
+	return w, nil
 }
 
 func (s *RFCIndexCacheStore) Get(format RFCIndexDataFormat) ([]byte, error) {
-	cacheDir, err := s.cacheDirectory()
+	cacheDir, err := s.Directory()
 	if err != nil {
 		return nil, err
 	}
 
-	fileName, err := format.FileName()
+	cacheFile, err := s.cacheFile(cacheDir, format)
+	if err != nil {
+		return nil, err
+	}
+
+	content, hasChecksum, compression, err := s.read(cacheDir, cacheFile)
+	if err != nil || content == nil {
+		return content, err
+	}
+
+	// Rewriting the entry converts it to the configured compression and
+	// stores a checksum for entries written before checksums were stored.
+	if compression != s.Compression || !hasChecksum {
+		s.Put(content, format)
+	}
+
+	return content, nil
+}
+
+// ParseCached parses the cached index while reading it, so that the whole
+// document is never held in memory, verifying its checksum. It returns nil
+// if the index is not cached, or if it has to be rewritten by Get as it has
+// no checksum or another compression.
+func (s *RFCIndexCacheStore) ParseCached(format RFCIndexDataFormat) (*RFCIndex, error) {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return nil, err
+	}
+
+	cacheFile, err := s.cacheFile(cacheDir, format)
 	if err != nil {
 		return nil, err
 	}
 
-	cacheFile := filepath.Join(cacheDir, fileName)
+	lock, err := lockCacheDirectory(cacheDir, false)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	r, hasChecksum, compression, err := openCacheFile(cacheFile)
+	if err != nil || r == nil {
+		return nil, err
+	}
+	defer r.Close()
 
-	if _, err = os.Stat(cacheFile); os.IsNotExist(err) {
+	if !hasChecksum || compression != s.Compression {
 		return nil, nil
 	}
 
-	return ioutil.ReadFile(cacheFile)
+	var rfcIndex *RFCIndex
+	if format == RFCIndexDataFormatXML {
+		rfcIndex, err = ParseRFCIndexReader(r)
+	} else {
+		var doc []byte
+		if doc, err = ioutil.ReadAll(r); err == nil {
+			rfcIndex, err = ParseRFCIndexData(doc, format)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Reading to the end verifies the checksum.
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		return nil, err
+	}
+
+	return rfcIndex, nil
 }
 
-func (s *RFCIndexCacheStore) cacheDirectory() (string, error) {
+func (s *RFCIndexCacheStore) read(cacheDir string, cacheFile string) ([]byte, bool, CacheCompression, error) {
+	lock, err := lockCacheDirectory(cacheDir, false)
+	if err != nil {
+		return nil, false, CacheCompressionNone, err
+	}
+	defer lock.Unlock()
+
+	return getCacheFile(cacheFile)
+}
+
+func (s *RFCIndexCacheStore) Remove(format RFCIndexDataFormat) error {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return err
+	}
+
+	cacheFile, err := s.cacheFile(cacheDir, format)
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return removeCacheFile(cacheFile)
+}
+
+// KeepPrevious moves the index as of the last refresh aside so that it can be
+// compared with the index replacing it. That is the baseline kept by
+// KeepBaseline if the index was fetched since, and the cached index
+// otherwise.
+func (s *RFCIndexCacheStore) KeepPrevious(format RFCIndexDataFormat) error {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return err
+	}
+
+	cacheFile, err := s.cacheFile(cacheDir, format)
+	if err != nil {
+		return err
+	}
+
+	previousCacheFile, err := s.previousCacheFile(cacheDir, format)
+	if err != nil {
+		return err
+	}
+
+	baselineCacheFile, err := s.baselineCacheFile(cacheDir, format)
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if info, _ := statCacheFile(baselineCacheFile); info != nil {
+		return renameCacheFile(baselineCacheFile, previousCacheFile)
+	}
+
+	return renameCacheFile(cacheFile, previousCacheFile)
+}
+
+// KeepBaseline moves the cached index aside before it is replaced other than
+// by a refresh, unless an earlier one was kept, so that the next refresh
+// still reports the changes since the last one.
+func (s *RFCIndexCacheStore) KeepBaseline(format RFCIndexDataFormat) error {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return err
+	}
+
+	cacheFile, err := s.cacheFile(cacheDir, format)
+	if err != nil {
+		return err
+	}
+
+	baselineCacheFile, err := s.baselineCacheFile(cacheDir, format)
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if info, _ := statCacheFile(baselineCacheFile); info != nil {
+		return nil
+	}
+
+	return renameCacheFile(cacheFile, baselineCacheFile)
+}
+
+// GetPrevious returns the index kept by KeepPrevious, or nil if there is
+// none.
+func (s *RFCIndexCacheStore) GetPrevious(format RFCIndexDataFormat) ([]byte, error) {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return nil, err
+	}
+
+	previousCacheFile, err := s.previousCacheFile(cacheDir, format)
+	if err != nil {
+		return nil, err
+	}
+
+	content, _, _, err := s.read(cacheDir, previousCacheFile)
+	return content, err
+}
+
+// RemovePrevious removes the indexes kept by KeepPrevious and KeepBaseline.
+func (s *RFCIndexCacheStore) RemovePrevious(format RFCIndexDataFormat) error {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return err
+	}
+
+	previousCacheFile, err := s.previousCacheFile(cacheDir, format)
+	if err != nil {
+		return err
+	}
+
+	baselineCacheFile, err := s.baselineCacheFile(cacheDir, format)
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	if err := removeCacheFile(baselineCacheFile); err != nil {
+		return err
+	}
+
+	return removeCacheFile(previousCacheFile)
+}
+
+func (s *RFCIndexCacheStore) StatPrevious(format RFCIndexDataFormat) (os.FileInfo, error) {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return nil, err
+	}
+
+	previousCacheFile, err := s.previousCacheFile(cacheDir, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return statCacheFile(previousCacheFile)
+}
+
+func (s *RFCIndexCacheStore) Stat(format RFCIndexDataFormat) (os.FileInfo, error) {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return nil, err
+	}
+
+	cacheFile, err := s.cacheFile(cacheDir, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return statCacheFile(cacheFile)
+}
+
+func (s *RFCIndexCacheStore) cacheFile(cacheDir string, format RFCIndexDataFormat) (string, error) {
+	fileName, err := format.FileName()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(cacheDir, fileName), nil
+}
+
+func (s *RFCIndexCacheStore) previousCacheFile(cacheDir string, format RFCIndexDataFormat) (string, error) {
+	return s.variantCacheFile(cacheDir, format, "previous")
+}
+
+func (s *RFCIndexCacheStore) baselineCacheFile(cacheDir string, format RFCIndexDataFormat) (string, error) {
+	return s.variantCacheFile(cacheDir, format, "baseline")
+}
+
+// variantCacheFile returns the cache file of the index named like
+// rfc-index.previous.xml.
+func (s *RFCIndexCacheStore) variantCacheFile(cacheDir string, format RFCIndexDataFormat, variant string) (string, error) {
+	fileName, err := format.FileName()
+	if err != nil {
+		return "", err
+	}
+
+	ext := filepath.Ext(fileName)
+
+	return filepath.Join(cacheDir, strings.TrimSuffix(fileName, ext)+"."+variant+ext), nil
+}
+
+func (s *RFCIndexCacheStore) Directory() (string, error) {
 	if s.CacheDirectory != "" {
 		return s.CacheDirectory, nil
 	}
 
-	if dir := GetUserCacheDirectory("rfcs"); dir != "" {
+	if dir := DefaultCacheDirectory(); dir != "" {
 		return dir, nil
 	}
 
 	return "", fmt.Errorf("cannot determine the cache directory")
 }
+
+type RFCIndexContentSizeProvider struct {
+	Loader   *RFCIndexLoader
+	rfcIndex *RFCIndex
+	loaded   bool
+	mu       sync.Mutex
+}
+
+func (p *RFCIndexContentSizeProvider) ContentSize(number int, format RFCContentFileFormat) (int, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.loaded {
+		p.loaded = true
+		p.rfcIndex, _ = p.Loader.Load()
+	}
+
+	if p.rfcIndex == nil {
+		return 0, false
+	}
+
+	return p.rfcIndex.ContentSize(number, format)
+}
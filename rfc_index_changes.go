@@ -0,0 +1,113 @@
+package main
+
+import "sort"
+
+const (
+	RFCIndexChangeNew         = "new"
+	RFCIndexChangeStatus      = "status"
+	RFCIndexChangeObsoletedBy = "obsoleted-by"
+	RFCIndexChangeUpdatedBy   = "updated-by"
+	RFCIndexChangeErrata      = "errata"
+)
+
+// RFCIndexChange is a change to an RFC between two versions of the index.
+// Old and New are the status before and after a status change, the RFC that
+// obsoletes or updates it, or the errata URL.
+type RFCIndexChange struct {
+	Kind   string `json:"kind"`
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	Old    string `json:"old,omitempty"`
+	New    string `json:"new,omitempty"`
+}
+
+// DiffRFCIndexes returns the changes from the old to the new index, ordered
+// by kind and then by RFC number.
+func DiffRFCIndexes(old, new *RFCIndex) []*RFCIndexChange {
+	var changes []*RFCIndexChange
+
+	add := func(kind string, entry *RFCIndexRFCEntry, oldValue, newValue string) {
+		number, _ := entry.DocID.Number()
+		changes = append(changes, &RFCIndexChange{
+			Kind:   kind,
+			Number: number,
+			Title:  entry.Title,
+			Old:    oldValue,
+			New:    newValue,
+		})
+	}
+
+	for _, entry := range new.RFCEntries {
+		previous := old.RFCEntry(entry.DocID)
+		if previous == nil {
+			add(RFCIndexChangeNew, entry, "", string(entry.CurrentStatus))
+			continue
+		}
+
+		if previous.CurrentStatus != entry.CurrentStatus {
+			add(RFCIndexChangeStatus, entry, string(previous.CurrentStatus), string(entry.CurrentStatus))
+		}
+
+		for _, docID := range newDocIDs(previous.ObsoletedBy, entry.ObsoletedBy) {
+			add(RFCIndexChangeObsoletedBy, entry, "", docID)
+		}
+
+		for _, docID := range newDocIDs(previous.UpdatedBy, entry.UpdatedBy) {
+			add(RFCIndexChangeUpdatedBy, entry, "", docID)
+		}
+
+		if previous.ErrataURL == "" && entry.ErrataURL != "" {
+			add(RFCIndexChangeErrata, entry, "", entry.ErrataURL)
+		}
+	}
+
+	sort.Stable(ByRFCIndexChange(changes))
+
+	return changes
+}
+
+var rfcIndexChangeOrder = map[string]int{
+	RFCIndexChangeNew:         0,
+	RFCIndexChangeStatus:      1,
+	RFCIndexChangeObsoletedBy: 2,
+	RFCIndexChangeUpdatedBy:   3,
+	RFCIndexChangeErrata:      4,
+}
+
+type ByRFCIndexChange []*RFCIndexChange
+
+func (c ByRFCIndexChange) Len() int {
+	return len(c)
+}
+
+func (c ByRFCIndexChange) Swap(i, j int) {
+	c[i], c[j] = c[j], c[i]
+}
+
+func (c ByRFCIndexChange) Less(i, j int) bool {
+	if rfcIndexChangeOrder[c[i].Kind] != rfcIndexChangeOrder[c[j].Kind] {
+		return rfcIndexChangeOrder[c[i].Kind] < rfcIndexChangeOrder[c[j].Kind]
+	}
+	return c[i].Number < c[j].Number
+}
+
+func newDocIDs(old, new *RFCIndexDocumentRef) []string {
+	if new == nil {
+		return nil
+	}
+
+	seen := make(map[RFCIndexDocumentID]bool)
+	if old != nil {
+		for _, docID := range old.DocIDs {
+			seen[docID] = true
+		}
+	}
+
+	var docIDs []string
+	for _, docID := range new.DocIDs {
+		if !seen[docID] {
+			docIDs = append(docIDs, string(docID))
+		}
+	}
+	return docIDs
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+const (
+	testRFCIndexBefore = `<rfc-index xmlns="https://www.rfc-editor.org/rfc-index">
+    <rfc-entry>
+        <doc-id>RFC2616</doc-id>
+        <title>Hypertext Transfer Protocol -- HTTP/1.1</title>
+        <date><month>June</month><year>1999</year></date>
+        <current-status>DRAFT STANDARD</current-status>
+    </rfc-entry>
+    <rfc-entry>
+        <doc-id>RFC3986</doc-id>
+        <title>Uniform Resource Identifier (URI): Generic Syntax</title>
+        <date><month>January</month><year>2005</year></date>
+        <current-status>INTERNET STANDARD</current-status>
+    </rfc-entry>
+    <rfc-entry>
+        <doc-id>RFC7230</doc-id>
+        <title>HTTP/1.1: Message Syntax and Routing</title>
+        <date><month>June</month><year>2014</year></date>
+        <updated-by><doc-id>RFC7231</doc-id></updated-by>
+        <current-status>PROPOSED STANDARD</current-status>
+    </rfc-entry>
+</rfc-index>`
+
+	testRFCIndexAfter = `<rfc-index xmlns="https://www.rfc-editor.org/rfc-index">
+    <rfc-entry>
+        <doc-id>RFC2616</doc-id>
+        <title>Hypertext Transfer Protocol -- HTTP/1.1</title>
+        <date><month>June</month><year>1999</year></date>
+        <obsoleted-by><doc-id>RFC7230</doc-id><doc-id>RFC7231</doc-id></obsoleted-by>
+        <current-status>HISTORIC</current-status>
+    </rfc-entry>
+    <rfc-entry>
+        <doc-id>RFC3986</doc-id>
+        <title>Uniform Resource Identifier (URI): Generic Syntax</title>
+        <date><month>January</month><year>2005</year></date>
+        <current-status>INTERNET STANDARD</current-status>
+        <errata-url>https://www.rfc-editor.org/errata/rfc3986</errata-url>
+    </rfc-entry>
+    <rfc-entry>
+        <doc-id>RFC7230</doc-id>
+        <title>HTTP/1.1: Message Syntax and Routing</title>
+        <date><month>June</month><year>2014</year></date>
+        <updated-by><doc-id>RFC7231</doc-id><doc-id>RFC8615</doc-id></updated-by>
+        <current-status>PROPOSED STANDARD</current-status>
+    </rfc-entry>
+    <rfc-entry>
+        <doc-id>RFC9110</doc-id>
+        <title>HTTP Semantics</title>
+        <date><month>June</month><year>2022</year></date>
+        <current-status>INTERNET STANDARD</current-status>
+    </rfc-entry>
+</rfc-index>`
+)
+
+func TestDiffRFCIndexes(t *testing.T) {
+	before, err := ParseRFCIndex([]byte(testRFCIndexBefore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := ParseRFCIndex([]byte(testRFCIndexAfter))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []RFCIndexChange
+	for _, change := range DiffRFCIndexes(before, after) {
+		got = append(got, *change)
+	}
+
+	want := []RFCIndexChange{
+		{Kind: RFCIndexChangeNew, Number: 9110, Title: "HTTP Semantics", New: "INTERNET STANDARD"},
+		{Kind: RFCIndexChangeStatus, Number: 2616, Title: "Hypertext Transfer Protocol -- HTTP/1.1", Old: "DRAFT STANDARD", New: "HISTORIC"},
+		{Kind: RFCIndexChangeObsoletedBy, Number: 2616, Title: "Hypertext Transfer Protocol -- HTTP/1.1", New: "RFC7230"},
+		{Kind: RFCIndexChangeObsoletedBy, Number: 2616, Title: "Hypertext Transfer Protocol -- HTTP/1.1", New: "RFC7231"},
+		{Kind: RFCIndexChangeUpdatedBy, Number: 7230, Title: "HTTP/1.1: Message Syntax and Routing", New: "RFC8615"},
+		{Kind: RFCIndexChangeErrata, Number: 3986, Title: "Uniform Resource Identifier (URI): Generic Syntax", New: "https://www.rfc-editor.org/errata/rfc3986"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got changes\n%+v\nwant\n%+v", got, want)
+	}
+
+	if changes := DiffRFCIndexes(after, after); len(changes) != 0 {
+		t.Errorf("got %d changes between the same indexes", len(changes))
+	}
+}
+
+func TestRFCIndexCacheStoreKeepPrevious(t *testing.T) {
+	store := &RFCIndexCacheStore{CacheDirectory: newTestCacheDirectory(t)}
+	format := RFCIndexDataFormatXML
+
+	put := func(content string) {
+		t.Helper()
+		if err := store.Put([]byte(content), format); err != nil {
+			t.Fatal(err)
+		}
+	}
+	previous := func() string {
+		t.Helper()
+		content, err := store.GetPrevious(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(content)
+	}
+
+	if got := previous(); got != "" {
+		t.Errorf("got previous index %q before any refresh", got)
+	}
+
+	// A refresh keeps the index it replaces.
+	put("first")
+	if err := store.KeepPrevious(format); err != nil {
+		t.Fatal(err)
+	}
+	put("second")
+	if got := previous(); got != "first" {
+		t.Errorf("got previous index %q, want first", got)
+	}
+
+	// Fetching the index other than by a refresh keeps a baseline, so the
+	// next refresh reports the changes since the last refresh.
+	if err := store.KeepBaseline(format); err != nil {
+		t.Fatal(err)
+	}
+	put("third")
+	if err := store.KeepBaseline(format); err != nil {
+		t.Fatal(err)
+	}
+	put("fourth")
+	if err := store.KeepPrevious(format); err != nil {
+		t.Fatal(err)
+	}
+	put("fifth")
+	if got := previous(); got != "second" {
+		t.Errorf("got previous index %q, want second", got)
+	}
+
+	if err := store.RemovePrevious(format); err != nil {
+		t.Fatal(err)
+	}
+	if got := previous(); got != "" {
+		t.Errorf("got previous index %q after removing it", got)
+	}
+}
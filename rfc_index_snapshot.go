@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Bump rfcIndexSnapshotVersion whenever the layout of RFCIndex changes so
+// that snapshots written by older versions are rebuilt from the index.
+const (
+	rfcIndexSnapshotVersion  = 2
+	rfcIndexSnapshotFileName = "rfc-index.gob"
+)
+
+type RFCIndexSnapshot struct {
+	Version        int
+	SourceChecksum string
+	RFCIndex       *RFCIndex
+}
+
+func (s *RFCIndexCacheStore) PutSnapshot(rfcIndex *RFCIndex, sourceChecksum string) error {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return err
+	}
+
+	snapshot := RFCIndexSnapshot{
+		Version:        rfcIndexSnapshotVersion,
+		SourceChecksum: sourceChecksum,
+		RFCIndex:       rfcIndex,
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&snapshot); err != nil {
+		return err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return putCacheFile(filepath.Join(cacheDir, rfcIndexSnapshotFileName), buf.Bytes(), s.Compression)
+}
+
+// GetSnapshot returns nil if there is no snapshot built from the source with
+// the given checksum.
+func (s *RFCIndexCacheStore) GetSnapshot(sourceChecksum string) (*RFCIndex, error) {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return nil, err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, false)
+	if err != nil {
+		return nil, err
+	}
+	defer lock.Unlock()
+
+	content, _, _, err := getCacheFile(filepath.Join(cacheDir, rfcIndexSnapshotFileName))
+	if err != nil || content == nil {
+		return nil, err
+	}
+
+	var snapshot RFCIndexSnapshot
+	if err := gob.NewDecoder(bytes.NewReader(content)).Decode(&snapshot); err != nil {
+		return nil, ErrCorruptCacheEntry
+	}
+
+	if snapshot.Version != rfcIndexSnapshotVersion || snapshot.SourceChecksum != sourceChecksum {
+		return nil, nil
+	}
+
+	return snapshot.RFCIndex, nil
+}
+
+func (s *RFCIndexCacheStore) RemoveSnapshot() error {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return err
+	}
+
+	lock, err := lockCacheDirectory(cacheDir, true)
+	if err != nil {
+		return err
+	}
+	defer lock.Unlock()
+
+	return removeCacheFile(filepath.Join(cacheDir, rfcIndexSnapshotFileName))
+}
+
+// Checksum returns the checksum stored for the cached index without reading
+// the index itself, or an empty string if there is none.
+func (s *RFCIndexCacheStore) Checksum(format RFCIndexDataFormat) (string, error) {
+	cacheDir, err := s.Directory()
+	if err != nil {
+		return "", err
+	}
+
+	cacheFile, err := s.cacheFile(cacheDir, format)
+	if err != nil {
+		return "", err
+	}
+
+	checksum, err := ioutil.ReadFile(checksumFileFor(cacheFile))
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(checksum)), nil
+}
+
+type RFCIndexLoader struct {
+	DataFormat RFCIndexDataFormat
+	CacheStore *RFCIndexCacheStore
+	Fetcher    *RFCIndexFetcher
+
+	// Refresh makes Load fetch the index even if it is cached. The index
+	// as of the last refresh is kept as the previous index.
+	Refresh bool
+
+	// MaxAge makes Load fetch the index if the cached one is older, falling
+	// back to the cached one if that fails. Zero means no limit.
+	MaxAge time.Duration
+}
+
+// NewRFCIndexLoader returns a loader fetching the index if it is not cached,
+// or if it is older than RFCS_INDEX_TTL.
+func NewRFCIndexLoader(format RFCIndexDataFormat) *RFCIndexLoader {
+	loader := RFCIndexLoader{
+		DataFormat: format,
+		CacheStore: NewRFCIndexCacheStore(),
+		Fetcher:    &RFCIndexFetcher{DataFormat: format},
+	}
+
+	if ttl := os.Getenv(envIndexTTL); ttl != "" {
+		loader.MaxAge, _ = ParseDuration(ttl)
+	}
+
+	return &loader
+}
+
+// Load returns the RFC index from the pre-parsed snapshot if it is up to date
+// with the cached index, and otherwise parses the index, fetching it if it is
+// not cached and a fetcher is set, and rebuilds the snapshot.
+func (l *RFCIndexLoader) Load() (*RFCIndex, error) {
+	if l.Refresh && l.Fetcher != nil {
+		return l.fetch(true)
+	}
+
+	if l.MaxAge > 0 && l.Fetcher != nil {
+		if info, err := l.CacheStore.Stat(l.DataFormat); err == nil && info != nil && time.Since(info.ModTime()) > l.MaxAge {
+			if rfcIndex, err := l.fetch(false); err == nil {
+				return rfcIndex, nil
+			}
+		}
+	}
+
+	if checksum, _ := l.CacheStore.Checksum(l.DataFormat); checksum != "" {
+		if rfcIndex, err := l.CacheStore.GetSnapshot(checksum); err == nil && rfcIndex != nil {
+			return rfcIndex, nil
+		}
+
+		// Failures are handled below, where the index is read as a whole.
+		if rfcIndex, err := l.CacheStore.ParseCached(l.DataFormat); err == nil && rfcIndex != nil {
+			l.CacheStore.PutSnapshot(rfcIndex, checksum)
+			return rfcIndex, nil
+		}
+	}
+
+	doc, err := l.CacheStore.Get(l.DataFormat)
+	if err == ErrCorruptCacheEntry {
+		l.CacheStore.Remove(l.DataFormat)
+	}
+	if doc == nil {
+		if l.Fetcher == nil {
+			return nil, fmt.Errorf("RFC index is not cached")
+		}
+
+		return l.fetch(false)
+	}
+
+	rfcIndex, err := ParseRFCIndexData(doc, l.DataFormat)
+	if err != nil {
+		// Indexes cached before checksums were stored are not verified, so
+		// a truncated one is only noticed here.
+		l.CacheStore.Remove(l.DataFormat)
+		if l.Fetcher == nil {
+			return nil, err
+		}
+
+		return l.fetch(false)
+	}
+
+	l.CacheStore.PutSnapshot(rfcIndex, checksumOf(doc))
+
+	return rfcIndex, nil
+}
+
+// fetch parses the index while it is being downloaded and written to the
+// cache, so that the whole document is never held in memory. The index it
+// replaces becomes the previous index on a refresh, and the baseline of the
+// next refresh otherwise.
+func (l *RFCIndexLoader) fetch(refresh bool) (*RFCIndex, error) {
+	body, err := l.Fetcher.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	w, err := l.CacheStore.Create(l.DataFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	r := io.TeeReader(body, w)
+
+	var rfcIndex *RFCIndex
+	if l.DataFormat == RFCIndexDataFormatXML {
+		rfcIndex, err = ParseRFCIndexReader(r)
+	} else {
+		var doc []byte
+		if doc, err = ioutil.ReadAll(r); err == nil {
+			rfcIndex, err = ParseRFCIndexData(doc, l.DataFormat)
+		}
+	}
+	if err != nil {
+		w.Abort()
+		return nil, err
+	}
+
+	if _, err := io.Copy(w, body); err != nil {
+		w.Abort()
+		return nil, err
+	}
+
+	// The replaced index is kept even if it is the same, so that changes are
+	// always reported since the last refresh.
+	if info, _ := l.CacheStore.Stat(l.DataFormat); info != nil {
+		keep := l.CacheStore.KeepBaseline
+		if refresh {
+			keep = l.CacheStore.KeepPrevious
+		}
+		if err := keep(l.DataFormat); err != nil {
+			w.Abort()
+			return nil, err
+		}
+	}
+
+	if err := w.Commit(); err != nil {
+		return nil, err
+	}
+
+	l.CacheStore.PutSnapshot(rfcIndex, w.Checksum())
+
+	return rfcIndex, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"testing"
+)
+
+func readRFCIndexFixture(tb testing.TB) []byte {
+	doc, err := ioutil.ReadFile("testdata/rfc-index.xml")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return doc
+}
+
+// rfcIndexFixtureEntryPattern matches an rfc-entry of the fixture index with
+// its own doc-id as a submatch.
+var rfcIndexFixtureEntryPattern = regexp.MustCompile(`(?s)<rfc-entry>\s*<doc-id>(RFC\d+)</doc-id>.*?</rfc-entry>`)
+
+// largeRFCIndexFixtureSize is about the number of RFCs in the real index.
+const largeRFCIndexFixtureSize = 9500
+
+// generateRFCIndexFixture returns an index of size RFC entries made by
+// repeating the entries of the fixture index under distinct doc-ids.
+func generateRFCIndexFixture(tb testing.TB, size int) []byte {
+	doc := readRFCIndexFixture(tb)
+
+	entries := rfcIndexFixtureEntryPattern.FindAll(doc, -1)
+	if len(entries) == 0 {
+		tb.Fatal("no RFC entries in the fixture")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>` + "\n")
+	buf.WriteString(`<rfc-index xmlns="https://www.rfc-editor.org/rfc-index">` + "\n")
+	for i := 0; i < size; i++ {
+		entry := entries[i%len(entries)]
+		docID := rfcIndexFixtureEntryPattern.FindSubmatch(entry)[1]
+		entry = bytes.Replace(entry, docID, []byte(fmt.Sprintf("RFC%04d", i+1)), -1)
+
+		buf.WriteString("    ")
+		buf.Write(entry)
+		buf.WriteString("\n")
+	}
+	buf.WriteString("</rfc-index>\n")
+
+	return buf.Bytes()
+}
+
+// newRFCIndexFixtureCacheStore returns a cache store in a temporary
+// directory holding the given index and its snapshot.
+func newRFCIndexFixtureCacheStore(tb testing.TB, doc []byte) *RFCIndexCacheStore {
+	dir, err := ioutil.TempDir("", "rfcs")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { os.RemoveAll(dir) })
+
+	store := RFCIndexCacheStore{CacheDirectory: dir}
+
+	if err := store.Put(doc, RFCIndexDataFormatXML); err != nil {
+		tb.Fatal(err)
+	}
+
+	rfcIndex, err := ParseRFCIndex(doc)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := store.PutSnapshot(rfcIndex, checksumOf(doc)); err != nil {
+		tb.Fatal(err)
+	}
+
+	return &store
+}
+
+func BenchmarkParseRFCIndex(b *testing.B) {
+	doc := generateRFCIndexFixture(b, largeRFCIndexFixtureSize)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseRFCIndex(doc); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRFCIndexCacheStoreGetSnapshot(b *testing.B) {
+	doc := generateRFCIndexFixture(b, largeRFCIndexFixtureSize)
+	store := newRFCIndexFixtureCacheStore(b, doc)
+	checksum := checksumOf(doc)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rfcIndex, err := store.GetSnapshot(checksum)
+		if err != nil {
+			b.Fatal(err)
+		} else if rfcIndex == nil {
+			b.Fatal("no snapshot")
+		}
+	}
+}
+
+func BenchmarkRFCIndexLoaderLoad(b *testing.B) {
+	loader := RFCIndexLoader{
+		DataFormat: RFCIndexDataFormatXML,
+		CacheStore: newRFCIndexFixtureCacheStore(b, generateRFCIndexFixture(b, largeRFCIndexFixtureSize)),
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := loader.Load(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRFCIndexLoaderLoadSnapshot(t *testing.T) {
+	store := newRFCIndexFixtureCacheStore(t, readRFCIndexFixture(t))
+	loader := RFCIndexLoader{DataFormat: RFCIndexDataFormatXML, CacheStore: store}
+
+	rfcIndex, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(rfcIndex.RFCEntries), 7; got != want {
+		t.Errorf("got %d RFC entries, want %d", got, want)
+	}
+	if entry := rfcIndex.RFCEntry("RFC9110"); entry == nil || entry.Title != "HTTP Semantics" {
+		t.Errorf("got RFC9110 entry %+v", entry)
+	}
+}
+
+func TestGenerateRFCIndexFixture(t *testing.T) {
+	rfcIndex, err := ParseRFCIndex(generateRFCIndexFixture(t, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := len(rfcIndex.RFCEntries), 100; got != want {
+		t.Errorf("got %d RFC entries, want %d", got, want)
+	}
+	for i, entry := range rfcIndex.RFCEntries {
+		if want := toRFCIndexDocumentID(i + 1); entry.DocID != want || rfcIndex.RFCEntry(want) != entry {
+			t.Errorf("got entry %s at %d, want %s", entry.DocID, i, want)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// RFCIndexDecoder reads the entries of an XML RFC index one at a time, so
+// that the index can be processed while it is being read without holding the
+// whole document in memory.
+type RFCIndexDecoder struct {
+	decoder *xml.Decoder
+	root    xml.Name
+}
+
+func NewRFCIndexDecoder(r io.Reader) *RFCIndexDecoder {
+	return &RFCIndexDecoder{decoder: xml.NewDecoder(r)}
+}
+
+// Next returns the next entry of the index, which is one of
+// *RFCIndexSTDEntry, *RFCIndexBCPEntry, *RFCIndexFYIEntry, *RFCIndexRFCEntry
+// and *RFCIndexRFCNotIssuedEntry, or io.EOF at the end of the index.
+func (d *RFCIndexDecoder) Next() (interface{}, error) {
+	for {
+		token, err := d.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		var entry interface{}
+		switch start.Name.Local {
+		case "rfc-index":
+			d.root = start.Name
+			continue
+		case "std-entry":
+			entry = &RFCIndexSTDEntry{}
+		case "bcp-entry":
+			entry = &RFCIndexBCPEntry{}
+		case "fyi-entry":
+			entry = &RFCIndexFYIEntry{}
+		case "rfc-entry":
+			entry = &RFCIndexRFCEntry{}
+		case "rfc-not-issued-entry":
+			entry = &RFCIndexRFCNotIssuedEntry{}
+		default:
+			if err := d.decoder.Skip(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		if err := d.decoder.DecodeElement(entry, &start); err != nil {
+			return nil, err
+		}
+
+		return entry, nil
+	}
+}
+
+// Root returns the name of the root element once it has been read.
+func (d *RFCIndexDecoder) Root() xml.Name {
+	return d.root
+}
+
+func ParseRFCIndexReader(r io.Reader) (*RFCIndex, error) {
+	var rfcIndex RFCIndex
+
+	decoder := NewRFCIndexDecoder(r)
+
+	for {
+		entry, err := decoder.Next()
+		if err == io.EOF && decoder.Root().Local != "" {
+			break
+		} else if err == io.EOF {
+			return nil, fmt.Errorf("no rfc-index element found")
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch entry := entry.(type) {
+		case *RFCIndexSTDEntry:
+			rfcIndex.STDEntries = append(rfcIndex.STDEntries, entry)
+		case *RFCIndexBCPEntry:
+			rfcIndex.BCPEntries = append(rfcIndex.BCPEntries, entry)
+		case *RFCIndexFYIEntry:
+			rfcIndex.FYIEntries = append(rfcIndex.FYIEntries, entry)
+		case *RFCIndexRFCEntry:
+			rfcIndex.RFCEntries = append(rfcIndex.RFCEntries, entry)
+		case *RFCIndexRFCNotIssuedEntry:
+			rfcIndex.RFCNotIssuedEntries = append(rfcIndex.RFCNotIssuedEntries, entry)
+		}
+	}
+
+	rfcIndex.XMLName = decoder.Root()
+
+	return &rfcIndex, nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// testRFCIndexRelations is an index where RFC 2616 is obsoleted by RFC 7230
+// and RFC 7231, which are both obsoleted by RFC 9110, and RFC 7230 is
+// updated by RFC 8615.
+const testRFCIndexRelations = `<rfc-index xmlns="https://www.rfc-editor.org/rfc-index">
+    <std-entry>
+        <doc-id>STD0097</doc-id>
+        <is-also><doc-id>RFC9110</doc-id></is-also>
+    </std-entry>
+    <bcp-entry>
+        <doc-id>BCP0190</doc-id>
+        <is-also><doc-id>RFC8820</doc-id></is-also>
+    </bcp-entry>
+    <fyi-entry>
+        <doc-id>FYI0036</doc-id>
+        <is-also><doc-id>RFC4949</doc-id><doc-id>RFC9999</doc-id></is-also>
+    </fyi-entry>
+    <rfc-entry>
+        <doc-id>RFC2616</doc-id>
+        <date><month>June</month><year>2022</year></date>
+        <obsoleted-by><doc-id>RFC7231</doc-id><doc-id>RFC7230</doc-id></obsoleted-by>
+        <wg_acronym>http</wg_acronym>
+    </rfc-entry>
+    <rfc-entry>
+        <doc-id>RFC4949</doc-id>
+        <date><month>June</month><year>2022</year></date>
+    </rfc-entry>
+    <rfc-entry>
+        <doc-id>RFC7230</doc-id>
+        <date><month>June</month><year>2022</year></date>
+        <draft>draft-ietf-httpbis-p1-messaging-26</draft>
+        <obsoleted-by><doc-id>RFC9110</doc-id></obsoleted-by>
+        <updated-by><doc-id>RFC8615</doc-id></updated-by>
+        <wg_acronym>httpbis</wg_acronym>
+    </rfc-entry>
+    <rfc-entry>
+        <doc-id>RFC7231</doc-id>
+        <date><month>June</month><year>2022</year></date>
+        <draft>draft-ietf-httpbis-p2-semantics-26</draft>
+        <obsoleted-by><doc-id>RFC9110</doc-id></obsoleted-by>
+        <wg_acronym>HTTPbis</wg_acronym>
+    </rfc-entry>
+    <rfc-entry>
+        <doc-id>RFC8615</doc-id>
+        <date><month>June</month><year>2022</year></date>
+        <wg_acronym>NON WORKING GROUP</wg_acronym>
+    </rfc-entry>
+    <rfc-entry>
+        <doc-id>RFC8820</doc-id>
+        <date><month>June</month><year>2022</year></date>
+    </rfc-entry>
+    <rfc-entry>
+        <doc-id>RFC9110</doc-id>
+        <date><month>June</month><year>2022</year></date>
+        <draft>draft-ietf-httpbis-semantics-19</draft>
+        <wg_acronym>httpbis</wg_acronym>
+    </rfc-entry>
+</rfc-index>`
+
+func rfcNumbers(rfcs []*RFC) []int {
+	var numbers []int
+	for _, rfc := range rfcs {
+		numbers = append(numbers, rfc.Number)
+	}
+	return numbers
+}
+
+func TestRFCIndexRFCRepositoryRelations(t *testing.T) {
+	rfcIndex, err := ParseRFCIndex([]byte(testRFCIndexRelations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repository := RFCIndexRFCRepository{RFCIndex: rfcIndex}
+
+	tests := []struct {
+		name   string
+		find   func(int) ([]*RFC, error)
+		number int
+		want   []int
+	}{
+		{"obsoleted by 9110", repository.FindObsoletedBy, 9110, []int{7230, 7231}},
+		{"obsoleted by 7231", repository.FindObsoletedBy, 7231, []int{2616}},
+		{"obsoleted by 2616", repository.FindObsoletedBy, 2616, nil},
+		{"obsoleting 2616", repository.FindObsolete, 2616, []int{7230, 7231}},
+		{"obsoleting 9110", repository.FindObsolete, 9110, nil},
+		{"updated by 8615", repository.FindUpdatedBy, 8615, []int{7230}},
+		{"updating 7230", repository.FindUpdate, 7230, []int{8615}},
+		{"updating missing RFC", repository.FindUpdate, 1, nil},
+		{"STD 97", repository.FindBySTDNumber, 97, []int{9110}},
+		{"STD 1", repository.FindBySTDNumber, 1, nil},
+		{"BCP 190", repository.FindByBCPNumber, 190, []int{8820}},
+		{"FYI 36", repository.FindByFYINumber, 36, []int{4949}},
+	}
+
+	for _, test := range tests {
+		rfcs, err := test.find(test.number)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got := rfcNumbers(rfcs); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRFCIndexRFCRepositoryFindByDraft(t *testing.T) {
+	rfcIndex, err := ParseRFCIndex([]byte(testRFCIndexRelations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repository := RFCIndexRFCRepository{RFCIndex: rfcIndex}
+
+	tests := []struct {
+		name string
+		want int
+	}{
+		{"draft-ietf-httpbis-semantics", 9110},
+		{"draft-ietf-httpbis-semantics-12", 9110},
+		{"draft-ietf-httpbis-p1-messaging-26", 7230},
+		{"draft-ietf-httpbis-cache", 0},
+	}
+
+	for _, test := range tests {
+		rfc, err := repository.FindByDraft(test.name)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		got := 0
+		if rfc != nil {
+			got = rfc.Number
+		}
+		if got != test.want {
+			t.Errorf("%s: got RFC %d, want RFC %d", test.name, got, test.want)
+		}
+	}
+}
+
+func TestRFCIndexRFCRepositoryFindByWorkingGroup(t *testing.T) {
+	rfcIndex, err := ParseRFCIndex([]byte(testRFCIndexRelations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	repository := RFCIndexRFCRepository{RFCIndex: rfcIndex}
+
+	rfcs, err := repository.FindByWorkingGroup("HTTPBIS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := rfcNumbers(rfcs), []int{7230, 7231, 9110}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
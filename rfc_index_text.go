@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	rfcIndexTextEntryStartPattern = regexp.MustCompile(`^(\d{4,}) (.*)$`)
+	rfcIndexTextDatePattern       = regexp.MustCompile(`(?:(\d{1,2}) )?(January|February|March|April|May|June|July|August|September|October|November|December) (\d{4})\.`)
+	rfcIndexTextGroupPattern      = regexp.MustCompile(`\((Format|Obsoletes|Obsoleted by|Updates|Updated by|Also|See Also|Status|Stream|DOI)\b:? ?([^()]*)\)`)
+	rfcIndexTextAuthorStart       = regexp.MustCompile(`\. ([A-Z][A-Za-z'-]*\.(?: |[A-Z-])|[A-Z][a-z]+ [A-Z]\. )`)
+)
+
+// ParseRFCIndexText parses the plain-text rfc-index.txt into the same
+// structure as ParseRFCIndex. The text index carries no STD, BCP and FYI
+// entries of its own; they are reconstructed from the "Also" references of
+// the RFC entries.
+func ParseRFCIndexText(doc []byte) (*RFCIndex, error) {
+	var rfcIndex RFCIndex
+
+	for _, block := range splitRFCIndexTextEntries(doc) {
+		match := rfcIndexTextEntryStartPattern.FindStringSubmatch(block)
+		if match == nil {
+			continue
+		}
+
+		docID := RFCIndexDocumentID("RFC" + match[1])
+		body := strings.TrimSpace(match[2])
+
+		if strings.HasPrefix(body, "Not Issued") {
+			rfcIndex.RFCNotIssuedEntries = append(rfcIndex.RFCNotIssuedEntries, &RFCIndexRFCNotIssuedEntry{DocID: docID})
+			continue
+		}
+
+		entry, err := parseRFCIndexTextEntry(docID, body)
+		if err != nil {
+			return nil, err
+		}
+
+		rfcIndex.RFCEntries = append(rfcIndex.RFCEntries, entry)
+	}
+
+	rfcIndex.addSeriesEntriesFromRFCEntries()
+
+	return &rfcIndex, nil
+}
+
+// splitRFCIndexTextEntries returns the entries of the index with their
+// continuation lines joined and whitespace collapsed. The preamble before the
+// first entry is skipped.
+func splitRFCIndexTextEntries(doc []byte) []string {
+	var entries []string
+	var current []string
+
+	flush := func() {
+		if len(current) > 0 {
+			entries = append(entries, strings.Join(current, " "))
+			current = nil
+		}
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(doc))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r\f")
+
+		if line == "" {
+			flush()
+			continue
+		}
+
+		if rfcIndexTextEntryStartPattern.MatchString(line) {
+			flush()
+			current = append(current, line)
+		} else if len(current) > 0 {
+			current = append(current, strings.TrimSpace(line))
+		}
+	}
+	flush()
+
+	return entries
+}
+
+func parseRFCIndexTextEntry(docID RFCIndexDocumentID, body string) (*RFCIndexRFCEntry, error) {
+	entry := RFCIndexRFCEntry{DocID: docID}
+
+	head := body
+	if loc := rfcIndexTextGroupPattern.FindStringIndex(body); loc != nil {
+		head = strings.TrimSpace(body[:loc[0]])
+	}
+
+	dates := rfcIndexTextDatePattern.FindAllStringSubmatchIndex(head, -1)
+	if len(dates) == 0 {
+		return nil, fmt.Errorf("cannot find the publication date of %s", docID)
+	}
+	date := dates[len(dates)-1]
+
+	if date[2] >= 0 {
+		day, _ := strconv.Atoi(head[date[2]:date[3]])
+		entry.Date.Day = RFCIndexDayOfMonth(day)
+	}
+	entry.Date.Month = RFCIndexMonthName(head[date[4]:date[5]])
+	entry.Date.Year, _ = strconv.Atoi(head[date[6]:date[7]])
+
+	entry.Title, entry.Authors = splitRFCIndexTextTitleAndAuthors(strings.TrimSpace(head[:date[0]]))
+
+	for _, group := range rfcIndexTextGroupPattern.FindAllStringSubmatch(body, -1) {
+		key, value := group[1], strings.TrimSpace(group[2])
+
+		switch key {
+		case "Format":
+			entry.Formats = parseRFCIndexTextFormats(value)
+		case "Obsoletes":
+			entry.Obsoletes = parseRFCIndexTextDocumentRef(value)
+		case "Obsoleted by":
+			entry.ObsoletedBy = parseRFCIndexTextDocumentRef(value)
+		case "Updates":
+			entry.Updates = parseRFCIndexTextDocumentRef(value)
+		case "Updated by":
+			entry.UpdatedBy = parseRFCIndexTextDocumentRef(value)
+		case "Also":
+			entry.IsAlso = parseRFCIndexTextDocumentRef(value)
+		case "See Also":
+			entry.SeeAlso = parseRFCIndexTextDocumentRef(value)
+		case "Status":
+			entry.CurrentStatus = RFCIndexStatus(value)
+		case "Stream":
+			parseRFCIndexTextStream(&entry, value)
+		case "DOI":
+			entry.DOI = strings.Replace(value, " ", "", -1)
+		}
+	}
+
+	return &entry, nil
+}
+
+// splitRFCIndexTextTitleAndAuthors splits "Title. A. Author, B. Author, Ed.."
+// at the first period followed by something that looks like an initial.
+func splitRFCIndexTextTitleAndAuthors(s string) (string, []RFCIndexAuthor) {
+	s = strings.TrimSuffix(s, ".")
+
+	loc := rfcIndexTextAuthorStart.FindStringIndex(s)
+	if loc == nil {
+		i := strings.LastIndex(s, ". ")
+		if i < 0 {
+			return s, nil
+		}
+		loc = []int{i, i + 1}
+	}
+
+	title := s[:loc[0]]
+	authors := strings.TrimSpace(s[loc[0]+1:])
+
+	var result []RFCIndexAuthor
+	for _, name := range strings.Split(authors, ", ") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		if name == "Ed." || name == "Ed" {
+			if len(result) > 0 {
+				result[len(result)-1].Title = "Editor"
+			}
+			continue
+		}
+
+		result = append(result, RFCIndexAuthor{Name: name})
+	}
+
+	return title, result
+}
+
+func parseRFCIndexTextFormats(value string) []RFCIndexFormat {
+	var formats []RFCIndexFormat
+
+	value = strings.TrimSuffix(value, "bytes")
+
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		var format RFCIndexFormat
+
+		name := field
+		if i := strings.IndexByte(field, '='); i >= 0 {
+			name = field[:i]
+			format.CharCount, _ = strconv.Atoi(strings.TrimSpace(field[i+1:]))
+		}
+
+		switch name = strings.TrimSpace(name); name {
+		case "TXT":
+			format.FileFormat = "ASCII"
+		default:
+			format.FileFormat = RFCIndexFileFormat(name)
+		}
+
+		formats = append(formats, format)
+	}
+
+	return formats
+}
+
+func parseRFCIndexTextDocumentRef(value string) *RFCIndexDocumentRef {
+	var ref RFCIndexDocumentRef
+
+	for _, field := range strings.Split(value, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			ref.DocIDs = append(ref.DocIDs, RFCIndexDocumentID(field))
+		}
+	}
+
+	if len(ref.DocIDs) == 0 {
+		return nil
+	}
+
+	return &ref
+}
+
+// parseRFCIndexTextStream parses "IETF, Area: art, WG: httpbis".
+func parseRFCIndexTextStream(entry *RFCIndexRFCEntry, value string) {
+	for i, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+
+		if i == 0 {
+			entry.Stream = RFCIndexStream(field)
+		} else if strings.HasPrefix(field, "Area: ") {
+			entry.Area = strings.TrimPrefix(field, "Area: ")
+		} else if strings.HasPrefix(field, "WG: ") {
+			entry.WgAcronym = strings.TrimPrefix(field, "WG: ")
+		}
+	}
+}
+
+func (i *RFCIndex) addSeriesEntriesFromRFCEntries() {
+	stdEntries := make(map[RFCIndexDocumentID]*RFCIndexSTDEntry)
+	bcpEntries := make(map[RFCIndexDocumentID]*RFCIndexBCPEntry)
+	fyiEntries := make(map[RFCIndexDocumentID]*RFCIndexFYIEntry)
+
+	for _, entry := range i.RFCEntries {
+		if entry.IsAlso == nil {
+			continue
+		}
+
+		for _, docID := range entry.IsAlso.DocIDs {
+			switch {
+			case strings.HasPrefix(string(docID), "STD"):
+				if stdEntries[docID] == nil {
+					stdEntries[docID] = &RFCIndexSTDEntry{DocID: docID, IsAlso: &RFCIndexDocumentRef{}}
+					i.STDEntries = append(i.STDEntries, stdEntries[docID])
+				}
+				stdEntries[docID].IsAlso.DocIDs = append(stdEntries[docID].IsAlso.DocIDs, entry.DocID)
+			case strings.HasPrefix(string(docID), "BCP"):
+				if bcpEntries[docID] == nil {
+					bcpEntries[docID] = &RFCIndexBCPEntry{DocID: docID, IsAlso: &RFCIndexDocumentRef{}}
+					i.BCPEntries = append(i.BCPEntries, bcpEntries[docID])
+				}
+				bcpEntries[docID].IsAlso.DocIDs = append(bcpEntries[docID].IsAlso.DocIDs, entry.DocID)
+			case strings.HasPrefix(string(docID), "FYI"):
+				if fyiEntries[docID] == nil {
+					fyiEntries[docID] = &RFCIndexFYIEntry{DocID: docID, IsAlso: &RFCIndexDocumentRef{}}
+					i.FYIEntries = append(i.FYIEntries, fyiEntries[docID])
+				}
+				fyiEntries[docID].IsAlso.DocIDs = append(fyiEntries[docID].IsAlso.DocIDs, entry.DocID)
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+// withoutXMLOnlyFields clears the fields of an entry that rfc-index.txt does
+// not carry.
+func withoutXMLOnlyFields(entry *RFCIndexRFCEntry) *RFCIndexRFCEntry {
+	copied := *entry
+	copied.Keywords = nil
+	copied.Abstract = nil
+	copied.Draft = ""
+	copied.Notes = ""
+	copied.PublicationStatus = ""
+	copied.ErrataURL = ""
+
+	copied.Authors = nil
+	for _, author := range entry.Authors {
+		copied.Authors = append(copied.Authors, RFCIndexAuthor{Name: author.Name, Title: author.Title})
+	}
+
+	return &copied
+}
+
+func TestParseRFCIndexTextMatchesXML(t *testing.T) {
+	xmlDoc, err := ioutil.ReadFile("testdata/rfc-index.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	textDoc, err := ioutil.ReadFile("testdata/rfc-index.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := ParseRFCIndex(xmlDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ParseRFCIndexText(textDoc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got.RFCEntries) != len(want.RFCEntries) {
+		t.Fatalf("got %d RFC entries, want %d", len(got.RFCEntries), len(want.RFCEntries))
+	}
+	for i, entry := range want.RFCEntries {
+		if expected := withoutXMLOnlyFields(entry); !reflect.DeepEqual(got.RFCEntries[i], expected) {
+			t.Errorf("%s:\ngot  %+v\nwant %+v", entry.DocID, got.RFCEntries[i], expected)
+		}
+	}
+
+	if !reflect.DeepEqual(got.RFCNotIssuedEntries, want.RFCNotIssuedEntries) {
+		t.Errorf("got not issued entries %+v, want %+v", got.RFCNotIssuedEntries, want.RFCNotIssuedEntries)
+	}
+
+	// The text index has no series entries of their own, so they have no
+	// titles.
+	for _, entry := range want.STDEntries {
+		if other := got.STDEntry(entry.DocID); other == nil || !reflect.DeepEqual(other.IsAlso, entry.IsAlso) {
+			t.Errorf("got %s entry %+v, want %+v", entry.DocID, other, entry)
+		}
+	}
+	for _, entry := range want.BCPEntries {
+		if other := got.BCPEntry(entry.DocID); other == nil || !reflect.DeepEqual(other.IsAlso, entry.IsAlso) {
+			t.Errorf("got %s entry %+v, want %+v", entry.DocID, other, entry)
+		}
+	}
+	if len(got.STDEntries) != len(want.STDEntries) || len(got.BCPEntries) != len(want.BCPEntries) || len(got.FYIEntries) != len(want.FYIEntries) {
+		t.Errorf("got %d STD, %d BCP and %d FYI entries, want %d, %d and %d", len(got.STDEntries), len(got.BCPEntries), len(got.FYIEntries), len(want.STDEntries), len(want.BCPEntries), len(want.FYIEntries))
+	}
+}
+
+func TestParseRFCIndexTextFiveDigitNumbers(t *testing.T) {
+	doc := []byte(`9999 Last Four Digit RFC. A. Author. December 2025. (Format: TXT=1000
+     bytes) (Status: INFORMATIONAL) (Stream: IETF) (DOI: 10.17487/RFC9999)
+
+10000 First Five Digit RFC. B. Author. January 2026. (Format: TXT=2000
+     bytes) (Status: INFORMATIONAL) (Stream: IETF) (DOI: 10.17487/RFC10000)
+
+10001 Not Issued.
+`)
+
+	rfcIndex, err := ParseRFCIndexText(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rfcIndex.RFCEntries) != 2 {
+		t.Fatalf("got %d RFC entries, want 2", len(rfcIndex.RFCEntries))
+	}
+	if entry := rfcIndex.RFCEntries[1]; entry.DocID != "RFC10000" || entry.Title != "First Five Digit RFC" {
+		t.Errorf("got entry %s %q", entry.DocID, entry.Title)
+	}
+	if len(rfcIndex.RFCNotIssuedEntries) != 1 || rfcIndex.RFCNotIssuedEntries[0].DocID != "RFC10001" {
+		t.Errorf("got not issued entries %+v", rfcIndex.RFCNotIssuedEntries)
+	}
+}
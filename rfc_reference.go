@@ -0,0 +1,348 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// rfcReferencePattern matches, in order of preference, "RFC 3986, Section 3",
+// "Section 3 of [RFC3986]" or "Section 3 of RFC 3986", "[RFC7231]" or any
+// other citation label, "RFC 7231", "Section 4.2" and "Appendix A.1".
+var rfcReferencePattern = regexp.MustCompile(
+	`RFC ?(\d{1,5}),? [Ss]ection (\d+(?:\.\d+)*)` +
+		`|[Ss]ection (\d+(?:\.\d+)*) of (?:\[([^\]\s]+)\]|RFC ?(\d{1,5}))` +
+		`|\[([A-Za-z][A-Za-z0-9./+_-]*)\]` +
+		`|\bRFC ?(\d{1,5})\b` +
+		`|\b[Ss]ection (\d+(?:\.\d+)*)` +
+		`|\bAppendix ([A-Z](?:\.\d+)*)\b`)
+
+var rfcReferenceEntryPattern = regexp.MustCompile(`^\s{2,}\[([^\]]+)\]\s+(.*)$`)
+
+var rfcReferenceEntryRFCPattern = regexp.MustCompile(`\bRFC ?(\d{1,5})\b`)
+
+// RFCReferenceTarget is another RFC if RFCNumber is set, and a section of the
+// document itself otherwise.
+type RFCReferenceTarget struct {
+	RFCNumber int
+	Section   string
+	Title     string
+}
+
+type RFCReference struct {
+	Line   int
+	Start  int
+	End    int
+	Text   string
+	Target RFCReferenceTarget
+}
+
+type RFCReferenceResolver struct {
+	RFCRepository RFCRepository
+}
+
+// Resolve finds the references in the document that can be resolved, either
+// to RFCs known to the repository or to sections of the document itself. If
+// no repository is set, references to any RFC number are resolved.
+func (r *RFCReferenceResolver) Resolve(doc *RFCDocument) []*RFCReference {
+	labels := referenceLabels(doc)
+	titles := make(map[int]string)
+
+	lookup := func(number int) (string, bool) {
+		if title, ok := titles[number]; ok {
+			return title, title != "" || r.RFCRepository == nil
+		}
+
+		title := ""
+		if r.RFCRepository != nil {
+			if rfc, err := r.RFCRepository.FindByNumber(number); err == nil && rfc != nil {
+				title = rfc.Title
+			}
+		}
+		titles[number] = title
+
+		return title, title != "" || r.RFCRepository == nil
+	}
+
+	external := func(number string, section string) (RFCReferenceTarget, bool) {
+		n, _ := strconv.Atoi(number)
+		title, ok := lookup(n)
+		return RFCReferenceTarget{RFCNumber: n, Section: section, Title: title}, ok
+	}
+
+	internal := func(section string) (RFCReferenceTarget, bool) {
+		if s := doc.Section(section); s != nil {
+			return RFCReferenceTarget{Section: section, Title: s.Title}, true
+		}
+		return RFCReferenceTarget{}, false
+	}
+
+	headings := make(map[int]bool)
+	for _, section := range doc.Sections {
+		headings[section.Line] = true
+	}
+
+	var refs []*RFCReference
+
+	// A reference to an RFC at the end of a line may continue with the section
+	// on the next one, as in "RFC 3986,\n   Section 3".
+	continued := 0
+
+	for i, line := range doc.Lines {
+		if headings[i] {
+			continued = 0
+			continue
+		}
+
+		next := 0
+
+		matches := rfcReferencePattern.FindAllStringSubmatchIndex(line, -1)
+		for j, m := range matches {
+			// Part of a DOI or URL rather than a reference.
+			if m[0] > 0 && line[m[0]-1] == '/' {
+				continue
+			}
+
+			group := func(n int) string {
+				if m[2*n] < 0 {
+					return ""
+				}
+				return line[m[2*n]:m[2*n+1]]
+			}
+
+			var target RFCReferenceTarget
+			var ok bool
+
+			switch {
+			case group(1) != "":
+				target, ok = external(group(1), group(2))
+			case group(3) != "" && group(5) != "":
+				target, ok = external(group(5), group(3))
+			case group(3) != "":
+				if number, found := labels[group(4)]; found {
+					target, ok = external(strconv.Itoa(number), group(3))
+				}
+			case group(6) != "":
+				if number, found := labels[group(6)]; found {
+					target, ok = external(strconv.Itoa(number), "")
+				}
+			case group(7) != "":
+				target, ok = external(group(7), "")
+			case group(8) != "" && j == 0 && continued != 0 && strings.TrimSpace(line[:m[0]]) == "":
+				target, ok = external(strconv.Itoa(continued), group(8))
+			case group(8) != "":
+				target, ok = internal(group(8))
+			case group(9) != "":
+				target, ok = internal(group(9))
+			}
+
+			if !ok {
+				continue
+			}
+
+			if j == len(matches)-1 && group(7) != "" && strings.TrimRight(line[m[1]:], ",") == "" {
+				next = target.RFCNumber
+			}
+
+			refs = append(refs, &RFCReference{
+				Line:   i,
+				Start:  m[0],
+				End:    m[1],
+				Text:   line[m[0]:m[1]],
+				Target: target,
+			})
+		}
+
+		continued = next
+	}
+
+	return refs
+}
+
+// referenceLabels maps citation labels such as "RFC7231" or "HTTP" to the
+// RFC the entry in the references sections cites.
+func referenceLabels(doc *RFCDocument) map[string]int {
+	labels := make(map[string]int)
+
+	var label string
+	for _, line := range doc.Lines {
+		if match := rfcReferenceEntryPattern.FindStringSubmatch(line); match != nil {
+			label = match[1]
+			line = match[2]
+		} else if strings.TrimSpace(line) == "" {
+			label = ""
+			continue
+		}
+
+		if label == "" {
+			continue
+		}
+
+		if _, found := labels[label]; found {
+			continue
+		}
+
+		if match := rfcReferenceEntryRFCPattern.FindStringSubmatch(line); match != nil {
+			number, _ := strconv.Atoi(match[1])
+			labels[label] = number
+		}
+	}
+
+	for _, match := range rfcReferencePattern.FindAllStringSubmatch(strings.Join(doc.Lines, "\n"), -1) {
+		if label := match[6]; strings.HasPrefix(label, "RFC") {
+			if number, err := strconv.Atoi(label[3:]); err == nil {
+				labels[label] = number
+			}
+		}
+	}
+
+	return labels
+}
+
+type RFCReferenceStyle int
+
+const (
+	RFCReferenceStyleNone RFCReferenceStyle = iota
+	RFCReferenceStyleOSC8
+	RFCReferenceStyleFootnotes
+	RFCReferenceStyleHTML
+)
+
+func toRFCReferenceStyle(style string) (RFCReferenceStyle, error) {
+	switch style {
+	case "none":
+		return RFCReferenceStyleNone, nil
+	case "osc8":
+		return RFCReferenceStyleOSC8, nil
+	case "footnotes":
+		return RFCReferenceStyleFootnotes, nil
+	case "html":
+		return RFCReferenceStyleHTML, nil
+	}
+	return RFCReferenceStyle(0), fmt.Errorf("unknown link style: %s", style)
+}
+
+type RFCReferenceRenderer struct {
+	Style RFCReferenceStyle
+
+	// RFCURLFormat is formatted with an RFC number to link to another RFC.
+	RFCURLFormat string
+
+	// Number is the number of the document being rendered, used to link to
+	// its own sections outside of HTML.
+	Number int
+}
+
+const defaultRFCURLFormat = "https://www.rfc-editor.org/rfc/rfc%d"
+
+func (r *RFCReferenceRenderer) URLFor(target RFCReferenceTarget) string {
+	format := r.RFCURLFormat
+	if format == "" {
+		format = defaultRFCURLFormat
+	}
+
+	var u string
+	if target.RFCNumber != 0 {
+		u = fmt.Sprintf(format, target.RFCNumber)
+	} else if r.Style != RFCReferenceStyleHTML {
+		u = fmt.Sprintf(format, r.Number)
+	}
+
+	if target.Section != "" {
+		u += "#" + rfcSectionAnchor(target.Section)
+	}
+
+	return u
+}
+
+// Render returns the document with references rendered in the style of the
+// renderer. For HTML, the text is escaped and section headings get anchors.
+func (r *RFCReferenceRenderer) Render(doc *RFCDocument, refs []*RFCReference) string {
+	byLine := make(map[int][]*RFCReference)
+	for _, ref := range refs {
+		byLine[ref.Line] = append(byLine[ref.Line], ref)
+	}
+
+	headings := make(map[int]*RFCSection)
+	for _, section := range doc.Sections {
+		headings[section.Line] = section
+	}
+
+	var footnotes []string
+	footnoteNumbers := make(map[string]int)
+
+	var b strings.Builder
+
+	for i, line := range doc.Lines {
+		if section := headings[i]; section != nil && r.Style == RFCReferenceStyleHTML {
+			fmt.Fprintf(&b, `<span id="%s"></span>`, section.Anchor())
+		}
+
+		pos := 0
+		for _, ref := range byLine[i] {
+			b.WriteString(r.escape(line[pos:ref.Start]))
+
+			u := r.URLFor(ref.Target)
+
+			switch r.Style {
+			case RFCReferenceStyleOSC8:
+				fmt.Fprintf(&b, "\x1b]8;;%s\x1b\\%s\x1b]8;;\x1b\\", u, ref.Text)
+			case RFCReferenceStyleHTML:
+				fmt.Fprintf(&b, `<a href="%s">%s</a>`, html.EscapeString(u), html.EscapeString(ref.Text))
+			case RFCReferenceStyleFootnotes:
+				n, ok := footnoteNumbers[u]
+				if !ok {
+					footnotes = append(footnotes, footnoteText(ref.Target, u))
+					n = len(footnotes)
+					footnoteNumbers[u] = n
+				}
+				fmt.Fprintf(&b, "%s[%d]", ref.Text, n)
+			default:
+				b.WriteString(ref.Text)
+			}
+
+			pos = ref.End
+		}
+
+		b.WriteString(r.escape(line[pos:]))
+		b.WriteString("\n")
+	}
+
+	if len(footnotes) > 0 {
+		b.WriteString("\nLinks\n\n")
+		for i, footnote := range footnotes {
+			fmt.Fprintf(&b, "   [%d] %s\n", i+1, footnote)
+		}
+	}
+
+	return b.String()
+}
+
+func (r *RFCReferenceRenderer) escape(s string) string {
+	if r.Style == RFCReferenceStyleHTML {
+		return html.EscapeString(s)
+	}
+	return s
+}
+
+func footnoteText(target RFCReferenceTarget, u string) string {
+	var name string
+	if target.RFCNumber != 0 {
+		name = fmt.Sprintf("RFC %d", target.RFCNumber)
+		if target.Section != "" {
+			name += ", Section " + target.Section
+		}
+	} else if rfcSectionAnchor(target.Section)[0] == 'a' {
+		name = "Appendix " + target.Section
+	} else {
+		name = "Section " + target.Section
+	}
+
+	if target.Title != "" {
+		name += ` "` + target.Title + `"`
+	}
+
+	return name + " <" + u + ">"
+}
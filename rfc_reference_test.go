@@ -0,0 +1,72 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRFCReferenceResolverResolve(t *testing.T) {
+	doc := ParseRFCDocument([]byte(`1.  Introduction
+
+   See RFC 3986, Section 3 and Section 4.2 of [HTTP]. Section 2
+   defines terms, as does Appendix A. Compare [RFC7231] with RFC 2616,
+   and with RFC 9112,
+   Section 6. Section 9 does not exist. See [UNKNOWN] and
+   https://doi.org/10.17487/RFC9110.
+
+2.  Terminology
+
+   Nothing.
+
+Appendix A.  Collected ABNF
+
+   Nothing.
+
+3.  Normative References
+
+   [HTTP]     Fielding, R., "HTTP Semantics", STD 97, RFC 9110.
+`))
+
+	resolver := RFCReferenceResolver{}
+
+	var got []string
+	for _, ref := range resolver.Resolve(doc) {
+		if ref.Line >= doc.Sections[1].Line {
+			break
+		}
+		got = append(got, ref.Text+" -> "+footnoteText(ref.Target, (&RFCReferenceRenderer{Number: 9999}).URLFor(ref.Target)))
+	}
+
+	want := []string{
+		`RFC 3986, Section 3 -> RFC 3986, Section 3 <https://www.rfc-editor.org/rfc/rfc3986#section-3>`,
+		`Section 4.2 of [HTTP] -> RFC 9110, Section 4.2 <https://www.rfc-editor.org/rfc/rfc9110#section-4.2>`,
+		`Section 2 -> Section 2 "Terminology" <https://www.rfc-editor.org/rfc/rfc9999#section-2>`,
+		`Appendix A -> Appendix A "Collected ABNF" <https://www.rfc-editor.org/rfc/rfc9999#appendix-A>`,
+		`[RFC7231] -> RFC 7231 <https://www.rfc-editor.org/rfc/rfc7231>`,
+		`RFC 2616 -> RFC 2616 <https://www.rfc-editor.org/rfc/rfc2616>`,
+		`RFC 9112 -> RFC 9112 <https://www.rfc-editor.org/rfc/rfc9112>`,
+		`Section 6 -> RFC 9112, Section 6 <https://www.rfc-editor.org/rfc/rfc9112#section-6>`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got references\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestRFCReferenceResolverResolveKnownRFCs(t *testing.T) {
+	rfcIndex, err := ParseRFCIndex([]byte(testRFCIndexAfter))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc := ParseRFCDocument([]byte("1.  Introduction\n\n   RFC 9110 replaces RFC 2616 but not RFC 1234.\n"))
+	resolver := RFCReferenceResolver{RFCRepository: &RFCIndexRFCRepository{RFCIndex: rfcIndex}}
+
+	var got []int
+	for _, ref := range resolver.Resolve(doc) {
+		got = append(got, ref.Target.RFCNumber)
+	}
+	if want := []int{9110, 2616}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got references to %v, want %v", got, want)
+	}
+}
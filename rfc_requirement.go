@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var bcp14KeywordPattern = regexp.MustCompile(`\b(MUST NOT|SHALL NOT|SHOULD NOT|NOT RECOMMENDED|MUST|SHALL|REQUIRED|SHOULD|RECOMMENDED|MAY|OPTIONAL)\b`)
+
+// The paragraph that defines the keywords is not a requirement itself.
+var bcp14BoilerplatePattern = regexp.MustCompile(`(?:BCP ?14|RFC ?2119).*interpreted|interpreted as described in`)
+
+type RFCRequirementLevel int
+
+const (
+	RFCRequirementLevelMay RFCRequirementLevel = iota
+	RFCRequirementLevelShouldNot
+	RFCRequirementLevelShould
+	RFCRequirementLevelMustNot
+	RFCRequirementLevelMust
+)
+
+func toRFCRequirementLevel(keyword string) RFCRequirementLevel {
+	switch keyword {
+	case "MUST", "SHALL", "REQUIRED":
+		return RFCRequirementLevelMust
+	case "MUST NOT", "SHALL NOT":
+		return RFCRequirementLevelMustNot
+	case "SHOULD", "RECOMMENDED":
+		return RFCRequirementLevelShould
+	case "SHOULD NOT", "NOT RECOMMENDED":
+		return RFCRequirementLevelShouldNot
+	}
+	return RFCRequirementLevelMay
+}
+
+func (l RFCRequirementLevel) String() string {
+	switch l {
+	case RFCRequirementLevelMust:
+		return "MUST"
+	case RFCRequirementLevelMustNot:
+		return "MUST NOT"
+	case RFCRequirementLevelShould:
+		return "SHOULD"
+	case RFCRequirementLevelShouldNot:
+		return "SHOULD NOT"
+	}
+	return "MAY"
+}
+
+func (l RFCRequirementLevel) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// RFCRequirement is a sentence containing BCP 14 keywords. Its level is the
+// strongest of the keywords.
+type RFCRequirement struct {
+	ID           string              `json:"id"`
+	Section      string              `json:"section"`
+	SectionTitle string              `json:"section_title"`
+	Level        RFCRequirementLevel `json:"level"`
+	Keywords     []string            `json:"keywords"`
+	Text         string              `json:"text"`
+}
+
+// ExtractRFCRequirements returns the requirements in the sections of the
+// document. IDs are of the form "rfc9110#s8.8.3-req2", numbering the
+// requirements within each section, so that they stay the same as long as
+// the section is unchanged.
+func ExtractRFCRequirements(number int, doc *RFCDocument) []*RFCRequirement {
+	var requirements []*RFCRequirement
+
+	for _, section := range doc.Sections {
+		n := 0
+		for _, sentence := range splitSentences(doc.SectionLines(section)) {
+			keywords := bcp14KeywordPattern.FindAllString(sentence, -1)
+			if keywords == nil || bcp14BoilerplatePattern.MatchString(sentence) {
+				continue
+			}
+
+			level := RFCRequirementLevelMay
+			for _, keyword := range keywords {
+				if l := toRFCRequirementLevel(keyword); l > level {
+					level = l
+				}
+			}
+
+			n++
+			requirements = append(requirements, &RFCRequirement{
+				ID:           fmt.Sprintf("rfc%d#s%s-req%d", number, section.Number, n),
+				Section:      section.Number,
+				SectionTitle: section.Title,
+				Level:        level,
+				Keywords:     keywords,
+				Text:         sentence,
+			})
+		}
+	}
+
+	return requirements
+}
+
+var sentenceAbbreviations = map[string]bool{
+	"e.g.": true, "i.e.": true, "etc.": true, "al.": true,
+	"Ed.": true, "Eds.": true, "Sec.": true, "Vol.": true, "No.": true,
+	"vs.": true, "cf.": true, "Fig.": true,
+}
+
+// splitSentences joins the lines of each paragraph and splits it into
+// sentences at a period, question or exclamation mark followed by a space and
+// an upper case letter, a quote or a parenthesis.
+func splitSentences(lines []string) []string {
+	var sentences []string
+
+	var paragraphs []string
+	var paragraph []string
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			paragraph = append(paragraph, strings.TrimSpace(line))
+		}
+
+		if (strings.TrimSpace(line) == "" || i == len(lines)-1) && paragraph != nil {
+			paragraphs = append(paragraphs, strings.Join(paragraph, " "))
+			paragraph = nil
+		}
+	}
+
+	for _, text := range paragraphs {
+		words := strings.Fields(text)
+
+		start := 0
+		for i, word := range words {
+			if i == len(words)-1 {
+				sentences = append(sentences, strings.Join(words[start:], " "))
+				break
+			}
+
+			end := strings.TrimRight(word, `"')]`)
+			if end == "" || !strings.ContainsAny(end[len(end)-1:], ".?!") {
+				continue
+			}
+
+			if sentenceAbbreviations[end] || len(end) == 2 && end[0] >= 'A' && end[0] <= 'Z' {
+				continue
+			}
+
+			next := words[i+1][0]
+			if next >= 'A' && next <= 'Z' || next == '"' || next == '(' || next == '[' {
+				sentences = append(sentences, strings.Join(words[start:i+1], " "))
+				start = i + 1
+			}
+		}
+	}
+
+	return sentences
+}
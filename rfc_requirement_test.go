@@ -0,0 +1,78 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		lines []string
+		want  []string
+	}{
+		{
+			[]string{"   A sender MUST send it.  A recipient MAY ignore it."},
+			[]string{"A sender MUST send it.", "A recipient MAY ignore it."},
+		},
+		{
+			[]string{"   A sentence split", "   across lines.", "", "   Another paragraph"},
+			[]string{"A sentence split across lines.", "Another paragraph"},
+		},
+		{
+			[]string{"   See Section 3, e.g. The header. Defined by J. Postel in 1981."},
+			[]string{"See Section 3, e.g. The header.", "Defined by J. Postel in 1981."},
+		},
+		{
+			[]string{`   It is "quoted." (This is aside.) [RFC9110] applies. version 1.1 is lowercase.`},
+			[]string{`It is "quoted."`, "(This is aside.)", "[RFC9110] applies. version 1.1 is lowercase."},
+		},
+	}
+
+	for _, test := range tests {
+		if got := splitSentences(test.lines); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%q:\ngot  %q\nwant %q", test.lines, got, test.want)
+		}
+	}
+}
+
+func TestExtractRFCRequirements(t *testing.T) {
+	doc := ParseRFCDocument([]byte(`1.  Introduction
+
+   This is not a requirement.
+
+1.1.  Requirements Notation
+
+   The key words "MUST", "MUST NOT", "REQUIRED", "SHALL", "SHALL NOT",
+   "SHOULD", "SHOULD NOT", "RECOMMENDED", "NOT RECOMMENDED", "MAY", and
+   "OPTIONAL" in this document are to be interpreted as described in
+   BCP 14 [RFC2119] [RFC8174] when, and only when, they appear in all
+   capitals, as shown here.
+
+8.8.3.  ETag
+
+   A server MAY send an ETag. A sender SHOULD NOT generate it
+   twice. Recipients must accept it. A client MUST NOT send it, but MAY
+   ignore it. A proxy is NOT RECOMMENDED to change it.
+`))
+
+	type requirement struct {
+		id, section string
+		level       RFCRequirementLevel
+		keywords    []string
+	}
+
+	var got []requirement
+	for _, r := range ExtractRFCRequirements(9110, doc) {
+		got = append(got, requirement{r.ID, r.Section, r.Level, r.Keywords})
+	}
+
+	want := []requirement{
+		{"rfc9110#s8.8.3-req1", "8.8.3", RFCRequirementLevelMay, []string{"MAY"}},
+		{"rfc9110#s8.8.3-req2", "8.8.3", RFCRequirementLevelShouldNot, []string{"SHOULD NOT"}},
+		{"rfc9110#s8.8.3-req3", "8.8.3", RFCRequirementLevelMustNot, []string{"MUST NOT", "MAY"}},
+		{"rfc9110#s8.8.3-req4", "8.8.3", RFCRequirementLevelShouldNot, []string{"NOT RECOMMENDED"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got requirements\n%+v\nwant\n%+v", got, want)
+	}
+}
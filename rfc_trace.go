@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// rfcTracePattern matches requirement IDs such as "rfc9110#s8.8.3-req2",
+// section anchors such as "rfc9110#s8.8.3" and "rfc9110#section-8.8.3",
+// "RFC 9110, Section 8.8.3", "[RFC9110] Section 8.8.3" and "Section 8.8.3 of
+// RFC 9110". Only "RFC" is matched regardless of case: section numbers start
+// with a digit, or an uppercase letter for appendices.
+var rfcTracePattern = regexp.MustCompile(
+	`\b(?i:rfc)(\d{1,5})(?:\.html)?#(?:s|section-)(` + rfcTraceSectionPattern + `)(?:-req(\d+))?\b` +
+		`|\[?\b(?i:rfc) ?(\d{1,5})\]?,? [Ss]ection (` + rfcTraceSectionPattern + `)\b` +
+		`|\b[Ss]ection (` + rfcTraceSectionPattern + `) of (?i:rfc) ?(\d{1,5})\b`)
+
+const rfcTraceSectionPattern = `\d+(?:\.\d+)*|[A-Z](?:\.\d+)*`
+
+type RFCTraceLocation struct {
+	Path string `json:"path"`
+	Line int    `json:"line"`
+}
+
+// RFCTraceReference is a reference to a requirement, if RequirementID is
+// set, or to a section of an RFC found in a source file.
+type RFCTraceReference struct {
+	RFCNumber     int              `json:"rfc"`
+	Section       string           `json:"section"`
+	RequirementID string           `json:"requirement_id,omitempty"`
+	Location      RFCTraceLocation `json:"location"`
+}
+
+// ScanRFCTraceReferences returns the references to the given RFC in the files
+// under the paths. Hidden directories and binary files are skipped.
+func ScanRFCTraceReferences(number int, paths []string) ([]*RFCTraceReference, error) {
+	var refs []*RFCTraceReference
+
+	for _, root := range paths {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() {
+				if path != root && strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+
+			found, err := scanRFCTraceFile(number, path)
+			if err != nil {
+				return err
+			}
+			refs = append(refs, found...)
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return refs, nil
+}
+
+func scanRFCTraceFile(number int, path string) ([]*RFCTraceReference, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	head := content
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, nil
+	}
+
+	var refs []*RFCTraceReference
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, len(content)+1)
+
+	for line := 1; scanner.Scan(); line++ {
+		for _, m := range rfcTracePattern.FindAllStringSubmatch(scanner.Text(), -1) {
+			ref := RFCTraceReference{Location: RFCTraceLocation{Path: path, Line: line}}
+
+			switch {
+			case m[1] != "":
+				ref.RFCNumber, _ = strconv.Atoi(m[1])
+				ref.Section = m[2]
+				if m[3] != "" {
+					ref.RequirementID = fmt.Sprintf("rfc%d#s%s-req%s", ref.RFCNumber, ref.Section, m[3])
+				}
+			case m[4] != "":
+				ref.RFCNumber, _ = strconv.Atoi(m[4])
+				ref.Section = m[5]
+			default:
+				ref.RFCNumber, _ = strconv.Atoi(m[7])
+				ref.Section = m[6]
+			}
+
+			if ref.RFCNumber == number {
+				refs = append(refs, &ref)
+			}
+		}
+	}
+
+	return refs, scanner.Err()
+}
+
+// Coverage of a requirement: referenced by its ID, referenced through its
+// section or one of the enclosing sections, or not referenced at all.
+const (
+	RFCTraceCovered        = "covered"
+	RFCTraceSectionCovered = "section"
+	RFCTraceNotCovered     = "none"
+)
+
+type RFCTraceRequirement struct {
+	*RFCRequirement
+	Coverage  string             `json:"coverage"`
+	Locations []RFCTraceLocation `json:"locations"`
+}
+
+type RFCTraceSection struct {
+	Number       string             `json:"number"`
+	Title        string             `json:"title"`
+	Requirements int                `json:"requirements"`
+	Covered      int                `json:"covered"`
+	Locations    []RFCTraceLocation `json:"locations"`
+}
+
+type RFCTraceReport struct {
+	RFCNumber           int                    `json:"rfc"`
+	Requirements        []*RFCTraceRequirement `json:"requirements"`
+	Sections            []*RFCTraceSection     `json:"sections"`
+	Unresolved          []*RFCTraceReference   `json:"unresolved"`
+	CoveredCount        int                    `json:"covered"`
+	SectionCoveredCount int                    `json:"section_covered"`
+}
+
+// BuildRFCTraceReport matches the references against the requirements and
+// sections of the document. References to requirements or sections that do
+// not exist in the document are reported as unresolved.
+func BuildRFCTraceReport(number int, doc *RFCDocument, refs []*RFCTraceReference) *RFCTraceReport {
+	report := RFCTraceReport{
+		RFCNumber:    number,
+		Requirements: []*RFCTraceRequirement{},
+		Sections:     []*RFCTraceSection{},
+		Unresolved:   []*RFCTraceReference{},
+	}
+
+	sections := make(map[string]*RFCTraceSection)
+	for _, section := range doc.Sections {
+		s := &RFCTraceSection{Number: section.Number, Title: section.Title, Locations: []RFCTraceLocation{}}
+		sections[section.Number] = s
+		report.Sections = append(report.Sections, s)
+	}
+
+	requirements := make(map[string]*RFCTraceRequirement)
+	for _, requirement := range ExtractRFCRequirements(number, doc) {
+		r := &RFCTraceRequirement{RFCRequirement: requirement, Coverage: RFCTraceNotCovered, Locations: []RFCTraceLocation{}}
+		requirements[requirement.ID] = r
+		report.Requirements = append(report.Requirements, r)
+		sections[requirement.Section].Requirements++
+	}
+
+	for _, ref := range refs {
+		if ref.RequirementID != "" {
+			if r := requirements[ref.RequirementID]; r != nil {
+				r.Coverage = RFCTraceCovered
+				r.Locations = append(r.Locations, ref.Location)
+				continue
+			}
+		} else if s := sections[ref.Section]; s != nil {
+			s.Locations = append(s.Locations, ref.Location)
+			continue
+		}
+
+		report.Unresolved = append(report.Unresolved, ref)
+	}
+
+	for _, r := range report.Requirements {
+		if r.Coverage == RFCTraceNotCovered {
+			for number := r.Section; number != ""; number = parentSectionNumber(number) {
+				if s := sections[number]; s != nil && len(s.Locations) > 0 {
+					r.Coverage = RFCTraceSectionCovered
+					break
+				}
+			}
+		}
+
+		switch r.Coverage {
+		case RFCTraceCovered:
+			report.CoveredCount++
+		case RFCTraceSectionCovered:
+			report.SectionCoveredCount++
+		}
+
+		if r.Coverage != RFCTraceNotCovered {
+			sections[r.Section].Covered++
+		}
+	}
+
+	return &report
+}
+
+func parentSectionNumber(number string) string {
+	if i := strings.LastIndex(number, "."); i >= 0 {
+		return number[:i]
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScanRFCTraceReferences(t *testing.T) {
+	tests := []struct {
+		line        string
+		section     string
+		requirement string
+	}{
+		{line: "// RFC 9110, Section 8.6", section: "8.6"},
+		{line: "// [RFC9110] Section 4.2.1", section: "4.2.1"},
+		{line: "// see https://www.rfc-editor.org/rfc/rfc9110#section-8.6", section: "8.6"},
+		{line: "// see rfc9110.html#section-15.4.9", section: "15.4.9"},
+		{line: "// Section 5.1 of RFC 9110", section: "5.1"},
+		{line: "// rfc9110 section 3", section: "3"},
+		{line: "// Rfc9110#sA.2", section: "A.2"},
+		{line: "// rfc9110#s8.8.3-req2", section: "8.8.3", requirement: "rfc9110#s8.8.3-req2"},
+		{line: "// RFC 9110 section is cool"},
+		{line: "// RFC 9110 Section Ix"},
+		{line: "// rfc9110#sections"},
+		{line: "// RFC 9112, Section 2"},
+		{line: "// RFC 91100, Section 2"},
+	}
+
+	dir, err := ioutil.TempDir("", "rfcs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, test := range tests {
+		path := filepath.Join(dir, "source.go")
+		if err := ioutil.WriteFile(path, []byte(test.line+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		refs, err := ScanRFCTraceReferences(9110, []string{dir})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if test.section == "" {
+			if len(refs) != 0 {
+				t.Errorf("%q: got section %q, want no reference", test.line, refs[0].Section)
+			}
+			continue
+		}
+
+		if len(refs) != 1 {
+			t.Errorf("%q: got %d references, want 1", test.line, len(refs))
+			continue
+		}
+		if refs[0].Section != test.section || refs[0].RequirementID != test.requirement {
+			t.Errorf("%q: got section %q and requirement %q, want %q and %q", test.line, refs[0].Section, refs[0].RequirementID, test.section, test.requirement)
+		}
+		if refs[0].Location.Line != 1 || refs[0].Location.Path != path {
+			t.Errorf("%q: got location %+v", test.line, refs[0].Location)
+		}
+	}
+}
@@ -0,0 +1,471 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Server struct {
+	RFCRepository        RFCRepository
+	RFCContentRepository RFCContentRepository
+}
+
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(s.route)
+}
+
+func (s *Server) route(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(path) == 1 && path[0] == "":
+		s.handleIndexPage(w, r)
+	case len(path) == 2 && path[0] == "rfc":
+		s.handleRFCPage(w, r, path[1])
+	case len(path) == 3 && path[0] == "diff":
+		s.handleDiffPage(w, r, path[1], path[2])
+	case len(path) == 2 && path[0] == "api" && path[1] == "rfcs":
+		s.handleListRFCs(w, r)
+	case len(path) == 2 && path[0] == "api" && path[1] == "search":
+		s.handleSearch(w, r)
+	case len(path) == 3 && path[0] == "api" && path[1] == "rfcs":
+		s.handleShowRFC(w, r, path[2])
+	case len(path) == 4 && path[0] == "api" && path[1] == "rfcs" && path[3] == "content":
+		s.handleRFCContent(w, r, path[2])
+	case len(path) == 4 && path[0] == "api" && path[1] == "rfcs" && path[3] == "graph":
+		s.handleRFCGraph(w, r, path[2])
+	case strings.HasPrefix(r.URL.Path, "/api/"):
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("not found: %s", r.URL.Path))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) handleListRFCs(w http.ResponseWriter, r *http.Request) {
+	rfcs, err := s.selectRFCs(r.URL.Query())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nonNilRFCs(rfcs))
+}
+
+func (s *Server) handleShowRFC(w http.ResponseWriter, r *http.Request, number string) {
+	rfc, ok := s.findRFC(w, number)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rfc)
+}
+
+func (s *Server) handleRFCContent(w http.ResponseWriter, r *http.Request, numberParam string) {
+	number, err := strconv.Atoi(numberParam)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	content, err := s.RFCContentRepository.FindByNumber(number)
+	if err != nil {
+		writeJSONError(w, contentErrorStatus(err), err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write(content)
+}
+
+func (s *Server) handleRFCGraph(w http.ResponseWriter, r *http.Request, numberParam string) {
+	number, err := strconv.Atoi(numberParam)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	depth := 1
+	if v := r.URL.Query().Get("depth"); v != "" {
+		if depth, err = strconv.Atoi(v); err != nil || depth < 0 || depth > 5 {
+			writeJSONError(w, http.StatusBadRequest, fmt.Errorf("depth must be between 0 and 5"))
+			return
+		}
+	}
+
+	graph, err := BuildRFCGraph(s.RFCRepository, number, depth)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	} else if graph == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("RFC %d not found", number))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, graph)
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	if strings.TrimSpace(query) == "" {
+		writeJSONError(w, http.StatusBadRequest, fmt.Errorf("missing query parameter: q"))
+		return
+	}
+
+	rfcs, err := s.RFCRepository.Search(query)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, nonNilRFCs(rfcs))
+}
+
+func (s *Server) handleIndexPage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	data := struct {
+		Query      url.Values
+		Categories []string
+		RFCs       []*RFC
+		Error      string
+		Limit      int
+		Total      int
+		Search     bool
+	}{Query: query, Categories: rfcCategoryNames, Limit: 500}
+
+	if len(query) > 0 {
+		rfcs, err := s.selectRFCs(query)
+		if err != nil {
+			data.Error = err.Error()
+		}
+		data.Search = true
+		data.Total = len(rfcs)
+		if len(rfcs) > data.Limit {
+			rfcs = rfcs[:data.Limit]
+		}
+		data.RFCs = rfcs
+	}
+
+	renderHTML(w, http.StatusOK, indexPageTemplate, data)
+}
+
+func (s *Server) handleRFCPage(w http.ResponseWriter, r *http.Request, numberParam string) {
+	number, err := strconv.Atoi(numberParam)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	rfc, err := s.RFCRepository.FindByNumber(number)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Number  int
+		RFC     *RFC
+		Content template.HTML
+		Error   string
+	}{Number: number, RFC: rfc}
+
+	content, err := s.RFCContentRepository.FindByNumber(number)
+	if err != nil {
+		data.Error = err.Error()
+	} else {
+		data.Content = s.linkRFCReferences(number, content)
+	}
+
+	status := http.StatusOK
+	if rfc == nil && content == nil {
+		status = http.StatusNotFound
+	}
+
+	renderHTML(w, status, rfcPageTemplate, data)
+}
+
+func (s *Server) findRFC(w http.ResponseWriter, numberParam string) (*RFC, bool) {
+	number, err := strconv.Atoi(numberParam)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+
+	rfc, err := s.RFCRepository.FindByNumber(number)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err)
+		return nil, false
+	} else if rfc == nil {
+		writeJSONError(w, http.StatusNotFound, fmt.Errorf("RFC %d not found", number))
+		return nil, false
+	}
+
+	return rfc, true
+}
+
+// selectRFCs applies the same filters as "rfcs list", named after its flags
+// with dashes replaced by underscores, plus a free text query q.
+func (s *Server) selectRFCs(query url.Values) ([]*RFC, error) {
+	var options SelectOptions
+
+	intParams := map[string]*int{
+		"obsoleted_by": &options.ObsoletedBy,
+		"obsolete":     &options.Obsolete,
+		"updated_by":   &options.UpdatedBy,
+		"update":       &options.Update,
+		"std":          &options.STDNumber,
+		"bcp":          &options.BCPNumber,
+		"fyi":          &options.FYINumber,
+	}
+	for name, value := range intParams {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %s", name, v)
+			}
+			*value = n
+		}
+	}
+
+	if v := query.Get("exclude_obsolete"); v != "" {
+		exclude, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude_obsolete: %s", v)
+		}
+		options.ExcludeObsolete = exclude
+	}
+
+	if v := query.Get("category"); v != "" {
+		category, err := toRFCCategory(v)
+		if err != nil {
+			return nil, err
+		}
+		options.Category = &category
+	}
+
+	if v := query.Get("stream"); v != "" {
+		stream, err := toRFCStream(v)
+		if err != nil {
+			return nil, err
+		}
+		options.Stream = &stream
+	}
+
+	options.WorkingGroup = query.Get("wg")
+
+	rfcs, err := SelectRFCs(s.RFCRepository, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if q := strings.TrimSpace(query.Get("q")); q != "" {
+		matches, err := s.RFCRepository.Search(q)
+		if err != nil {
+			return nil, err
+		}
+		rfcs = intersectRFCs(rfcs, matches)
+	}
+
+	if query.Get("sort") == "date" {
+		sort.Stable(ByPublicationDate(rfcs))
+	}
+
+	return rfcs, nil
+}
+
+func intersectRFCs(rfcs []*RFC, others []*RFC) []*RFC {
+	numbers := make(map[int]bool, len(others))
+	for _, rfc := range others {
+		numbers[rfc.Number] = true
+	}
+
+	var result []*RFC
+	for _, rfc := range rfcs {
+		if numbers[rfc.Number] {
+			result = append(result, rfc)
+		}
+	}
+	return result
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func renderHTML(w http.ResponseWriter, status int, tmpl *template.Template, data interface{}) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// nonNilRFCs makes empty results encode as [] rather than null.
+func nonNilRFCs(rfcs []*RFC) []*RFC {
+	if rfcs == nil {
+		return []*RFC{}
+	}
+	return rfcs
+}
+
+func (s *Server) handleDiffPage(w http.ResponseWriter, r *http.Request, oldParam string, newParam string) {
+	oldNumber, err := strconv.Atoi(oldParam)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	newNumber, err := strconv.Atoi(newParam)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	oldContent, err := s.RFCContentRepository.FindByNumber(oldNumber)
+	if err != nil {
+		http.Error(w, err.Error(), contentErrorStatus(err))
+		return
+	}
+
+	newContent, err := s.RFCContentRepository.FindByNumber(newNumber)
+	if err != nil {
+		http.Error(w, err.Error(), contentErrorStatus(err))
+		return
+	}
+
+	renderer := RFCDiffRenderer{
+		Format:  RFCDiffFormatHTML,
+		OldName: fmt.Sprintf("RFC %d", oldNumber),
+		NewName: fmt.Sprintf("RFC %d", newNumber),
+		Context: 1,
+	}
+
+	var buf bytes.Buffer
+	if err := renderer.Render(&buf, DiffRFCDocuments(ParseRFCDocument(oldContent), ParseRFCDocument(newContent))); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	buf.WriteTo(w)
+}
+
+// contentErrorStatus returns the HTTP status for an error finding the text
+// of an RFC.
+func contentErrorStatus(err error) int {
+	if err == ErrRFCContentNotCached || err == ErrRFCContentNotFound {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *Server) linkRFCReferences(number int, content []byte) template.HTML {
+	doc := ParseRFCDocument(content)
+
+	resolver := RFCReferenceResolver{RFCRepository: s.RFCRepository}
+	renderer := RFCReferenceRenderer{
+		Style:        RFCReferenceStyleHTML,
+		RFCURLFormat: "/rfc/%d",
+		Number:       number,
+	}
+
+	return template.HTML(renderer.Render(doc, resolver.Resolve(doc)))
+}
+
+const serverPageStyle = `
+body { font-family: sans-serif; margin: 2em auto; max-width: 60em; padding: 0 1em; }
+pre { font-size: 0.9em; }
+table { border-collapse: collapse; }
+td, th { padding: 0.2em 0.6em; text-align: left; vertical-align: top; }
+.obsolete { color: #888; }
+.error { color: #b00; }
+form input, form select { margin-right: 0.5em; }
+`
+
+var indexPageTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>RFCs</title>
+<style>` + serverPageStyle + `</style>
+</head>
+<body>
+<h1>RFCs</h1>
+<form action="/" method="get">
+<input type="search" name="q" value="{{.Query.Get "q"}}" placeholder="Search titles, keywords, numbers" size="40" autofocus>
+<select name="category">
+<option value="">Any category</option>
+{{$category := .Query.Get "category"}}
+{{range $c := .Categories}}<option value="{{$c}}"{{if eq $c $category}} selected{{end}}>{{$c}}</option>{{end}}
+</select>
+<label><input type="checkbox" name="exclude_obsolete" value="true"{{if .Query.Get "exclude_obsolete"}} checked{{end}}>Exclude obsolete</label>
+<input type="submit" value="Search">
+</form>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+{{if .Search}}
+<p>{{.Total}} RFCs{{if gt .Total .Limit}}, showing the first {{.Limit}}{{end}}</p>
+<table>
+{{range .RFCs}}
+<tr{{if .IsObsolete}} class="obsolete"{{end}}><td><a href="/rfc/{{.Number}}">{{.DocumentID}}</a></td><td>{{.Title}}</td><td>{{.PublicationDate}}</td><td>{{.Status}}</td></tr>
+{{end}}
+</table>
+{{end}}
+</body>
+</html>
+`))
+
+var rfcPageTemplate = template.Must(template.New("rfc").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>RFC {{.Number}}{{with .RFC}}: {{.Title}}{{end}}</title>
+<style>` + serverPageStyle + `</style>
+</head>
+<body>
+<p><a href="/">RFCs</a></p>
+{{with .RFC}}
+<h1>{{.DocumentID}}: {{.Title}}</h1>
+<table>
+<tr><th>Authors</th><td>{{range $i, $a := .Authors}}{{if $i}}, {{end}}{{$a}}{{end}}</td></tr>
+<tr><th>Published</th><td>{{.PublicationDate}}</td></tr>
+<tr><th>Status</th><td>{{.Status}}{{with .Stream}} ({{.}} stream){{end}}</td></tr>
+{{with .Series}}<tr><th>Also</th><td>{{range .}}{{.}} {{end}}</td></tr>{{end}}
+{{with .Obsoletes}}<tr><th>Obsoletes</th><td>{{range .}}<a href="/rfc/{{.}}">RFC {{.}}</a> {{end}}</td></tr>{{end}}
+{{with .ObsoletedBy}}<tr><th>Obsoleted by</th><td>{{range .}}<a href="/rfc/{{.}}">RFC {{.}}</a> {{end}}</td></tr>{{end}}
+{{with .Updates}}<tr><th>Updates</th><td>{{range .}}<a href="/rfc/{{.}}">RFC {{.}}</a> {{end}}</td></tr>{{end}}
+{{with .UpdatedBy}}<tr><th>Updated by</th><td>{{range .}}<a href="/rfc/{{.}}">RFC {{.}}</a> {{end}}</td></tr>{{end}}
+{{with .ErrataURL}}<tr><th>Errata</th><td><a href="{{.}}">{{.}}</a></td></tr>{{end}}
+</table>
+{{else}}
+<h1>RFC {{.Number}}</h1>
+{{end}}
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+{{if .Content}}<pre>{{.Content}}</pre>{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// testRFCContents serves the text of RFCs from memory.
+type testRFCContents map[int]string
+
+func (c testRFCContents) FindByNumber(number int) ([]byte, error) {
+	if number == 500 {
+		return nil, errors.New("broken cache")
+	}
+
+	content, ok := c[number]
+	if !ok {
+		return nil, ErrRFCContentNotCached
+	}
+	return []byte(content), nil
+}
+
+func newTestServer(t *testing.T) *httptest.Server {
+	rfcIndex, err := ParseRFCIndex(readRFCIndexFixture(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := Server{
+		RFCRepository: &RFCIndexRFCRepository{RFCIndex: rfcIndex},
+		RFCContentRepository: testRFCContents{
+			2616: "1. Introduction\n\n   HTTP/1.1 is defined here.\n\n2. Notation\n\n   As in RFC 2119.\n",
+			9110: "1. Introduction\n\n   HTTP semantics are defined here.\n\n2. Notation\n\n   As in RFC 2119.\n",
+		},
+	}
+
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestServerPages(t *testing.T) {
+	ts := newTestServer(t)
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/", http.StatusOK, "<h1>RFCs</h1>"},
+		{"/?q=http", http.StatusOK, `<a href="/rfc/9110">RFC9110</a>`},
+		{"/?category=nonsense", http.StatusOK, `class="error"`},
+		{"/rfc/9110", http.StatusOK, "RFC9110: HTTP Semantics"},
+		{"/rfc/9110", http.StatusOK, `<a href="/rfc/2119">RFC 2119</a>`},
+		{"/rfc/3986", http.StatusOK, "RFC is not cached"},
+		{"/rfc/1234", http.StatusNotFound, "<h1>RFC 1234</h1>"},
+		{"/rfc/x", http.StatusNotFound, ""},
+		{"/diff/2616/9110", http.StatusOK, "HTTP semantics are defined here."},
+		{"/diff/2616/1234", http.StatusNotFound, "RFC is not cached"},
+		{"/diff/2616/500", http.StatusInternalServerError, "broken cache"},
+		{"/diff/2616/x", http.StatusNotFound, ""},
+	}
+
+	for _, test := range tests {
+		response, err := http.Get(ts.URL + test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if response.StatusCode != test.status {
+			t.Errorf("%s: got status %d, want %d", test.path, response.StatusCode, test.status)
+		}
+		if !strings.Contains(string(body), test.body) {
+			t.Errorf("%s: got body without %q:\n%s", test.path, test.body, body)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+)
+
+// Terminal is only supported on platforms with stty(1).
+type Terminal struct{}
+
+func OpenTerminal() (*Terminal, error) {
+	return nil, errors.New("interactive mode is not supported on this platform")
+}
+
+func (t *Terminal) Read(p []byte) (int, error) {
+	return 0, errors.New("interactive mode is not supported on this platform")
+}
+
+func (t *Terminal) Write(p []byte) (int, error) {
+	return 0, errors.New("interactive mode is not supported on this platform")
+}
+
+func (t *Terminal) Size() (int, int, error) {
+	return 0, 0, errors.New("interactive mode is not supported on this platform")
+}
+
+func (t *Terminal) Close() error {
+	return nil
+}
@@ -0,0 +1,77 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Terminal is the controlling terminal, switched to raw mode with stty(1) so
+// that keys are read as they are pressed and not echoed.
+type Terminal struct {
+	tty   *os.File
+	state string
+}
+
+func OpenTerminal() (*Terminal, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	t := Terminal{tty: tty}
+
+	state, err := t.stty("-g")
+	if err != nil {
+		tty.Close()
+		return nil, err
+	}
+	t.state = strings.TrimSpace(state)
+
+	if _, err := t.stty("raw", "-echo"); err != nil {
+		tty.Close()
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func (t *Terminal) Read(p []byte) (int, error) {
+	return t.tty.Read(p)
+}
+
+func (t *Terminal) Write(p []byte) (int, error) {
+	return t.tty.Write(p)
+}
+
+// Size returns the number of columns and rows of the terminal.
+func (t *Terminal) Size() (int, int, error) {
+	size, err := t.stty("size")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var rows, columns int
+	if _, err := fmt.Sscan(size, &rows, &columns); err != nil {
+		return 0, 0, err
+	}
+
+	return columns, rows, nil
+}
+
+// Close restores the terminal to the state it was in when it was opened.
+func (t *Terminal) Close() error {
+	_, err := t.stty(t.state)
+	t.tty.Close()
+	return err
+}
+
+func (t *Terminal) stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = t.tty
+	out, err := cmd.Output()
+	return string(out), err
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WatchList is the list of RFCs a user tracks, stored one number per line.
+type WatchList struct {
+	Path string
+}
+
+func NewWatchList() *WatchList {
+	return &WatchList{}
+}
+
+func (l *WatchList) Load() ([]int, error) {
+	path, err := l.path()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var numbers []int
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		number, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid RFC number: %s", path, line)
+		}
+		numbers = append(numbers, number)
+	}
+
+	return numbers, scanner.Err()
+}
+
+func (l *WatchList) Save(numbers []int) error {
+	path, err := l.path()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	sort.Ints(numbers)
+
+	var buf bytes.Buffer
+	for _, number := range numbers {
+		fmt.Fprintf(&buf, "%d\n", number)
+	}
+
+	return WriteFileAtomic(path, buf.Bytes(), 0644)
+}
+
+// Add adds the RFCs not already on the list and returns the ones added.
+func (l *WatchList) Add(numbers ...int) ([]int, error) {
+	watched, err := l.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var added []int
+	for _, number := range numbers {
+		if !containsInt(watched, number) {
+			watched = append(watched, number)
+			added = append(added, number)
+		}
+	}
+
+	return added, l.Save(watched)
+}
+
+// Remove removes the RFCs from the list and returns the ones that were on it.
+func (l *WatchList) Remove(numbers ...int) ([]int, error) {
+	watched, err := l.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var kept, removed []int
+	for _, number := range watched {
+		if containsInt(numbers, number) {
+			removed = append(removed, number)
+		} else {
+			kept = append(kept, number)
+		}
+	}
+
+	return removed, l.Save(kept)
+}
+
+func (l *WatchList) path() (string, error) {
+	if l.Path != "" {
+		return l.Path, nil
+	}
+
+	if dir := GetUserConfigDirectory("rfcs"); dir != "" {
+		return filepath.Join(dir, "watchlist"), nil
+	}
+
+	return "", fmt.Errorf("cannot determine the config directory")
+}
+
+func containsInt(numbers []int, n int) bool {
+	for _, number := range numbers {
+		if number == n {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, s string) bool {
+	for _, value := range values {
+		if value == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// captureStdout returns what f writes to the standard output.
+func captureStdout(t *testing.T, f func()) []byte {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan []byte)
+	go func() {
+		output, _ := ioutil.ReadAll(r)
+		done <- output
+	}()
+
+	f()
+
+	w.Close()
+	return <-done
+}
+
+func TestWatchList(t *testing.T) {
+	list := &WatchList{Path: filepath.Join(newTestCacheDirectory(t), "rfcs", "watchlist")}
+
+	if numbers, err := list.Load(); err != nil || numbers != nil {
+		t.Errorf("got %v, %v from a missing watch list", numbers, err)
+	}
+
+	if added, err := list.Add(9110, 3986, 9110); err != nil || !reflect.DeepEqual(added, []int{9110, 3986}) {
+		t.Errorf("got added %v, %v", added, err)
+	}
+	if added, err := list.Add(3986, 8615); err != nil || !reflect.DeepEqual(added, []int{8615}) {
+		t.Errorf("got added %v, %v", added, err)
+	}
+	if removed, err := list.Remove(8615, 2616); err != nil || !reflect.DeepEqual(removed, []int{8615}) {
+		t.Errorf("got removed %v, %v", removed, err)
+	}
+
+	content, err := ioutil.ReadFile(list.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "3986\n9110\n" {
+		t.Errorf("got watch list file %q", content)
+	}
+
+	if err := ioutil.WriteFile(list.Path, []byte("# HTTP\n9110\n\n  3986  \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if numbers, err := list.Load(); err != nil || !reflect.DeepEqual(numbers, []int{9110, 3986}) {
+		t.Errorf("got %v, %v from an edited watch list", numbers, err)
+	}
+
+	if err := ioutil.WriteFile(list.Path, []byte("9110\nRFC3986\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := list.Load(); err == nil {
+		t.Error("got no error for an invalid RFC number")
+	}
+}
+
+func TestChangesCommandWatched(t *testing.T) {
+	store := &RFCIndexCacheStore{CacheDirectory: newTestCacheDirectory(t)}
+	if err := store.Put([]byte(testRFCIndexBefore), RFCIndexDataFormatXML); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.KeepPrevious(RFCIndexDataFormatXML); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put([]byte(testRFCIndexAfter), RFCIndexDataFormatXML); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		watched []int
+		numbers []int
+	}{
+		{watched: []int{2616, 8615}, numbers: []int{2616, 2616, 2616}},
+		{watched: []int{3986}, numbers: []int{3986}},
+		{watched: []int{8615}, numbers: nil},
+		{watched: []int{}, numbers: nil},
+	}
+
+	for _, test := range tests {
+		command := ChangesCommand{
+			Loader:        &RFCIndexLoader{DataFormat: RFCIndexDataFormatXML, CacheStore: store},
+			Format:        OutputFormatJSON,
+			Watched:       test.watched,
+			FailOnChanges: true,
+		}
+
+		var err error
+		output := captureStdout(t, func() { err = command.Execute() })
+
+		var report rfcIndexChangesReport
+		if err := json.Unmarshal(output, &report); err != nil {
+			t.Fatalf("%v: %v in %q", test.watched, err, output)
+		}
+
+		var numbers []int
+		for _, change := range report.Changes {
+			numbers = append(numbers, change.Number)
+		}
+		if !reflect.DeepEqual(numbers, test.numbers) {
+			t.Errorf("%v: got changes to %v, want %v", test.watched, numbers, test.numbers)
+		}
+
+		if test.numbers == nil {
+			if err != nil {
+				t.Errorf("%v: got %v without changes", test.watched, err)
+			}
+		} else if exitErr, ok := err.(*ExitError); !ok || exitErr.Code != 1 {
+			t.Errorf("%v: got %v, want exit status 1", test.watched, err)
+		}
+	}
+}